@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"tms-backend/database"
+)
+
+// runCheck : tms-backend check, list the rows breaking the data invariants and fail when there are some
+func runCheck(args []string) error {
+	cfg, err := loadConfig(newFlagSet("check"), args)
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(cfg.Database, cfg.Log.Level)
+	if err != nil {
+		return fmt.Errorf("unable to connect to the database: %w", err)
+	}
+
+	violations, err := database.CheckInvariants(db)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		fmt.Println("all invariants hold")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "INVARIANT\tENTITY\tID\tDETAIL")
+	for _, violation := range violations {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", violation.Invariant, violation.EntityType, violation.EntityId, violation.Detail)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d invariant violations", len(violations))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"tms-backend/database"
+	"tms-backend/importer"
+	"tms-backend/models"
+)
+
+const importUsage = "usage: tms-backend import checkpoints|tractors|lots --file path [--owner username] [--dry-run] [flags]"
+
+// runImport : tms-backend import <kind> --file path, create checkpoints, tractors or lots from a CSV file as the upload
+// endpoints do. Tractors and lots belong to --owner
+func runImport(args []string) error {
+	if len(args) == 0 || !slices.Contains(importer.Kinds, importer.Kind(args[0])) {
+		return errors.New(importUsage)
+	}
+	kind := importer.Kind(args[0])
+	fs := newFlagSet("import " + args[0])
+	path := fs.String("file", "", "CSV file to import, its first line names the columns")
+	ownerName := fs.String("owner", "", "Username of the owner of the tractors or lots")
+	dryRun := fs.Bool("dry-run", false, "Only check the rows and print their errors")
+	cfg, err := loadConfig(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--file is required\n" + importUsage)
+	}
+	if kind != importer.KindCheckpoints && *ownerName == "" {
+		return fmt.Errorf("--owner is required to import %s\n%s", kind, importUsage)
+	}
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	db := commandDb(database.InitDb(cfg.Database, cfg.Log.Level), "import "+string(kind))
+	var owner models.User
+	if *ownerName != "" {
+		if err := owner.FindByUsername(db, *ownerName); err != nil {
+			return fmt.Errorf("unknown owner %q: %w", *ownerName, err)
+		}
+		// The rows join the organization of their owner, as when the owner uploads them. importer.Import refuses owners
+		// that are not clients of an organization
+		if owner.OrganizationId != nil {
+			db = models.ForOrganization(db, *owner.OrganizationId)
+		}
+	}
+
+	report, err := importer.Import(db, kind, owner, file, *dryRun)
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Errors {
+		column := ""
+		if problem.Column != "" {
+			column = " " + problem.Column
+		}
+		fmt.Printf("line %d%s: %s\n", problem.Line, column, problem.Message)
+	}
+	switch {
+	case !report.Valid():
+		return fmt.Errorf("%d of the %d rows have errors, nothing was imported", countLines(report.Errors), report.Rows)
+	case *dryRun:
+		fmt.Printf("%d %s are valid, nothing was imported (dry run)\n", report.Rows, kind)
+	default:
+		fmt.Printf("%d %s imported\n", report.Created, kind)
+	}
+	return nil
+}
+
+func countLines(problems []importer.RowError) int {
+	lines := map[int]bool{}
+	for _, problem := range problems {
+		lines[problem.Line] = true
+	}
+	return len(lines)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"tms-backend/database"
+)
+
+const migrateUsage = "usage: tms-backend migrate up|down|status [--steps N] [flags]"
+
+// runMigrate : tms-backend migrate up|down|status, down rolls back --steps migrations
+func runMigrate(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New(migrateUsage)
+	}
+	action := args[0]
+	if action != "up" && action != "down" && action != "status" {
+		return fmt.Errorf("unknown migrate command %q, %s", action, migrateUsage)
+	}
+
+	fs := newFlagSet("migrate " + action)
+	steps := fs.Int("steps", 1, "Number of migrations to roll back")
+	cfg, err := loadConfig(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if *steps < 1 {
+		return errors.New("--steps must be positive")
+	}
+	db, err := database.Open(cfg.Database, cfg.Log.Level)
+	if err != nil {
+		return fmt.Errorf("unable to connect to the database: %w", err)
+	}
+
+	switch action {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("the database is up to date")
+		}
+		return err
+	case "down":
+		rolledBack, err := database.MigrateDown(db, *steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(rolledBack) == 0 {
+			fmt.Println("no migration to roll back")
+		}
+		return err
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return writer.Flush()
+	}
+	return nil
+}
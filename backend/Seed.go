@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"tms-backend/database"
+	"tms-backend/fixtures"
+	"tms-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// runSeed : tms-backend seed [--reset] [--fixture path], load the demo world or a fixture file
+func runSeed(args []string) error {
+	fs := newFlagSet("seed")
+	reset := fs.Bool("reset", false, "Empty the database before seeding, the audit log is kept")
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	db := commandDb(database.InitDb(cfg.Database, cfg.Log.Level), "seed")
+
+	if *reset {
+		if err := database.ResetDB(db); err != nil {
+			return fmt.Errorf("unable to reset the database: %w", err)
+		}
+		fmt.Println("database emptied")
+	}
+	initializeSimulationDate(db, cfg.Simulation)
+	if err := seed(db, cfg.Simulation.Fixture); err != nil {
+		return err
+	}
+	fmt.Println("database seeded")
+	return nil
+}
+
+// seedEmptyDatabase : Seed at startup, only once so restarting the server does not duplicate the world
+func seedEmptyDatabase(db *gorm.DB, path string) error {
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if users > 0 {
+		log.Println("The database already has users, seeding skipped")
+		return nil
+	}
+	return seed(db, path)
+}
+
+// seedCatalogue : Load the checkpoint catalogue in a database without countries, so countries and checkpoints
+// deleted by admins do not come back on restart
+func seedCatalogue(db *gorm.DB) error {
+	var countries int64
+	if err := db.Model(&models.Country{}).Count(&countries).Error; err != nil {
+		return err
+	}
+	if countries > 0 {
+		return nil
+	}
+	return loadCatalogue(db)
+}
+
+func loadCatalogue(db *gorm.DB) error {
+	catalogue, err := fixtures.Catalogue()
+	if err != nil {
+		return err
+	}
+	if err := fixtures.Load(db, catalogue); err != nil {
+		return fmt.Errorf("unable to load the checkpoint catalogue:\n%w", err)
+	}
+	return nil
+}
+
+// seed : Load the fixture at path, the demo world when empty, over the checkpoint catalogue
+func seed(db *gorm.DB, path string) error {
+	fixture, err := fixtures.Demo()
+	if path != "" {
+		fixture, err = fixtures.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := loadCatalogue(db); err != nil {
+		return err
+	}
+	if err := fixtures.Load(db, fixture); err != nil {
+		return fmt.Errorf("unable to load the fixture:\n%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tms-backend/database"
+	"tms-backend/services"
+)
+
+const simulateUsage = "usage: tms-backend simulate advance [--days N] [flags]"
+
+// runSimulate : tms-backend simulate advance --days N, play N days like the admin does from the navbar
+func runSimulate(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || args[0] != "advance" {
+		return errors.New(simulateUsage)
+	}
+	fs := newFlagSet("simulate advance")
+	days := fs.Int("days", 1, "Number of days to move the simulation forward")
+	cfg, err := loadConfig(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if *days < 1 {
+		return errors.New("--days must be positive")
+	}
+	db := commandDb(database.InitDb(cfg.Database, cfg.Log.Level), "simulate advance")
+	initializeSimulationDate(db, cfg.Simulation)
+
+	// Each day is the date change followed by the tractors moving, in the order of the frontend
+	for day := 1; day <= *days; day++ {
+		date, err := services.AdvanceSimulationDate(db)
+		if err != nil {
+			return fmt.Errorf("day %d: %w", day, err)
+		}
+		if err := services.MoveTractors(db); err != nil {
+			return fmt.Errorf("day %d: %w", day, err)
+		}
+		fmt.Printf("simulation date is now %s\n", date.Format("2006-01-02"))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package apierror
+
+import "net/http"
+
+// Code identifies an error for clients, codes are part of the API and never change once released
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "INVALID_REQUEST"
+	CodeInvalidParameter Code = "INVALID_PARAMETER"
+	CodeUnauthorized     Code = "UNAUTHORIZED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeEndpointNotFound Code = "ENDPOINT_NOT_FOUND"
+	CodeInternal         Code = "INTERNAL_ERROR"
+
+	// Authentication
+	CodeMissingCredentials  Code = "MISSING_CREDENTIALS"
+	CodeInvalidCredentials  Code = "INVALID_CREDENTIALS"
+	CodeInvalidToken        Code = "INVALID_TOKEN"
+	CodeInvalidResetToken   Code = "INVALID_RESET_TOKEN"
+	CodeSessionRevoked      Code = "SESSION_REVOKED"
+	CodeAccountLocked       Code = "ACCOUNT_LOCKED"
+	CodeWeakPassword        Code = "WEAK_PASSWORD"
+	CodePasswordNotEditable Code = "PASSWORD_NOT_EDITABLE"
+	CodeInvalidApiKey       Code = "INVALID_API_KEY"
+	CodeMissingScope        Code = "MISSING_SCOPE"
+	CodeInvalidScope        Code = "INVALID_SCOPE"
+	CodeInteractiveOnly     Code = "INTERACTIVE_SESSION_REQUIRED"
+
+	// Missing entities
+	CodeLotNotFound            Code = "LOT_NOT_FOUND"
+	CodeTractorNotFound        Code = "TRACTOR_NOT_FOUND"
+	CodeRouteNotFound          Code = "ROUTE_NOT_FOUND"
+	CodeUserNotFound           Code = "USER_NOT_FOUND"
+	CodeTrafficManagerNotFound Code = "TRAFFIC_MANAGER_NOT_FOUND"
+	CodeTraderNotFound         Code = "TRADER_NOT_FOUND"
+	CodeCountryNotFound        Code = "COUNTRY_NOT_FOUND"
+	CodeCityNotFound           Code = "CITY_NOT_FOUND"
+	CodeCheckpointNotFound     Code = "CHECKPOINT_NOT_FOUND"
+	CodeLegNotFound            Code = "LEG_NOT_FOUND"
+	CodeOfferNotFound          Code = "OFFER_NOT_FOUND"
+	CodeOrganizationNotFound   Code = "ORGANIZATION_NOT_FOUND"
+	CodeApiKeyNotFound         Code = "API_KEY_NOT_FOUND"
+	CodeSimulationNotFound     Code = "SIMULATION_NOT_FOUND"
+
+	// Business rules
+	CodeTractorCapacityExceeded Code = "TRACTOR_CAPACITY_EXCEEDED"
+	CodeResourceTypeMismatch    Code = "RESOURCE_TYPE_MISMATCH"
+	CodeLotIncompatible         Code = "LOT_INCOMPATIBLE_WITH_TRACTOR"
+	CodeOrganizationExists      Code = "ORGANIZATION_EXISTS"
+	CodeCheckpointExists        Code = "CHECKPOINT_EXISTS"
+	CodeCheckpointInUse         Code = "CHECKPOINT_IN_USE"
+	CodeCountryExists           Code = "COUNTRY_EXISTS"
+	CodeCountryInUse            Code = "COUNTRY_IN_USE"
+	CodeLegExists               Code = "LEG_EXISTS"
+	CodeRouteLegMissing         Code = "ROUTE_LEG_MISSING"
+	CodeRouteLegClosed          Code = "ROUTE_LEG_CLOSED"
+	CodeNoRouteFound            Code = "NO_ROUTE_FOUND"
+	CodeRouteStopsInvalid       Code = "ROUTE_STOPS_INVALID"
+	CodeRouteInUse              Code = "ROUTE_IN_USE"
+	CodePlanOutdated            Code = "PLAN_OUTDATED"
+	CodeImportInvalid           Code = "IMPORT_INVALID"
+	CodeOfferClosed             Code = "OFFER_CLOSED"
+	CodeOwnOffer                Code = "OWN_OFFER"
+
+	// Concurrency
+	CodeVersionConflict Code = "VERSION_CONFLICT"
+)
+
+var (
+	ErrInvalidRequest = New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request payload")
+	ErrUnauthorized   = New(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	ErrForbidden      = New(http.StatusForbidden, CodeForbidden, "You are not allowed to perform this action")
+	ErrNotFound       = New(http.StatusNotFound, CodeEndpointNotFound, "No endpoint matches the request")
+	ErrInternal       = New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+
+	ErrMissingCredentials  = New(http.StatusUnauthorized, CodeMissingCredentials, "Missing bearer token")
+	ErrInvalidCredentials  = New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid username or password")
+	ErrInvalidToken        = New(http.StatusUnauthorized, CodeInvalidToken, "Invalid or expired token")
+	ErrInvalidResetToken   = New(http.StatusBadRequest, CodeInvalidResetToken, "Invalid or expired reset token")
+	ErrSessionRevoked      = New(http.StatusUnauthorized, CodeSessionRevoked, "Session expired or revoked")
+	ErrAccountLocked       = New(http.StatusTooManyRequests, CodeAccountLocked, "Too many failed attempts, account locked")
+	ErrWeakPassword        = New(http.StatusBadRequest, CodeWeakPassword, "Password does not match the password policy")
+	ErrPasswordNotEditable = New(http.StatusBadRequest, CodePasswordNotEditable, "Use /auth/password to change a password")
+	ErrInvalidApiKey       = New(http.StatusUnauthorized, CodeInvalidApiKey, "Invalid, expired or revoked API key")
+	ErrMissingScope        = New(http.StatusForbidden, CodeMissingScope, "API key is missing a scope")
+	ErrInvalidScope        = New(http.StatusBadRequest, CodeInvalidScope, "Invalid API key scopes")
+	ErrInteractiveOnly     = New(http.StatusForbidden, CodeInteractiveOnly, "This endpoint requires an interactive session")
+
+	ErrLotNotFound            = New(http.StatusNotFound, CodeLotNotFound, "Lot not found")
+	ErrTractorNotFound        = New(http.StatusNotFound, CodeTractorNotFound, "Tractor not found")
+	ErrRouteNotFound          = New(http.StatusNotFound, CodeRouteNotFound, "Route not found")
+	ErrUserNotFound           = New(http.StatusNotFound, CodeUserNotFound, "User not found")
+	ErrTrafficManagerNotFound = New(http.StatusNotFound, CodeTrafficManagerNotFound, "Traffic Manager not found")
+	ErrTraderNotFound         = New(http.StatusNotFound, CodeTraderNotFound, "Trader not found")
+	ErrCountryNotFound        = New(http.StatusNotFound, CodeCountryNotFound, "Country not found")
+	ErrCityNotFound           = New(http.StatusNotFound, CodeCityNotFound, "City not found")
+	ErrCheckpointNotFound     = New(http.StatusNotFound, CodeCheckpointNotFound, "Checkpoint not found")
+	ErrLegNotFound            = New(http.StatusNotFound, CodeLegNotFound, "Leg not found")
+	ErrOfferNotFound          = New(http.StatusNotFound, CodeOfferNotFound, "Offer not found")
+	ErrOrganizationNotFound   = New(http.StatusNotFound, CodeOrganizationNotFound, "Organization not found")
+	ErrApiKeyNotFound         = New(http.StatusNotFound, CodeApiKeyNotFound, "API key not found")
+	ErrSimulationNotFound     = New(http.StatusInternalServerError, CodeSimulationNotFound, "Unable to fetch simulation date")
+
+	ErrTractorCapacityExceeded = New(http.StatusBadRequest, CodeTractorCapacityExceeded, "Lot exceeds tractor's capacity")
+	ErrResourceTypeMismatch    = New(http.StatusBadRequest, CodeResourceTypeMismatch, "Lot is not the same resource type as the tractor")
+	ErrLotIncompatible         = New(http.StatusBadRequest, CodeLotIncompatible, "Lot is not compatible with the tractor")
+	ErrOrganizationExists      = New(http.StatusConflict, CodeOrganizationExists, "Organization already exists")
+	ErrCheckpointExists        = New(http.StatusConflict, CodeCheckpointExists, "A checkpoint with this name already exists in the country")
+	ErrCheckpointInUse         = New(http.StatusConflict, CodeCheckpointInUse, "Checkpoint is used by legs, routes, lots or tractors")
+	ErrCountryExists           = New(http.StatusConflict, CodeCountryExists, "A country with this name or code already exists")
+	ErrCountryInUse            = New(http.StatusConflict, CodeCountryInUse, "Country still has checkpoints")
+	ErrLegExists               = New(http.StatusConflict, CodeLegExists, "A leg already connects these checkpoints")
+	ErrRouteLegMissing         = New(http.StatusBadRequest, CodeRouteLegMissing, "No leg connects two consecutive checkpoints of the route")
+	ErrRouteLegClosed          = New(http.StatusBadRequest, CodeRouteLegClosed, "The route goes along a closed leg")
+	ErrNoRouteFound            = New(http.StatusBadRequest, CodeNoRouteFound, "No route along open legs connects the checkpoints")
+	ErrRouteStopsInvalid       = New(http.StatusBadRequest, CodeRouteStopsInvalid, "A route goes through at least two distinct checkpoints at consecutive positions")
+	ErrRouteInUse              = New(http.StatusConflict, CodeRouteInUse, "Route is followed by tractors or referenced by transactions")
+	ErrImportInvalid           = New(http.StatusBadRequest, CodeImportInvalid, "Some rows of the file are invalid, nothing was imported")
+	ErrPlanOutdated            = New(http.StatusConflict, CodePlanOutdated, "A tractor or a lot of the plan was assigned or moved since it was planned, plan again")
+	ErrOfferClosed             = New(http.StatusConflict, CodeOfferClosed, "The offer is past its limit date or no longer on the market")
+	ErrOwnOffer                = New(http.StatusConflict, CodeOwnOffer, "Owners can not bid on their own lot or tractor")
+
+	ErrVersionConflict = New(http.StatusConflict, CodeVersionConflict, "The entity was modified since it was read, fetch it again and retry")
+)
@@ -0,0 +1,117 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error is the error every endpoint answers with, rendered by middlewares.Errors as {"error": {...}}
+type Error struct {
+	Status    int         `json:"-"`
+	Code      Code        `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"request_id,omitempty"`
+	// cause is logged by the server, never sent to the client
+	cause error
+}
+
+// Envelope is the body of an error response
+type Envelope struct {
+	Error *Error `json:"error"`
+}
+
+// New : Create an error answered with the given status
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is : Errors with the same code are the same error, whatever their details
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// Cause : The underlying error, nil when there is none
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// WithDetails : Copy of the error carrying machine-readable details
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithCause : Copy of the error keeping the underlying error for the logs
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// WithMessage : Copy of the error with a more specific message
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// InvalidRequest : The body or query could not be bound, the binding error is sent as details
+func InvalidRequest(err error) *Error {
+	return ErrInvalidRequest.WithDetails(err.Error())
+}
+
+// InvalidParameter : A path or body parameter is malformed, usually an id that is not a UUID
+func InvalidParameter(name string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidParameter, "Invalid "+name).WithDetails(gin.H{"parameter": name})
+}
+
+// WeakPassword : The password breaks the policy, err is sent as details so clients get the list of broken rules
+func WeakPassword(err error) *Error {
+	return ErrWeakPassword.WithMessage(err.Error()).WithDetails(err)
+}
+
+// Internal : Unexpected failure, the cause is logged and the client only gets a generic message
+func Internal(cause error) *Error {
+	return ErrInternal.WithCause(cause)
+}
+
+// VersionConflict : The write was based on a stale version of the entity
+func VersionConflict(err *models.StaleVersionError) *Error {
+	return ErrVersionConflict.WithDetails(gin.H{"entity_type": err.EntityType, "entity_id": err.EntityId, "version": err.Version})
+}
+
+// From : The API error wrapped in err, an internal error when there is none
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var staleErr *models.StaleVersionError
+	if errors.As(err, &staleErr) {
+		return VersionConflict(staleErr)
+	}
+	return Internal(err)
+}
+
+// Abort : Stop the handler chain with err, middlewares.Errors writes the response
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"tms-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Every API key starts with this prefix so it can be told apart from an access token
+const apiKeyPrefix = "l8_"
+
+const (
+	defaultApiKeyLifetime = 90 * 24 * time.Hour
+	maxApiKeyLifetime     = 365 * 24 * time.Hour
+)
+
+// Scopes a key can be granted, the key never gets more rights than the roles of its user
+const (
+	ScopeLotsRead         = "lots:read"
+	ScopeLotsWrite        = "lots:write"
+	ScopeTractorsRead     = "tractors:read"
+	ScopeTractorsWrite    = "tractors:write"
+	ScopeRoutesRead       = "routes:read"
+	ScopeRoutesWrite      = "routes:write"
+	ScopeMarketRead       = "market:read"
+	ScopeMarketWrite      = "market:write"
+	ScopeSimulationsRead  = "simulations:read"
+	ScopeSimulationsWrite = "simulations:write"
+	ScopeUsersRead        = "users:read"
+	ScopeUsersWrite       = "users:write"
+)
+
+var Scopes = []string{
+	ScopeLotsRead, ScopeLotsWrite,
+	ScopeTractorsRead, ScopeTractorsWrite,
+	ScopeRoutesRead, ScopeRoutesWrite,
+	ScopeMarketRead, ScopeMarketWrite,
+	ScopeSimulationsRead, ScopeSimulationsWrite,
+	ScopeUsersRead, ScopeUsersWrite,
+}
+
+var (
+	ErrInvalidApiKey = errors.New("invalid, expired or revoked API key")
+	ErrNoScope       = errors.New("an API key needs at least one scope")
+	ErrUnknownScope  = errors.New("unknown scope")
+	ErrInvalidExpiry = errors.New("expires_at must be in the future and at most one year away")
+)
+
+// IsApiKey : Tell whether a bearer credential is an API key rather than an access token
+func IsApiKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// ScopeFor : Scope needed to call a method on a resource, reads only need the read scope
+func ScopeFor(resource string, method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return resource + ":read"
+	default:
+		return resource + ":write"
+	}
+}
+
+// MintApiKey : Create a key for the user, the plain key is only ever returned here
+func MintApiKey(db *gorm.DB, user models.User, name string, scopes []string, expiresAt *time.Time) (models.ApiKey, string, error) {
+	grantedScopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return models.ApiKey{}, "", err
+	}
+
+	expiry := time.Now().Add(defaultApiKeyLifetime)
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) || expiresAt.After(time.Now().Add(maxApiKeyLifetime)) {
+			return models.ApiKey{}, "", ErrInvalidExpiry
+		}
+		expiry = *expiresAt
+	}
+
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		return models.ApiKey{}, "", err
+	}
+	key := apiKeyPrefix + token
+
+	apiKey := models.ApiKey{
+		UserId:    user.Id,
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashOpaqueToken(key),
+		Scopes:    grantedScopes,
+		ExpiresAt: expiry,
+	}
+	if err := apiKey.Create(db); err != nil {
+		return models.ApiKey{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+// AuthenticateApiKey : Resolve the key and its user, and record the key as used
+func AuthenticateApiKey(db *gorm.DB, key string) (models.ApiKey, models.User, error) {
+	var apiKey models.ApiKey
+	if err := apiKey.FindByKeyHash(db, hashOpaqueToken(key)); err != nil || !apiKey.IsActive() {
+		return models.ApiKey{}, models.User{}, ErrInvalidApiKey
+	}
+
+	var user models.User
+	user, err := user.FindById(db, apiKey.UserId)
+	if err != nil {
+		return models.ApiKey{}, models.User{}, ErrInvalidApiKey
+	}
+
+	// Last use tracking must not make the request fail
+	if err := apiKey.Touch(db); err != nil {
+		log.Printf("Unable to record use of API key %s: %v", apiKey.Id, err)
+	}
+	return apiKey, user, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrNoScope
+	}
+	known := make(map[string]bool, len(Scopes))
+	for _, scope := range Scopes {
+		known[scope] = true
+	}
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !known[scope] {
+			return nil, fmt.Errorf("%w %q", ErrUnknownScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"strconv"
+	"time"
+	"tms-backend/config"
+)
+
+// Configure : Apply the authentication and mail settings, called once at startup. Without a JWT secret, which
+// config.Validate only allows on debug logs, tokens are signed with a random key and die with the process
+func Configure(authConfig config.AuthConfig, mailConfig config.MailConfig) error {
+	signingKey = []byte(authConfig.JwtSecret)
+	if authConfig.JwtSecret == "" {
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			signingKey = nil
+			return err
+		}
+	}
+
+	Policy = PasswordPolicy{
+		MinLength:     authConfig.PasswordMinLength,
+		RequireUpper:  authConfig.PasswordRequireUpper,
+		RequireLower:  authConfig.PasswordRequireLower,
+		RequireDigit:  authConfig.PasswordRequireDigit,
+		RequireSymbol: authConfig.PasswordRequireSymbol,
+	}
+	Lockout.MaxAttempts = authConfig.LoginMaxAttempts
+	// The first lock never exceeds the longest one
+	Lockout.BaseDuration = min(time.Duration(authConfig.LoginLockoutSeconds)*time.Second, Lockout.MaxDuration)
+	resetUrl = authConfig.PasswordResetUrl
+
+	Mailer = LogMailSender{}
+	if mailConfig.SMTPHost != "" {
+		Mailer = SMTPMailSender{
+			Address:  mailConfig.SMTPHost + ":" + strconv.Itoa(mailConfig.SMTPPort),
+			Host:     mailConfig.SMTPHost,
+			Username: mailConfig.SMTPUsername,
+			Password: mailConfig.SMTPPassword,
+			From:     mailConfig.From,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userContextKey    = "user"
+	sessionContextKey = "session"
+	apiKeyContextKey  = "api_key"
+)
+
+// SetCurrentUser : Store the authenticated user in the request context
+func SetCurrentUser(c *gin.Context, user models.User) {
+	c.Set(userContextKey, user)
+}
+
+// CurrentUser : Get the authenticated user of the request
+func CurrentUser(c *gin.Context) (models.User, bool) {
+	value, exists := c.Get(userContextKey)
+	if !exists {
+		return models.User{}, false
+	}
+	user, ok := value.(models.User)
+	return user, ok
+}
+
+// SetCurrentSession : Store the session of the authenticated user in the request context
+func SetCurrentSession(c *gin.Context, session models.Session) {
+	c.Set(sessionContextKey, session)
+}
+
+// CurrentSession : Get the session the request was authenticated with
+func CurrentSession(c *gin.Context) (models.Session, bool) {
+	value, exists := c.Get(sessionContextKey)
+	if !exists {
+		return models.Session{}, false
+	}
+	session, ok := value.(models.Session)
+	return session, ok
+}
+
+// SetCurrentApiKey : Store the API key the request was authenticated with
+func SetCurrentApiKey(c *gin.Context, apiKey models.ApiKey) {
+	c.Set(apiKeyContextKey, apiKey)
+}
+
+// CurrentApiKey : Get the API key of the request, absent for interactive sessions
+func CurrentApiKey(c *gin.Context) (models.ApiKey, bool) {
+	value, exists := c.Get(apiKeyContextKey)
+	if !exists {
+		return models.ApiKey{}, false
+	}
+	apiKey, ok := value.(models.ApiKey)
+	return apiKey, ok
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RequestDb : Bind the db to the request so its writes are audited with the caller
+func RequestDb(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
+
+// TenantDb : Scope the queries of the request to the organization of the current user, admins see every tenant
+func TenantDb(c *gin.Context, db *gorm.DB) *gorm.DB {
+	db = RequestDb(c, db)
+	user, ok := CurrentUser(c)
+	if ok && user.Role == models.RoleAdmin {
+		return db
+	}
+	// Users without organization are scoped to the nil id, so they do not see any tenant
+	organizationId := uuid.Nil
+	if ok && user.OrganizationId != nil {
+		organizationId = *user.OrganizationId
+	}
+	return models.ForOrganization(db, organizationId)
+}
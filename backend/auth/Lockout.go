@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"time"
+)
+
+// LockoutPolicy : After MaxAttempts failures the account is locked, the lock doubles on every new failure
+type LockoutPolicy struct {
+	MaxAttempts  int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+var Lockout = LockoutPolicy{
+	MaxAttempts:  5,
+	BaseDuration: time.Minute,
+	MaxDuration:  time.Hour,
+}
+
+// LockDuration : How long an account stays locked after the given number of failed attempts, never more than MaxDuration
+func (policy LockoutPolicy) LockDuration(failedAttempts int) time.Duration {
+	if failedAttempts < policy.MaxAttempts {
+		return 0
+	}
+	duration := policy.BaseDuration
+	for i := policy.MaxAttempts; i < failedAttempts && duration < policy.MaxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, policy.MaxDuration)
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLockDuration(t *testing.T) {
+	policy := LockoutPolicy{MaxAttempts: 5, BaseDuration: time.Minute, MaxDuration: time.Hour}
+	tests := []struct {
+		name           string
+		policy         LockoutPolicy
+		failedAttempts int
+		want           time.Duration
+	}{
+		{"no failure", policy, 0, 0},
+		{"below the limit", policy, 4, 0},
+		{"at the limit", policy, 5, time.Minute},
+		{"one more failure", policy, 6, 2 * time.Minute},
+		{"two more failures", policy, 7, 4 * time.Minute},
+		{"just below the cap", policy, 10, 32 * time.Minute},
+		{"capped", policy, 11, time.Hour},
+		{"many failures do not overflow", policy, math.MaxInt32, time.Hour},
+		{"base above the cap", LockoutPolicy{MaxAttempts: 3, BaseDuration: 2 * time.Hour, MaxDuration: time.Hour}, 3, time.Hour},
+		{"locked from the first failure", LockoutPolicy{MaxAttempts: 1, BaseDuration: time.Second, MaxDuration: time.Minute}, 1, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.LockDuration(test.failedAttempts); got != test.want {
+				t.Errorf("LockDuration(%d) = %v, want %v", test.failedAttempts, got, test.want)
+			}
+		})
+	}
+}
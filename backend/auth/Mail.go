@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+)
+
+// MailSender delivers emails, swap Mailer to plug another provider
+type MailSender interface {
+	Send(to string, subject string, body string) error
+}
+
+var Mailer MailSender = LogMailSender{}
+
+// LogMailSender only writes the emails to the logs, used in development
+type LogMailSender struct{}
+
+func (LogMailSender) Send(to string, subject string, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailSender sends the emails through an SMTP server
+type SMTPMailSender struct {
+	Address  string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (sender SMTPMailSender) Send(to string, subject string, body string) error {
+	var smtpAuth smtp.Auth
+	if sender.Username != "" {
+		smtpAuth = smtp.PlainAuth("", sender.Username, sender.Password, sender.Host)
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", sender.From, to, subject, body)
+	return smtp.SendMail(sender.Address, smtpAuth, sender.From, []string{to}, []byte(message))
+}
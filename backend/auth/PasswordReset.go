@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+	"tms-backend/models"
+
+	"gorm.io/gorm"
+)
+
+const resetTokenLifetime = time.Hour
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+var resetUrl = "http://localhost:3000/reset-password"
+
+// RequestPasswordReset : Create a reset token for the user and mail the reset link
+func RequestPasswordReset(db *gorm.DB, user models.User) error {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	resetToken := models.PasswordResetToken{
+		UserId:    user.Id,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(resetTokenLifetime),
+	}
+	if err := resetToken.Create(db); err != nil {
+		return err
+	}
+
+	link := resetUrl + "?token=" + url.QueryEscape(token)
+	if user.Email == "" {
+		log.Printf("User %s has no email, password reset link: %s", user.Username, link)
+		return nil
+	}
+	body := "Hello " + user.Username + ",\n\nUse the following link to choose a new password, it expires in one hour:\n" + link
+	return Mailer.Send(user.Email, "Reset your Ligne8 password", body)
+}
+
+// ResetPassword : Set a new password using a reset token, every session of the user is closed. The token is used up in
+// the transaction changing the password, so only one of concurrent requests with the same token succeeds
+func ResetPassword(db *gorm.DB, token string, newPassword string) error {
+	var resetToken models.PasswordResetToken
+	if err := resetToken.FindByTokenHash(db, hashOpaqueToken(token)); err != nil || !resetToken.IsUsable() {
+		return ErrInvalidResetToken
+	}
+	if err := Policy.Validate(newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		used, err := resetToken.Use(tx)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidResetToken
+		}
+		var user models.User
+		user, err = user.FindById(tx, resetToken.UserId)
+		if err != nil {
+			return ErrInvalidResetToken
+		}
+		if err := user.UpdatePassword(tx, hashedPassword); err != nil {
+			return err
+		}
+		if err := user.ResetFailedLogins(tx); err != nil {
+			return err
+		}
+		if err := resetToken.InvalidateAllByUserId(tx, user.Id); err != nil {
+			return err
+		}
+		var sessionModel models.Session
+		_, err = sessionModel.RevokeAllByUserId(tx, user.Id)
+		return err
+	})
+}
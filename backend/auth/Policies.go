@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrForbidden = errors.New("You are not allowed to perform this action")
+
+// HasRole : Check if the user has one of the given roles, admins have every role
+func HasRole(user models.User, roles ...models.Role) bool {
+	if user.Role == models.RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanActAs : Check if the user may access resources scoped to the given user id
+func CanActAs(user models.User, userId uuid.UUID) bool {
+	return user.Role == models.RoleAdmin || user.Id == userId
+}
+
+// CanManageLot : Only the owner or the traffic manager of a lot may change it
+func CanManageLot(user models.User, lot models.Lot) bool {
+	return CanActAs(user, lot.OwnerId) || isSameUser(user, lot.TrafficManagerId)
+}
+
+// CanChangeLotState : The trader selling a lot may also change its state
+func CanChangeLotState(user models.User, lot models.Lot) bool {
+	return CanManageLot(user, lot) || isSameUser(user, lot.TraderId)
+}
+
+// CanManageTractor : Only the owner or the traffic manager of a tractor may change it
+func CanManageTractor(user models.User, tractor models.Tractor) bool {
+	return CanActAs(user, tractor.OwnerId) || isSameUser(user, tractor.TrafficManagerId)
+}
+
+// CanChangeTractorState : The trader selling a tractor may also change its state
+func CanChangeTractorState(user models.User, tractor models.Tractor) bool {
+	return CanManageTractor(user, tractor) || isSameUser(user, tractor.TraderId)
+}
+
+// CanManageRoute : Only the traffic manager of a route may change it
+func CanManageRoute(user models.User, route models.Route) bool {
+	return CanActAs(user, route.TrafficManagerId)
+}
+
+// CanAccessOrganization : Users only see their own organization, admins see all of them
+func CanAccessOrganization(user models.User, organizationId uuid.UUID) bool {
+	return user.Role == models.RoleAdmin || (user.OrganizationId != nil && *user.OrganizationId == organizationId)
+}
+
+func isSameUser(user models.User, userId *uuid.UUID) bool {
+	return userId != nil && *userId == user.Id
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+	"tms-backend/models"
+
+	"gorm.io/gorm"
+)
+
+const refreshTokenLifetime = 30 * 24 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// TokenPair is handed to the client when a session is opened or refreshed
+type TokenPair struct {
+	AccessToken           string    `json:"token"`
+	AccessTokenExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// StartSession : Open a new session for the user and issue its first tokens
+func StartSession(db *gorm.DB, user models.User, userAgent string, ipAddress string) (TokenPair, error) {
+	refreshToken, refreshTokenHash, err := newOpaqueToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	session := models.Session{
+		UserId:           user.Id,
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        userAgent,
+		IpAddress:        ipAddress,
+		LastUsedAt:       time.Now(),
+		ExpiresAt:        time.Now().Add(refreshTokenLifetime),
+	}
+	if err := session.Create(db); err != nil {
+		return TokenPair{}, err
+	}
+	return issueTokens(user, session, refreshToken)
+}
+
+// RefreshSession : Exchange a refresh token for a new token pair, the refresh token is rotated
+func RefreshSession(db *gorm.DB, refreshToken string) (models.User, TokenPair, error) {
+	hash := hashOpaqueToken(refreshToken)
+
+	var session models.Session
+	if err := session.FindByRefreshTokenHash(db, hash); err != nil {
+		// A rotated token being replayed means it leaked: kill the whole session
+		var reused models.Session
+		if err := reused.FindByPreviousRefreshTokenHash(db, hash); err == nil && reused.RevokedAt == nil {
+			log.Printf("Refresh token reuse detected for session %s, revoking it", reused.Id)
+			if err := reused.Revoke(db); err != nil {
+				return models.User{}, TokenPair{}, err
+			}
+		}
+		return models.User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+	if !session.IsActive() {
+		return models.User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	user, err := user.FindById(db, session.UserId)
+	if err != nil {
+		return models.User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newOpaqueToken()
+	if err != nil {
+		return models.User{}, TokenPair{}, err
+	}
+	rotated, err := session.Rotate(db, newHash, time.Now().Add(refreshTokenLifetime))
+	if err != nil {
+		return models.User{}, TokenPair{}, err
+	}
+	if !rotated {
+		return models.User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+	tokens, err := issueTokens(user, session, newToken)
+	return user, tokens, err
+}
+
+func issueTokens(user models.User, session models.Session, refreshToken string) (TokenPair, error) {
+	accessToken, accessExpiresAt, err := GenerateAccessToken(user, session.Id)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// newOpaqueToken : Generate a random token and the hash stored in its place
+func newOpaqueToken() (string, string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buffer)
+	return token, hashOpaqueToken(token), nil
+}
+
+// Only a hash of the tokens is stored so a database leak does not leak sessions
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
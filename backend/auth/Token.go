@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"time"
+	"tms-backend/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const accessTokenLifetime = 15 * time.Minute
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// AccessClaims are the claims carried by an access token
+type AccessClaims struct {
+	Role      models.Role `json:"role"`
+	SessionId string      `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// ErrNoSigningKey : Configure was not called, no token is signed nor accepted
+var ErrNoSigningKey = errors.New("no JWT signing key configured")
+
+var signingKey []byte
+
+// GenerateAccessToken : Sign a new access token for the given user and session
+func GenerateAccessToken(user models.User, sessionId uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(accessTokenLifetime)
+	claims := AccessClaims{
+		Role:      user.Role,
+		SessionId: sessionId.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.Id.String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if len(signingKey) == 0 {
+		return "", time.Time{}, ErrNoSigningKey
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseAccessToken : Verify an access token and return the ids of its user and session
+func ParseAccessToken(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	var claims AccessClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if len(signingKey) == 0 {
+			return nil, ErrNoSigningKey
+		}
+		return signingKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrInvalidToken
+	}
+	return userId, sessionId, nil
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config is the whole configuration of the backend.
+// Values are read from the defaults, then the YAML file, then the environment, then the flags.
+type Config struct {
+	Database   DatabaseConfig   `yaml:"database"`
+	Server     ServerConfig     `yaml:"server"`
+	Log        LogConfig        `yaml:"log"`
+	Simulation SimulationConfig `yaml:"simulation"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Mail       MailConfig       `yaml:"mail"`
+	Distance   DistanceConfig   `yaml:"distance"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	TimeZone string `yaml:"timezone"`
+	// Apply pending migrations at startup, otherwise refuse to start until `migrate up` is run
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type ServerConfig struct {
+	Address     string   `yaml:"address"`
+	CorsOrigins []string `yaml:"cors_origins"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+type SimulationConfig struct {
+	// Date the simulation starts at when the database has none yet, today by default
+	StartDate string `yaml:"start_date"`
+	// Seed an empty database with demo users, tractors and lots at startup
+	Seed bool `yaml:"seed"`
+	// YAML or JSON fixture used to seed, the built-in demo world when empty
+	Fixture string `yaml:"fixture"`
+}
+
+type AuthConfig struct {
+	JwtSecret             string `yaml:"jwt_secret"`
+	PasswordMinLength     int    `yaml:"password_min_length"`
+	PasswordRequireUpper  bool   `yaml:"password_require_upper"`
+	PasswordRequireLower  bool   `yaml:"password_require_lower"`
+	PasswordRequireDigit  bool   `yaml:"password_require_digit"`
+	PasswordRequireSymbol bool   `yaml:"password_require_symbol"`
+	PasswordResetUrl      string `yaml:"password_reset_url"`
+	LoginMaxAttempts      int    `yaml:"login_max_attempts"`
+	LoginLockoutSeconds   int    `yaml:"login_lockout_seconds"`
+}
+
+type MailConfig struct {
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	From         string `yaml:"from"`
+}
+
+// DistanceConfig turns great-circle distances into road distances, roads being longer than the straight line
+type DistanceConfig struct {
+	// Multiplier applied to every great-circle distance
+	RoadFactor float64 `yaml:"road_factor"`
+	// Multiplier by country name, a trip between two countries uses the mean of both
+	CountryRoadFactors map[string]float64 `yaml:"country_road_factors"`
+	// Speed estimating the travel time of a leg when it is not given
+	AverageSpeedKmh float64 `yaml:"average_speed_kmh"`
+}
+
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+const SimulationDateLayout = "2006-01-02"
+
+// Default : Configuration used when nothing else is set, it matches the docker-compose database
+func Default() Config {
+	return Config{
+		Database: DatabaseConfig{
+			Host:        "localhost",
+			Port:        5432,
+			User:        "ligne8",
+			Password:    "secret",
+			Name:        "tms_db",
+			SSLMode:     "disable",
+			TimeZone:    "Europe/Paris",
+			AutoMigrate: true,
+		},
+		Server: ServerConfig{
+			Address:     ":8080",
+			CorsOrigins: []string{"*"},
+		},
+		Log: LogConfig{
+			Level: LogLevelInfo,
+		},
+		Auth: AuthConfig{
+			PasswordMinLength:    8,
+			PasswordRequireUpper: true,
+			PasswordRequireLower: true,
+			PasswordRequireDigit: true,
+			PasswordResetUrl:     "http://localhost:3000/reset-password",
+			LoginMaxAttempts:     5,
+			LoginLockoutSeconds:  60,
+		},
+		Mail: MailConfig{
+			SMTPPort: 587,
+		},
+		Distance: DistanceConfig{
+			RoadFactor:      1.3,
+			AverageSpeedKmh: 70,
+		},
+	}
+}
+
+// Load : Build the configuration from the YAML file, the environment and the command line flags
+func Load(args []string) (Config, error) {
+	return LoadCommand(flag.NewFlagSet("tms-backend", flag.ContinueOnError), args)
+}
+
+// LoadCommand : Same as Load, the flag set may already hold the flags of a subcommand
+func LoadCommand(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+
+	flags, err := parseFlags(fs, args)
+	if err != nil {
+		return cfg, err
+	}
+
+	// The file is optional, it is only read when given by flag or CONFIG_FILE
+	path := flags.configFile
+	if path == "" {
+		path = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return cfg, err
+		}
+	}
+
+	problems := loadEnv(&cfg)
+	flags.apply(&cfg)
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return cfg, errors.Join(problems...)
+	}
+	return cfg, nil
+}
+
+// Validate : Check every setting and list all the problems at once
+func (cfg Config) Validate() []error {
+	var problems []error
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if cfg.Database.Host == "" {
+		invalid("database.host (DB_HOST) is required")
+	}
+	if cfg.Database.Port < 1 || cfg.Database.Port > 65535 {
+		invalid("database.port (DB_PORT) must be between 1 and 65535, got %d", cfg.Database.Port)
+	}
+	if cfg.Database.User == "" {
+		invalid("database.user (DB_USER) is required")
+	}
+	if cfg.Database.Name == "" {
+		invalid("database.name (DB_NAME) is required")
+	}
+	switch cfg.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		invalid("database.sslmode (DB_SSLMODE) must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", cfg.Database.SSLMode)
+	}
+	if _, err := time.LoadLocation(cfg.Database.TimeZone); err != nil {
+		invalid("database.timezone (DB_TIMEZONE) is not a known time zone: %q", cfg.Database.TimeZone)
+	}
+
+	if cfg.Server.Address == "" || !strings.Contains(cfg.Server.Address, ":") {
+		invalid("server.address (LISTEN_ADDR) must look like host:port or :port, got %q", cfg.Server.Address)
+	}
+	if len(cfg.Server.CorsOrigins) == 0 {
+		invalid("server.cors_origins (CORS_ORIGINS) needs at least one origin, use * to allow all")
+	}
+	for _, origin := range cfg.Server.CorsOrigins {
+		if origin == "*" {
+			continue
+		}
+		if parsed, err := url.Parse(origin); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			invalid("server.cors_origins (CORS_ORIGINS) contains an invalid origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+
+	switch cfg.Log.Level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		invalid("log.level (LOG_LEVEL) must be one of debug, info, warn, error, got %q", cfg.Log.Level)
+	}
+
+	if cfg.Simulation.StartDate != "" {
+		if _, err := time.Parse(SimulationDateLayout, cfg.Simulation.StartDate); err != nil {
+			invalid("simulation.start_date (SIMULATION_START_DATE) must be a YYYY-MM-DD date, got %q", cfg.Simulation.StartDate)
+		}
+	}
+
+	// Tokens signed with a missing secret could be forged by anyone, only local debugging runs without one
+	if cfg.Auth.JwtSecret == "" && cfg.Log.Level != LogLevelDebug {
+		invalid("auth.jwt_secret (JWT_SECRET) is required unless log.level (LOG_LEVEL) is debug")
+	}
+	if cfg.Auth.PasswordMinLength < 1 {
+		invalid("auth.password_min_length (PASSWORD_MIN_LENGTH) must be positive, got %d", cfg.Auth.PasswordMinLength)
+	}
+	if cfg.Auth.LoginMaxAttempts < 1 {
+		invalid("auth.login_max_attempts (LOGIN_MAX_ATTEMPTS) must be positive, got %d", cfg.Auth.LoginMaxAttempts)
+	}
+	if cfg.Auth.LoginLockoutSeconds < 1 {
+		invalid("auth.login_lockout_seconds (LOGIN_LOCKOUT_SECONDS) must be positive, got %d", cfg.Auth.LoginLockoutSeconds)
+	}
+	if parsed, err := url.Parse(cfg.Auth.PasswordResetUrl); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		invalid("auth.password_reset_url (PASSWORD_RESET_URL) must be an absolute URL, got %q", cfg.Auth.PasswordResetUrl)
+	}
+
+	if cfg.Mail.SMTPHost != "" {
+		if cfg.Mail.SMTPPort < 1 || cfg.Mail.SMTPPort > 65535 {
+			invalid("mail.smtp_port (SMTP_PORT) must be between 1 and 65535, got %d", cfg.Mail.SMTPPort)
+		}
+		if cfg.Mail.From == "" {
+			invalid("mail.from (SMTP_FROM) is required when mail.smtp_host is set")
+		}
+	}
+
+	if cfg.Distance.RoadFactor < 1 {
+		invalid("distance.road_factor (DISTANCE_ROAD_FACTOR) must be at least 1, got %v", cfg.Distance.RoadFactor)
+	}
+	if cfg.Distance.AverageSpeedKmh <= 0 {
+		invalid("distance.average_speed_kmh (DISTANCE_AVERAGE_SPEED_KMH) must be positive, got %v", cfg.Distance.AverageSpeedKmh)
+	}
+	for country, factor := range cfg.Distance.CountryRoadFactors {
+		if factor < 1 {
+			invalid("distance.country_road_factors.%s must be at least 1, got %v", country, factor)
+		}
+	}
+	return problems
+}
+
+// DSN : Connection string of the database
+func (database DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		quoteDSN(database.Host), quoteDSN(database.User), quoteDSN(database.Password), quoteDSN(database.Name),
+		database.Port, database.SSLMode, quoteDSN(database.TimeZone))
+}
+
+// quoteDSN : Quote a value so spaces or quotes in it do not break the connection string
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// StartTime : Date the simulation starts at, today when not configured
+func (simulation SimulationConfig) StartTime() time.Time {
+	if startDate, err := time.Parse(SimulationDateLayout, simulation.StartDate); err == nil {
+		return startDate
+	}
+	return time.Now().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commandLine holds the flags given on the command line, only the ones that were set override the configuration
+type commandLine struct {
+	configFile string
+	set        map[string]bool
+	values     Config
+}
+
+// parseFlags : Read the command line flags, next to the ones already declared on the flag set
+func parseFlags(fs *flag.FlagSet, args []string) (commandLine, error) {
+	line := commandLine{set: map[string]bool{}}
+	var corsOrigins string
+
+	fs.StringVar(&line.configFile, "config", "", "Path of the YAML configuration file")
+	fs.StringVar(&line.values.Database.Host, "db-host", "", "Database host")
+	fs.IntVar(&line.values.Database.Port, "db-port", 0, "Database port")
+	fs.StringVar(&line.values.Database.User, "db-user", "", "Database user")
+	fs.StringVar(&line.values.Database.Password, "db-password", "", "Database password")
+	fs.StringVar(&line.values.Database.Name, "db-name", "", "Database name")
+	fs.StringVar(&line.values.Server.Address, "addr", "", "Address the server listens on, like :8080")
+	fs.StringVar(&corsOrigins, "cors-origins", "", "Comma separated list of allowed CORS origins")
+	fs.StringVar(&line.values.Log.Level, "log-level", "", "Log level: debug, info, warn or error")
+	fs.StringVar(&line.values.Simulation.StartDate, "simulation-start-date", "", "Date the simulation starts at, YYYY-MM-DD")
+	fs.BoolVar(&line.values.Simulation.Seed, "seed", false, "Seed an empty database with demo data at startup")
+	fs.StringVar(&line.values.Simulation.Fixture, "fixture", "", "YAML or JSON fixture to seed with instead of the demo world")
+
+	if err := fs.Parse(args); err != nil {
+		return line, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		line.set[f.Name] = true
+	})
+	line.values.Server.CorsOrigins = splitList(corsOrigins)
+	return line, nil
+}
+
+// apply : Override the configuration with the flags that were set
+func (line commandLine) apply(cfg *Config) {
+	if line.set["db-host"] {
+		cfg.Database.Host = line.values.Database.Host
+	}
+	if line.set["db-port"] {
+		cfg.Database.Port = line.values.Database.Port
+	}
+	if line.set["db-user"] {
+		cfg.Database.User = line.values.Database.User
+	}
+	if line.set["db-password"] {
+		cfg.Database.Password = line.values.Database.Password
+	}
+	if line.set["db-name"] {
+		cfg.Database.Name = line.values.Database.Name
+	}
+	if line.set["addr"] {
+		cfg.Server.Address = line.values.Server.Address
+	}
+	if line.set["cors-origins"] {
+		cfg.Server.CorsOrigins = line.values.Server.CorsOrigins
+	}
+	if line.set["log-level"] {
+		cfg.Log.Level = line.values.Log.Level
+	}
+	if line.set["simulation-start-date"] {
+		cfg.Simulation.StartDate = line.values.Simulation.StartDate
+	}
+	if line.set["seed"] {
+		cfg.Simulation.Seed = line.values.Simulation.Seed
+	}
+	if line.set["fixture"] {
+		cfg.Simulation.Fixture = line.values.Simulation.Fixture
+	}
+}
+
+// loadFile : Read the YAML configuration file over the current values
+func loadFile(cfg *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read configuration file %s: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv : Read the environment variables over the current values
+func loadEnv(cfg *Config) []error {
+	var problems []error
+	str := func(name string, target *string) {
+		if value := lookupEnv(name); value != "" {
+			*target = value
+		}
+	}
+	integer := func(name string, target *int) {
+		if value := lookupEnv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s must be an integer, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	number := func(name string, target *float64) {
+		if value := lookupEnv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s must be a number, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	boolean := func(name string, target *bool) {
+		if value := lookupEnv(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s must be true or false, got %q", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	str("DB_HOST", &cfg.Database.Host)
+	integer("DB_PORT", &cfg.Database.Port)
+	str("DB_USER", &cfg.Database.User)
+	str("DB_PASSWORD", &cfg.Database.Password)
+	str("DB_NAME", &cfg.Database.Name)
+	str("DB_SSLMODE", &cfg.Database.SSLMode)
+	str("DB_TIMEZONE", &cfg.Database.TimeZone)
+	boolean("DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate)
+
+	str("LISTEN_ADDR", &cfg.Server.Address)
+	if value := lookupEnv("CORS_ORIGINS"); value != "" {
+		cfg.Server.CorsOrigins = splitList(value)
+	}
+
+	str("LOG_LEVEL", &cfg.Log.Level)
+
+	str("SIMULATION_START_DATE", &cfg.Simulation.StartDate)
+	boolean("SIMULATION_SEED", &cfg.Simulation.Seed)
+	str("SIMULATION_FIXTURE", &cfg.Simulation.Fixture)
+
+	str("JWT_SECRET", &cfg.Auth.JwtSecret)
+	integer("PASSWORD_MIN_LENGTH", &cfg.Auth.PasswordMinLength)
+	boolean("PASSWORD_REQUIRE_UPPER", &cfg.Auth.PasswordRequireUpper)
+	boolean("PASSWORD_REQUIRE_LOWER", &cfg.Auth.PasswordRequireLower)
+	boolean("PASSWORD_REQUIRE_DIGIT", &cfg.Auth.PasswordRequireDigit)
+	boolean("PASSWORD_REQUIRE_SYMBOL", &cfg.Auth.PasswordRequireSymbol)
+	str("PASSWORD_RESET_URL", &cfg.Auth.PasswordResetUrl)
+	integer("LOGIN_MAX_ATTEMPTS", &cfg.Auth.LoginMaxAttempts)
+	integer("LOGIN_LOCKOUT_SECONDS", &cfg.Auth.LoginLockoutSeconds)
+
+	str("SMTP_HOST", &cfg.Mail.SMTPHost)
+	integer("SMTP_PORT", &cfg.Mail.SMTPPort)
+	str("SMTP_USERNAME", &cfg.Mail.SMTPUsername)
+	str("SMTP_PASSWORD", &cfg.Mail.SMTPPassword)
+	str("SMTP_FROM", &cfg.Mail.From)
+
+	number("DISTANCE_ROAD_FACTOR", &cfg.Distance.RoadFactor)
+	number("DISTANCE_AVERAGE_SPEED_KMH", &cfg.Distance.AverageSpeedKmh)
+	return problems
+}
+
+func lookupEnv(name string) string {
+	return strings.TrimSpace(os.Getenv(name))
+}
+
+// splitList : Split a comma separated list, blank entries are dropped
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
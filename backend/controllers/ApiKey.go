@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ApiKeyController struct {
+	Db *gorm.DB
+}
+
+// CreateApiKey : Mint an API key, the plain key is only returned once
+//
+// @Summary      Create an API key
+// @Tags         api_keys
+// @Accept       json
+// @Produce      json
+// @Param        name        body  string    true   "Name of the key"
+// @Param        scopes      body  []string  true   "Scopes granted to the key"
+// @Param        expires_at  body  string    false  "Expiration date, defaults to 90 days"
+// @Param        user_id     body  string    false  "User the key acts as, admins only"
+// @Success      201  "API key created"
+// @Failure      400  "Invalid request payload"
+// @Failure      403  "Forbidden"
+// @Failure      500  "Unable to create API key"
+// @Router       /api_keys [post]
+func (ApiKeyController *ApiKeyController) CreateApiKey(c *gin.Context) {
+	var requestBody struct {
+		Name      string     `json:"name" binding:"required"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		UserId    *uuid.UUID `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	caller, _ := auth.CurrentUser(c)
+	owner := caller
+	if requestBody.UserId != nil && *requestBody.UserId != caller.Id {
+		if !auth.CanActAs(caller, *requestBody.UserId) {
+			apierror.Abort(c, apierror.ErrForbidden)
+			return
+		}
+		var err error
+		owner, err = owner.FindById(auth.RequestDb(c, ApiKeyController.Db), *requestBody.UserId)
+		if err != nil {
+			apierror.Abort(c, apierror.ErrUserNotFound)
+			return
+		}
+	}
+
+	apiKey, key, err := auth.MintApiKey(auth.RequestDb(c, ApiKeyController.Db), owner, requestBody.Name, requestBody.Scopes, requestBody.ExpiresAt)
+	if errors.Is(err, auth.ErrNoScope) || errors.Is(err, auth.ErrUnknownScope) {
+		apierror.Abort(c, apierror.ErrInvalidScope.WithMessage(err.Error()).WithDetails(gin.H{"scopes": auth.Scopes}))
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidExpiry) {
+		apierror.Abort(c, apierror.InvalidParameter("expires_at").WithMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to create API key"))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": apiKey, "key": key})
+}
+
+// apiKeyListSpec : Filters and sorts of the API key list
+var apiKeyListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name":       {Column: "name", Kind: listing.KindString},
+		"created_at": {Column: "created_at", Kind: listing.KindTime},
+		"expires_at": {Column: "expires_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"name":         "name",
+		"created_at":   "created_at",
+		"expires_at":   "expires_at",
+		"last_used_at": "last_used_at",
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+// ListApiKeys : List the API keys of a user
+//
+// @Summary      List API keys
+// @Tags         api_keys
+// @Produce      json
+// @Param        user_id  query  string  false  "User ID, defaults to the current user"
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of API keys to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.ApiKey
+// @Header       200  {integer}  X-Total-Count  "Number of API keys matching the filters"
+// @Failure      400  "Invalid user_id"
+// @Failure      403  "Forbidden"
+// @Failure      500  "Unable to retrieve API keys"
+// @Router       /api_keys [get]
+func (ApiKeyController *ApiKeyController) ListApiKeys(c *gin.Context) {
+	caller, _ := auth.CurrentUser(c)
+	userId := caller.Id
+	if param := c.Query("user_id"); param != "" {
+		var err error
+		userId, err = uuid.Parse(param)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter("user_id"))
+			return
+		}
+	}
+	if !auth.CanActAs(caller, userId) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, apiKeyListSpec)
+	if !ok {
+		return
+	}
+	var apiKeys []models.ApiKey
+	total, err := listing.Find(auth.RequestDb(c, ApiKeyController.Db).Where("user_id = ?", userId), query, &apiKeys)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve API keys"))
+		return
+	}
+	listing.Respond(c, query, total, apiKeys)
+}
+
+// ListScopes : List the scopes an API key can be granted
+//
+// @Summary      List API key scopes
+// @Tags         api_keys
+// @Produce      json
+// @Success      200  {array}  string
+// @Router       /api_keys/scopes [get]
+func (ApiKeyController *ApiKeyController) ListScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.Scopes)
+}
+
+// RevokeApiKey : Revoke an API key, it stops working right away
+//
+// @Summary      Revoke an API key
+// @Tags         api_keys
+// @Produce      json
+// @Param        id  path  string  true  "API key ID"
+// @Success      200  {object}  models.ApiKey
+// @Failure      400  "Invalid API key ID"
+// @Failure      403  "Forbidden"
+// @Failure      404  "API key not found"
+// @Failure      500  "Unable to revoke API key"
+// @Router       /api_keys/{id} [delete]
+func (ApiKeyController *ApiKeyController) RevokeApiKey(c *gin.Context) {
+	apiKeyId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("api_key_id"))
+		return
+	}
+
+	var apiKey models.ApiKey
+	if err := apiKey.FindById(auth.RequestDb(c, ApiKeyController.Db), apiKeyId); err != nil {
+		apierror.Abort(c, apierror.ErrApiKeyNotFound)
+		return
+	}
+	if caller, _ := auth.CurrentUser(c); !auth.CanActAs(caller, apiKey.UserId) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		if err := apiKey.Revoke(auth.RequestDb(c, ApiKeyController.Db)); err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to revoke API key"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, apiKey)
+}
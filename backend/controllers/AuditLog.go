@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"tms-backend/apierror"
+	"tms-backend/listing"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuditLogController struct {
+	Db *gorm.DB
+}
+
+// auditLogListSpec : Filters and sorts of the audit log
+var auditLogListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"entity_type": {Column: "entity_type", Kind: listing.KindString},
+		"entity_id":   {Column: "entity_id", Kind: listing.KindString},
+		"actor_id":    {Column: "actor_id", Kind: listing.KindUUID},
+		"action":      {Column: "action", Kind: listing.KindString},
+		"created_at":  {Column: "created_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"created_at":  "created_at",
+		"entity_type": "entity_type",
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+// GetAuditLogs : Search the audit log
+//
+// @Summary      Search the audit log
+// @Tags         audit
+// @Produce      json
+// @Param        entity_type      query  string  false  "Entity type, the table name (lots, tractors, ...)"
+// @Param        entity_id        query  string  false  "Entity ID"
+// @Param        actor_id         query  string  false  "User who made the change"
+// @Param        action           query  string  false  "Action (create, update, delete)"
+// @Param        created_at_from  query  string  false  "Start of the time range (RFC3339)"
+// @Param        created_at_to    query  string  false  "End of the time range (RFC3339)"
+// @Param        limit            query  int     false  "Page size, 100 by default"
+// @Param        offset           query  int     false  "Number of entries to skip"
+// @Param        sort             query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.AuditLog
+// @Header       200  {integer}  X-Total-Count  "Number of entries matching the filters"
+// @Failure      400  "Invalid filter"
+// @Failure      500  "Unable to retrieve audit log"
+// @Router       /audit_logs [get]
+func (AuditLogController *AuditLogController) GetAuditLogs(c *gin.Context) {
+	query, ok := listing.FromRequest(c, auditLogListSpec)
+	if !ok {
+		return
+	}
+
+	var auditLogs []models.AuditLog
+	total, err := listing.Find(AuditLogController.Db, query, &auditLogs)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve audit log"))
+		return
+	}
+	listing.Respond(c, query, total, auditLogs)
+}
@@ -1,11 +1,15 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
 	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (AuthController *AuthController) LoginUser(c *gin.Context) {
@@ -14,20 +18,49 @@ func (AuthController *AuthController) LoginUser(c *gin.Context) {
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&loginData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	var user models.User
-	if err := AuthController.Db.Where("username = ?", loginData.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	if err := auth.RequestDb(c, AuthController.Db).Where("username = ?", loginData.Username).First(&user).Error; err != nil {
+		apierror.Abort(c, apierror.ErrInvalidCredentials)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	if user.IsLocked() {
+		retryAfter := int(math.Ceil(time.Until(*user.LockedUntil).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		apierror.Abort(c, apierror.ErrAccountLocked.WithDetails(gin.H{"retry_after": retryAfter}))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user": user})
+	if !auth.CheckPassword(user.Password, loginData.Password) {
+		if err := user.RegisterFailedLogin(auth.RequestDb(c, AuthController.Db), auth.Lockout.LockDuration); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		apierror.Abort(c, apierror.ErrInvalidCredentials)
+		return
+	}
+
+	if err := user.ResetFailedLogins(auth.RequestDb(c, AuthController.Db)); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+
+	tokens, err := auth.StartSession(auth.RequestDb(c, AuthController.Db), user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Failed to open session"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                  "Login successful",
+		"user":                     user.ToResponse(),
+		"token":                    tokens.AccessToken,
+		"expires_at":               tokens.AccessTokenExpiresAt,
+		"refresh_token":            tokens.RefreshToken,
+		"refresh_token_expires_at": tokens.RefreshTokenExpiresAt,
+	})
 }
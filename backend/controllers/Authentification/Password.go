@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword : Change the password of the current user
+//
+// @Summary      Change password
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        current_password  body  string  true  "Current Password"
+// @Param        new_password  body  string  true  "New Password"
+// @Success      200  "Password changed"
+// @Failure      400  "Invalid request payload"
+// @Failure      401  "Invalid current password"
+// @Failure      500  "Unable to change password"
+// @Router       /auth/password [post]
+func (AuthController *AuthController) ChangePassword(c *gin.Context) {
+	var requestBody struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	if !auth.CheckPassword(user.Password, requestBody.CurrentPassword) {
+		apierror.Abort(c, apierror.ErrInvalidCredentials.WithMessage("Invalid current password"))
+		return
+	}
+
+	if err := auth.Policy.Validate(requestBody.NewPassword); err != nil {
+		apierror.Abort(c, apierror.WeakPassword(err))
+		return
+	}
+	hashedPassword, err := auth.HashPassword(requestBody.NewPassword)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to change password"))
+		return
+	}
+	if err := user.UpdatePassword(auth.RequestDb(c, AuthController.Db), hashedPassword); err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to change password"))
+		return
+	}
+
+	// Every other device has to log in again with the new password
+	session, _ := auth.CurrentSession(c)
+	if err := session.RevokeOthersByUserId(auth.RequestDb(c, AuthController.Db), user.Id, session.Id); err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to revoke sessions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// ForgotPassword : Send a password reset link to the user
+//
+// @Summary      Request a password reset
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        username  body  string  true  "Username"
+// @Success      202  "Reset link sent if the user exists"
+// @Failure      400  "Invalid request payload"
+// @Router       /auth/password/forgot [post]
+func (AuthController *AuthController) ForgotPassword(c *gin.Context) {
+	var requestBody struct {
+		Username string `json:"username" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	// The answer is the same whether the user exists or not to avoid leaking usernames
+	var user models.User
+	if err := user.FindByUsername(auth.RequestDb(c, AuthController.Db), requestBody.Username); err == nil {
+		if err := auth.RequestPasswordReset(auth.RequestDb(c, AuthController.Db), user); err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to send reset link"))
+			return
+		}
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a reset link has been sent"})
+}
+
+// ResetPassword : Choose a new password with a reset token
+//
+// @Summary      Reset password
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body  string  true  "Reset Token"
+// @Param        new_password  body  string  true  "New Password"
+// @Success      200  "Password reset"
+// @Failure      400  "Invalid or expired reset token"
+// @Failure      500  "Unable to reset password"
+// @Router       /auth/password/reset [post]
+func (AuthController *AuthController) ResetPassword(c *gin.Context) {
+	var requestBody struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	err := auth.ResetPassword(auth.RequestDb(c, AuthController.Db), requestBody.Token, requestBody.NewPassword)
+	var weakPassword *auth.WeakPasswordError
+	if err == auth.ErrInvalidResetToken {
+		apierror.Abort(c, apierror.ErrInvalidResetToken)
+		return
+	}
+	if errors.As(err, &weakPassword) {
+		apierror.Abort(c, apierror.WeakPassword(weakPassword))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to reset password"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
@@ -2,9 +2,10 @@ package controllers
 
 import (
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
 	"tms-backend/models"
 )
 
@@ -12,24 +13,68 @@ type AuthController struct {
 	Db *gorm.DB
 }
 
+// CreateUser : Self-registration, admins are only created by an admin through /users or by the fixtures
 func (AuthController *AuthController) CreateUser(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var requestBody struct {
+		Username         string      `json:"username" binding:"required"`
+		Password         string      `json:"password" binding:"required"`
+		Email            string      `json:"email"`
+		Role             models.Role `json:"role" binding:"required"`
+		OrganizationName string      `json:"organization_name"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	switch requestBody.Role {
+	case models.RoleClient, models.RoleTrafficManager, models.RoleTrader:
+	default:
+		apierror.Abort(c, apierror.InvalidParameter("role").WithMessage("role must be one of client, traffic_manager, trader"))
+		return
+	}
+	user := models.User{
+		Username: requestBody.Username,
+		Password: requestBody.Password,
+		Email:    requestBody.Email,
+		Role:     requestBody.Role,
+	}
+
+	if err := auth.Policy.Validate(user.Password); err != nil {
+		apierror.Abort(c, apierror.WeakPassword(err))
 		return
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Failed to hash password"))
 		return
 	}
-	user.Password = string(hashedPassword)
+	user.Password = hashedPassword
+
+	// Without an organization name the user joins the default organization, otherwise a new one is created for them
+	var organization models.Organization
+	if requestBody.OrganizationName == "" {
+		if err := organization.FindByName(auth.RequestDb(c, AuthController.Db), models.DefaultOrganizationName); err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Default organization not found"))
+			return
+		}
+	} else {
+		if err := organization.FindByName(auth.RequestDb(c, AuthController.Db), requestBody.OrganizationName); err == nil {
+			apierror.Abort(c, apierror.ErrOrganizationExists.WithMessage("Organization already exists, ask an administrator to add you"))
+			return
+		}
+		organization.Name = requestBody.OrganizationName
+		if err := organization.Create(auth.RequestDb(c, AuthController.Db)); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+	}
+	user.OrganizationId = &organization.Id
 
-	if err := AuthController.Db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.RequestDb(c, AuthController.Db).Create(&user).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.ToResponse())
 }
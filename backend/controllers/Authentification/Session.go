@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshToken : Exchange a refresh token for a new token pair
+//
+// @Summary      Refresh the access token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refresh_token  body  string  true  "Refresh Token"
+// @Success      200  {object}  auth.TokenPair
+// @Failure      400  "Invalid request payload"
+// @Failure      401  "Invalid or expired refresh token"
+// @Router       /auth/refresh [post]
+func (AuthController *AuthController) RefreshToken(c *gin.Context) {
+	var requestBody struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	_, tokens, err := auth.RefreshSession(auth.RequestDb(c, AuthController.Db), requestBody.RefreshToken)
+	if err == auth.ErrInvalidRefreshToken {
+		apierror.Abort(c, apierror.ErrInvalidToken.WithMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout : Revoke the session of the current access token
+//
+// @Summary      Log out
+// @Tags         auth
+// @Produce      json
+// @Success      200  "Logged out"
+// @Failure      401  "Unauthorized"
+// @Failure      500  "Unable to revoke session"
+// @Router       /auth/logout [post]
+func (AuthController *AuthController) Logout(c *gin.Context) {
+	session, ok := auth.CurrentSession(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	if err := session.Revoke(auth.RequestDb(c, AuthController.Db)); err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to revoke session"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll : Revoke every session of the current user
+//
+// @Summary      Log out of all devices
+// @Tags         auth
+// @Produce      json
+// @Success      200  "Logged out of all devices"
+// @Failure      401  "Unauthorized"
+// @Failure      500  "Unable to revoke sessions"
+// @Router       /auth/logout/all [post]
+func (AuthController *AuthController) LogoutAll(c *gin.Context) {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	var sessionModel models.Session
+	revoked, err := sessionModel.RevokeAllByUserId(auth.RequestDb(c, AuthController.Db), user.Id)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to revoke sessions"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all devices", "revoked_sessions": revoked})
+}
+
+// sessionListSpec : Filters and sorts of the session list
+var sessionListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"created_at":   {Column: "created_at", Kind: listing.KindTime},
+		"last_used_at": {Column: "last_used_at", Kind: listing.KindTime},
+		"expires_at":   {Column: "expires_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"created_at":   "created_at",
+		"last_used_at": "last_used_at",
+		"expires_at":   "expires_at",
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+// ListSessions : List the active sessions of the current user
+//
+// @Summary      List active sessions
+// @Tags         auth
+// @Produce      json
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of sessions to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.Session
+// @Header       200  {integer}  X-Total-Count  "Number of sessions matching the filters"
+// @Failure      401  "Unauthorized"
+// @Failure      500  "Unable to retrieve sessions"
+// @Router       /auth/sessions [get]
+func (AuthController *AuthController) ListSessions(c *gin.Context) {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	query, ok := listing.FromRequest(c, sessionListSpec)
+	if !ok {
+		return
+	}
+	var sessions []models.Session
+	active := auth.RequestDb(c, AuthController.Db).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.Id, time.Now())
+	total, err := listing.Find(active, query, &sessions)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve sessions"))
+		return
+	}
+	listing.Respond(c, query, total, sessions)
+}
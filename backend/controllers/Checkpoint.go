@@ -2,9 +2,18 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/distance"
+	"tms-backend/importer"
+	"tms-backend/listing"
 	"tms-backend/models"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -12,6 +21,25 @@ type CheckpointController struct {
 	Db *gorm.DB
 }
 
+// maxMatrixCheckpoints : Largest distance matrix served, it grows with the square of the checkpoints. The from and to
+// lists of the distances are limited alike
+const maxMatrixCheckpoints = 500
+
+// checkpointListSpec : Filters and sorts of the checkpoint list
+var checkpointListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name":    {Column: "name", Kind: listing.KindString},
+		"country": {Column: "country", Kind: listing.KindString},
+		"code":    {Column: "code", Kind: listing.KindString},
+	},
+	Sorts: map[string]string{
+		"name":    "name",
+		"country": "country",
+	},
+	DefaultSort: "country,name",
+	Key:         "id",
+}
+
 // GetAllCheckpoints Retrieve all checkpoints
 //
 //		@Summary      List checkpoints
@@ -19,82 +47,502 @@ type CheckpointController struct {
 //		@Tags         checkpoints
 //		@Accept       json
 //		@Produce      json
-//		@Success      200  {array}   models.Checkpoint
+//		@Param        limit  query  int  false  "Page size, 100 by default"
+//		@Param        offset  query  int  false  "Number of checkpoints to skip"
+//		@Param        sort  query  string  false  "Sort keys, - for a descending order"
+//		@Success      200  {array}   models.CheckpointResponse
+//		@Header       200  {integer}  X-Total-Count  "Number of checkpoints matching the filters"
 //	 	@Failure   	  500 "Unable to retrieve checkpoints"
 //		@Router       /checkpoints [get]
 func (controller *CheckpointController) GetAllCheckpoints(c *gin.Context) {
 	var checkpoints []models.Checkpoint
-	if err := controller.Db.Find(&checkpoints).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve checkpoints"})
+	query, ok := listing.FromRequest(c, checkpointListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(controller.Db, query, &checkpoints)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
 		return
 	}
-	c.JSON(http.StatusOK, checkpoints)
+	listing.Respond(c, query, total, models.ToResponses(checkpoints, (*models.Checkpoint).ToResponse))
 }
 
 // GetCitiesByCountry Retrieve cities by country
 //
 //	@Summary      List cities by country
-//	@Description  get all cities for a given country
+//	@Description  get the names of the checkpoints of a country
 //	@Tags         checkpoints
 //	@Accept       json
 //	@Produce      json
 //	@Param        country  path      string  true  "Country"
-//	@Success      200  {array}   models.City
+//	@Success      200  {array}   string
 //	@Failure	  404	"Country not found"
-//	@Router       /checkpoints/cities/{country} [get]
+//	@Router       /checkpoints/countries/{country}/cities [get]
 func (controller *CheckpointController) GetCitiesByCountry(c *gin.Context) {
-	country := c.Param("country")
-	switch models.Country(country) {
-	case models.CountryFrance:
-		c.JSON(http.StatusOK, []models.City{
-			models.CityParis, models.CityMarseille, models.CityPerpignan, models.CityStrasbourg, models.CityLyon,
-		})
-	case models.CountryItaly:
-		c.JSON(http.StatusOK, []models.City{
-			models.CityRome, models.CityFlorence, models.CityMilan, models.CityComo, models.CityNaples,
-		})
-	case models.CountrySwitzerland:
-		c.JSON(http.StatusOK, []models.City{
-			models.CityGeneva, models.CityZurich, models.CityBern, models.CityLausanne, models.CityChatelSaintDenis,
-		})
-	case models.CountrySpain:
-		c.JSON(http.StatusOK, []models.City{
-			models.CityMadrid, models.CityBarcelona, models.CitySeville, models.CityLloretDelMar, models.CityMalaga,
-		})
-	case models.CountryPortugal:
-		c.JSON(http.StatusOK, []models.City{
-			models.CityLisbon, models.CityPorto, models.CityBraga, models.CityLeiria, models.CityEvora,
-		})
-	default:
-		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+	var country models.Country
+	if err := country.FindByName(controller.Db, c.Param("country")); err != nil {
+		apierror.Abort(c, apierror.ErrCountryNotFound)
+		return
 	}
+	cities, err := models.GetCitiesByCountry(controller.Db, country.Name)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve cities"))
+		return
+	}
+	c.JSON(http.StatusOK, cities)
 }
 
 // GetCountryByCity Retrieve the country by city
 //
 //	@Summary      Get country by city
-//	@Description  get the country for a given city
+//	@Description  get the country for a given city, the first one in alphabetical order when several countries have a checkpoint with that name
 //	@Tags         checkpoints
 //	@Accept       json
 //	@Produce      json
 //	@Param        city  path      string  true  "City"
 //	@Success      200  {string}  string  "Country"
 //	@Failure	  404	"City not found"
-//	@Router       /checkpoints/country/{city} [get]
+//	@Router       /checkpoints/cities/{city}/country [get]
 func (controller *CheckpointController) GetCountryByCity(c *gin.Context) {
-	city := c.Param("city")
-	switch models.City(city) {
-	case models.CityParis, models.CityMarseille, models.CityPerpignan, models.CityStrasbourg, models.CityLyon:
-		c.JSON(http.StatusOK, models.CountryFrance)
-	case models.CityRome, models.CityFlorence, models.CityMilan, models.CityComo, models.CityNaples:
-		c.JSON(http.StatusOK, models.CountryItaly)
-	case models.CityGeneva, models.CityZurich, models.CityBern, models.CityLausanne, models.CityChatelSaintDenis:
-		c.JSON(http.StatusOK, models.CountrySwitzerland)
-	case models.CityMadrid, models.CityBarcelona, models.CitySeville, models.CityLloretDelMar, models.CityMalaga:
-		c.JSON(http.StatusOK, models.CountrySpain)
-	case models.CityLisbon, models.CityPorto, models.CityBraga, models.CityLeiria, models.CityEvora:
-		c.JSON(http.StatusOK, models.CountryPortugal)
-	default:
-		c.JSON(http.StatusNotFound, gin.H{"error": "City not found"})
+	var checkpoint models.Checkpoint
+	if err := controller.Db.Where("name = ?", c.Param("city")).Order("country").First(&checkpoint).Error; err != nil {
+		apierror.Abort(c, apierror.ErrCityNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, checkpoint.Country)
+}
+
+// GetDistances : Distances between checkpoints
+//
+//	@Summary      Get distances between checkpoints
+//	@Description  great-circle and road distances from every checkpoint of from to every checkpoint of to, the road distance applies the configured road factors
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        from  query  string  true  "Comma separated checkpoint IDs"
+//	@Param        to  query  string  true  "Comma separated checkpoint IDs"
+//	@Success      200  {array}   distance.Distance
+//	@Failure      400  "Invalid checkpoint ID or too many checkpoints"
+//	@Failure      404  "Checkpoint not found"
+//	@Failure      500  "Unable to retrieve checkpoints"
+//	@Router       /checkpoints/distances [get]
+func (controller *CheckpointController) GetDistances(c *gin.Context) {
+	from, ok := controller.findCheckpoints(c, "from", true)
+	if !ok {
+		return
+	}
+	to, ok := controller.findCheckpoints(c, "to", true)
+	if !ok {
+		return
+	}
+	distances := make([]distance.Distance, 0, len(from)*len(to))
+	for _, fromCheckpoint := range from {
+		for _, toCheckpoint := range to {
+			distances = append(distances, distance.Between(fromCheckpoint, toCheckpoint))
+		}
+	}
+	c.JSON(http.StatusOK, distances)
+}
+
+// GetDistanceMatrix : Road distances between every pair of checkpoints
+//
+//	@Summary      Get the distance matrix
+//	@Description  road distances between the checkpoints given, or those of a country, or all of them, ordered by country and name
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        checkpoint_ids  query  string  false  "Comma separated checkpoint IDs, in the order of the matrix"
+//	@Param        country  query  string  false  "Only the checkpoints of this country"
+//	@Success      200  {object}  distance.Matrix
+//	@Failure      400  "Invalid checkpoint ID or too many checkpoints"
+//	@Failure      404  "Checkpoint not found"
+//	@Failure      500  "Unable to retrieve checkpoints"
+//	@Router       /checkpoints/distances/matrix [get]
+func (controller *CheckpointController) GetDistanceMatrix(c *gin.Context) {
+	checkpoints, ok := controller.findCheckpoints(c, "checkpoint_ids", false)
+	if !ok {
+		return
+	}
+	if c.Query("checkpoint_ids") == "" {
+		db := controller.Db.Order("country, name")
+		if country := c.Query("country"); country != "" {
+			db = db.Where("country = ?", country)
+		}
+		if err := db.Find(&checkpoints).Error; err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+			return
+		}
+	}
+	if len(checkpoints) > maxMatrixCheckpoints {
+		apierror.Abort(c, apierror.InvalidParameter("checkpoint_ids").
+			WithMessage("The matrix is limited to "+strconv.Itoa(maxMatrixCheckpoints)+" checkpoints, select some with checkpoint_ids or country"))
+		return
+	}
+	c.JSON(http.StatusOK, distance.MatrixOf(checkpoints))
+}
+
+// GetNearestCheckpoints : Checkpoints closest to a point
+//
+//	@Summary      Get the nearest checkpoints
+//	@Description  checkpoints from the closest to a point to the furthest, by great-circle distance
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        latitude  query  number  true  "Latitude, between -90 and 90"
+//	@Param        longitude  query  number  true  "Longitude, between -180 and 180"
+//	@Param        limit  query  int  false  "Number of checkpoints, 5 by default and 100 at most"
+//	@Success      200  {array}   nearbyCheckpoint
+//	@Failure      400  "Invalid coordinates or limit"
+//	@Failure      500  "Unable to retrieve checkpoints"
+//	@Router       /checkpoints/nearest [get]
+func (controller *CheckpointController) GetNearestCheckpoints(c *gin.Context) {
+	latitude, longitude, ok := point(c)
+	if !ok {
+		return
+	}
+	limit := defaultNearest
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxNearest {
+			apierror.Abort(c, apierror.InvalidParameter("limit").WithMessage("limit must be between 1 and "+strconv.Itoa(maxNearest)))
+			return
+		}
+	}
+	var checkpoints []models.Checkpoint
+	if err := controller.Db.Find(&checkpoints).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+		return
+	}
+	nearby := distance.Around(checkpoints, latitude, longitude, 0)
+	c.JSON(http.StatusOK, nearbyCheckpoints(nearby[:min(limit, len(nearby))]))
+}
+
+// GetCheckpointsWithin : Checkpoints within a radius of a point
+//
+//	@Summary      Get the checkpoints within a radius
+//	@Description  checkpoints at most radius_km from a point by great-circle distance, the closest first
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        latitude  query  number  true  "Latitude, between -90 and 90"
+//	@Param        longitude  query  number  true  "Longitude, between -180 and 180"
+//	@Param        radius_km  query  number  true  "Radius in km, up to 5000"
+//	@Success      200  {array}   nearbyCheckpoint
+//	@Failure      400  "Invalid coordinates or radius"
+//	@Failure      500  "Unable to retrieve checkpoints"
+//	@Router       /checkpoints/within [get]
+func (controller *CheckpointController) GetCheckpointsWithin(c *gin.Context) {
+	latitude, longitude, ok := point(c)
+	if !ok {
+		return
+	}
+	radiusKm, ok := radius(c)
+	if !ok {
+		return
+	}
+	var checkpoints []models.Checkpoint
+	if err := controller.Db.Find(&checkpoints).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+		return
+	}
+	c.JSON(http.StatusOK, nearbyCheckpoints(distance.Around(checkpoints, latitude, longitude, radiusKm)))
+}
+
+func nearbyCheckpoints(nearby []distance.Nearby) []nearbyCheckpoint {
+	responses := make([]nearbyCheckpoint, len(nearby))
+	for i, checkpoint := range nearby {
+		responses[i] = nearbyCheckpoint{CheckpointResponse: checkpoint.Checkpoint.ToResponse(), DistanceKm: checkpoint.DistanceKm}
+	}
+	return responses
+}
+
+// GetCheckpoint : Get a checkpoint
+//
+//	@Summary      Get checkpoint by id
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        checkpoint_id  path  string  true  "Checkpoint ID"
+//	@Success      200  {object}  models.CheckpointResponse
+//	@Failure      400  "Invalid checkpoint ID"
+//	@Failure      404  "Checkpoint not found"
+//	@Router       /checkpoints/{checkpoint_id} [get]
+func (controller *CheckpointController) GetCheckpoint(c *gin.Context) {
+	checkpoint, ok := controller.findCheckpoint(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, checkpoint.ToResponse())
+}
+
+// GetCheckpointUsage : Count what goes through a checkpoint
+//
+//	@Summary      Get checkpoint usage
+//	@Description  count the routes, lots, tractors and transactions referring to the checkpoint, a checkpoint in use cannot be deleted
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        checkpoint_id  path  string  true  "Checkpoint ID"
+//	@Success      200  {object}  models.CheckpointUsage
+//	@Failure      400  "Invalid checkpoint ID"
+//	@Failure      404  "Checkpoint not found"
+//	@Failure      500  "Unable to count checkpoint usage"
+//	@Router       /checkpoints/{checkpoint_id}/usage [get]
+func (controller *CheckpointController) GetCheckpointUsage(c *gin.Context) {
+	checkpoint, ok := controller.findCheckpoint(c)
+	if !ok {
+		return
+	}
+	usage, err := checkpoint.Usage(auth.RequestDb(c, controller.Db))
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to count checkpoint usage"))
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// CreateCheckpoint : Create a checkpoint in an existing country
+//
+//	@Summary      Create checkpoint
+//	@Tags         checkpoints
+//	@Accept       json
+//	@Produce      json
+//	@Param        name  body  string  true  "City name"
+//	@Param        country  body  string  true  "Country name"
+//	@Param        code  body  string  false  "Code of the depot"
+//	@Param        latitude  body  number  true  "Latitude, between -90 and 90"
+//	@Param        longitude  body  number  true  "Longitude, between -180 and 180"
+//	@Param        metadata  body  object  false  "Free form metadata"
+//	@Success      201  {object}  models.CheckpointResponse
+//	@Failure      400  "Invalid request"
+//	@Failure      404  "Country not found"
+//	@Failure      409  "Checkpoint already exists"
+//	@Failure      500  "Unable to create checkpoint"
+//	@Router       /checkpoints [post]
+func (controller *CheckpointController) CreateCheckpoint(c *gin.Context) {
+	var requestBody struct {
+		Name      string                 `json:"name" binding:"required"`
+		Country   string                 `json:"country" binding:"required"`
+		Code      string                 `json:"code"`
+		Latitude  *float64               `json:"latitude" binding:"required"`
+		Longitude *float64               `json:"longitude" binding:"required"`
+		Metadata  map[string]interface{} `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	checkpoint := models.Checkpoint{
+		Name:      requestBody.Name,
+		Country:   requestBody.Country,
+		Code:      requestBody.Code,
+		Latitude:  *requestBody.Latitude,
+		Longitude: *requestBody.Longitude,
+		Metadata:  requestBody.Metadata,
+	}
+	if !controller.validateCheckpoint(c, checkpoint) {
+		return
+	}
+	if err := auth.RequestDb(c, controller.Db).Create(&checkpoint).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to create checkpoint"))
+		return
+	}
+	c.JSON(http.StatusCreated, checkpoint.ToResponse())
+}
+
+// ImportCheckpoints : Create checkpoints from a CSV file
+//
+//	@Summary      Import checkpoints from CSV
+//	@Description  checks every row as CreateCheckpoint does, the country is given by name or code. The first line names the columns: name, country, latitude, longitude and optionally code. Every row is checked first, the file is imported in one transaction only when all of them are valid, otherwise nothing is and the error details list the problems by line
+//	@Tags         checkpoints
+//	@Accept       text/csv
+//	@Accept       multipart/form-data
+//	@Produce      json
+//	@Param        file  formData  file  false  "CSV file, or send it as the body"
+//	@Param        dry_run  query  bool  false  "Only check the rows and report their errors"
+//	@Success      200  {object}  importer.Report  "Dry run"
+//	@Success      201  {object}  importer.Report  "Rows imported"
+//	@Failure      400  "Invalid file, or IMPORT_INVALID with the report as details"
+//	@Failure      401  "Unauthorized"
+//	@Failure      500  "Unable to import checkpoints"
+//	@Router       /checkpoints/import [post]
+func (controller *CheckpointController) ImportCheckpoints(c *gin.Context) {
+	importFile(c, auth.RequestDb(c, controller.Db), importer.KindCheckpoints)
+}
+
+// UpdateCheckpoint : Update a checkpoint, only the fields sent are changed
+//
+//	@Summary      Update checkpoint
+//	@Tags         checkpoints
+//	@Accept       json
+//	@Produce      json
+//	@Param        checkpoint_id  path  string  true  "Checkpoint ID"
+//	@Param        name  body  string  false  "City name"
+//	@Param        country  body  string  false  "Country name"
+//	@Param        code  body  string  false  "Code of the depot"
+//	@Param        latitude  body  number  false  "Latitude, between -90 and 90"
+//	@Param        longitude  body  number  false  "Longitude, between -180 and 180"
+//	@Param        metadata  body  object  false  "Free form metadata, replaces the current one"
+//	@Success      200  {object}  models.CheckpointResponse
+//	@Failure      400  "Invalid request"
+//	@Failure      404  "Checkpoint or country not found"
+//	@Failure      409  "Checkpoint already exists"
+//	@Failure      500  "Unable to update checkpoint"
+//	@Router       /checkpoints/{checkpoint_id} [patch]
+func (controller *CheckpointController) UpdateCheckpoint(c *gin.Context) {
+	checkpoint, ok := controller.findCheckpoint(c)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		Name      *string                `json:"name"`
+		Country   *string                `json:"country"`
+		Code      *string                `json:"code"`
+		Latitude  *float64               `json:"latitude"`
+		Longitude *float64               `json:"longitude"`
+		Metadata  map[string]interface{} `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	updated := checkpoint
+	if requestBody.Name != nil {
+		updated.Name = *requestBody.Name
+	}
+	if requestBody.Country != nil {
+		updated.Country = *requestBody.Country
+	}
+	if requestBody.Code != nil {
+		updated.Code = *requestBody.Code
+	}
+	if requestBody.Latitude != nil {
+		updated.Latitude = *requestBody.Latitude
+	}
+	if requestBody.Longitude != nil {
+		updated.Longitude = *requestBody.Longitude
+	}
+	if requestBody.Metadata != nil {
+		updated.Metadata = requestBody.Metadata
+	}
+	if !controller.validateCheckpoint(c, updated) {
+		return
+	}
+	if err := auth.RequestDb(c, controller.Db).Save(&updated).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to update checkpoint"))
+		return
+	}
+	c.JSON(http.StatusOK, updated.ToResponse())
+}
+
+// DeleteCheckpoint : Delete a checkpoint no route, lot, tractor or transaction refers to
+//
+//	@Summary      Delete checkpoint
+//	@Tags         checkpoints
+//	@Produce      json
+//	@Param        checkpoint_id  path  string  true  "Checkpoint ID"
+//	@Success      200  {object}  models.CheckpointResponse
+//	@Failure      400  "Invalid checkpoint ID"
+//	@Failure      404  "Checkpoint not found"
+//	@Failure      409  "Checkpoint in use, the details hold its usage"
+//	@Failure      500  "Unable to delete checkpoint"
+//	@Router       /checkpoints/{checkpoint_id} [delete]
+func (controller *CheckpointController) DeleteCheckpoint(c *gin.Context) {
+	checkpointId, err := uuid.Parse(c.Param("checkpoint_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("checkpoint_id"))
+		return
+	}
+	checkpoint, err := services.DeleteCheckpoint(auth.RequestDb(c, controller.Db), checkpointId)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	c.JSON(http.StatusOK, checkpoint.ToResponse())
+}
+
+// findCheckpoint : Load the checkpoint of the path
+func (controller *CheckpointController) findCheckpoint(c *gin.Context) (models.Checkpoint, bool) {
+	var checkpoint models.Checkpoint
+	checkpointId, err := uuid.Parse(c.Param("checkpoint_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("checkpoint_id"))
+		return checkpoint, false
+	}
+	if err := checkpoint.FindById(controller.Db, checkpointId); err != nil {
+		apierror.Abort(c, apierror.ErrCheckpointNotFound)
+		return checkpoint, false
+	}
+	return checkpoint, true
+}
+
+// findCheckpoints : Load the checkpoints of a comma separated list of ids in the query, in the order given
+func (controller *CheckpointController) findCheckpoints(c *gin.Context, param string, required bool) ([]models.Checkpoint, bool) {
+	raw := c.Query(param)
+	if raw == "" {
+		if required {
+			apierror.Abort(c, apierror.InvalidParameter(param).WithMessage(param+" is required"))
+			return nil, false
+		}
+		return []models.Checkpoint{}, true
+	}
+	values := strings.Split(raw, ",")
+	if len(values) > maxMatrixCheckpoints {
+		apierror.Abort(c, apierror.InvalidParameter(param).
+			WithMessage(param+" is limited to "+strconv.Itoa(maxMatrixCheckpoints)+" checkpoints"))
+		return nil, false
+	}
+	var ids []uuid.UUID
+	for _, value := range values {
+		id, err := uuid.Parse(strings.TrimSpace(value))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter(param).WithMessage("Invalid "+param+", expected checkpoint IDs"))
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+
+	var found []models.Checkpoint
+	if err := controller.Db.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+		return nil, false
+	}
+	byId := make(map[uuid.UUID]models.Checkpoint, len(found))
+	for _, checkpoint := range found {
+		byId[checkpoint.Id] = checkpoint
+	}
+	checkpoints := make([]models.Checkpoint, 0, len(ids))
+	for _, id := range ids {
+		checkpoint, ok := byId[id]
+		if !ok {
+			apierror.Abort(c, apierror.ErrCheckpointNotFound.WithDetails(gin.H{"checkpoint_id": id}))
+			return nil, false
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, true
+}
+
+// validateCheckpoint : Check the coordinates, that the country exists and that no other checkpoint of the country has the same name
+func (controller *CheckpointController) validateCheckpoint(c *gin.Context, checkpoint models.Checkpoint) bool {
+	if checkpoint.Name == "" {
+		apierror.Abort(c, apierror.InvalidParameter("name").WithMessage("name must not be empty"))
+		return false
+	}
+	if checkpoint.Latitude < -90 || checkpoint.Latitude > 90 {
+		apierror.Abort(c, apierror.InvalidParameter("latitude").WithMessage("latitude must be between -90 and 90"))
+		return false
+	}
+	if checkpoint.Longitude < -180 || checkpoint.Longitude > 180 {
+		apierror.Abort(c, apierror.InvalidParameter("longitude").WithMessage("longitude must be between -180 and 180"))
+		return false
+	}
+	var country models.Country
+	if err := country.FindByName(controller.Db, checkpoint.Country); err != nil {
+		apierror.Abort(c, apierror.ErrCountryNotFound)
+		return false
+	}
+	var existing models.Checkpoint
+	if err := existing.FindByNameAndCountry(controller.Db, checkpoint.Name, checkpoint.Country); err == nil && existing.Id != checkpoint.Id {
+		apierror.Abort(c, apierror.ErrCheckpointExists)
+		return false
 	}
+	return true
 }
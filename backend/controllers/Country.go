@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
+	"tms-backend/models"
+	"tms-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CountryController struct {
+	Db *gorm.DB
+}
+
+// countryListSpec : Filters and sorts of the country list
+var countryListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name": {Column: "name", Kind: listing.KindString},
+		"code": {Column: "code", Kind: listing.KindString},
+	},
+	Sorts: map[string]string{
+		"name": "name",
+		"code": "code",
+	},
+	DefaultSort: "name",
+	Key:         "id",
+}
+
+// GetCountries : Get countries
+//
+// @Summary      List countries
+// @Tags         countries
+// @Produce      json
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of countries to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.CountryResponse
+// @Header       200  {integer}  X-Total-Count  "Number of countries matching the filters"
+// @Failure      500  "Unable to retrieve countries"
+// @Router       /countries [get]
+func (CountryController *CountryController) GetCountries(c *gin.Context) {
+	var countries []models.Country
+	query, ok := listing.FromRequest(c, countryListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(CountryController.Db, query, &countries)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve countries"))
+		return
+	}
+	listing.Respond(c, query, total, models.ToResponses(countries, (*models.Country).ToResponse))
+}
+
+// GetCountry : Get a country
+//
+// @Summary      Get country by id
+// @Tags         countries
+// @Produce      json
+// @Param        id  path  string  true  "Country ID"
+// @Success      200  {object}  models.CountryResponse
+// @Failure      400  "Invalid country ID"
+// @Failure      404  "Country not found"
+// @Router       /countries/{id} [get]
+func (CountryController *CountryController) GetCountry(c *gin.Context) {
+	country, ok := CountryController.findCountry(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, country.ToResponse())
+}
+
+// CreateCountry : Create a country
+//
+// @Summary      Create country
+// @Tags         countries
+// @Accept       json
+// @Produce      json
+// @Param        name  body  string  true  "Name"
+// @Param        code  body  string  false  "ISO 3166-1 alpha-2 code"
+// @Param        metadata  body  object  false  "Free form metadata"
+// @Success      201  {object}  models.CountryResponse
+// @Failure      400  "Invalid request"
+// @Failure      409  "Country already exists"
+// @Failure      500  "Unable to create country"
+// @Router       /countries [post]
+func (CountryController *CountryController) CreateCountry(c *gin.Context) {
+	var requestBody struct {
+		Name     string                 `json:"name" binding:"required"`
+		Code     *string                `json:"code"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	country := models.Country{Name: requestBody.Name, Code: requestBody.Code, Metadata: requestBody.Metadata}
+	if !CountryController.validateCountry(c, &country) {
+		return
+	}
+	if err := auth.RequestDb(c, CountryController.Db).Create(&country).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to create country"))
+		return
+	}
+	c.JSON(http.StatusCreated, country.ToResponse())
+}
+
+// UpdateCountry : Update a country, only the fields sent are changed. Renaming a country moves its checkpoints along
+//
+// @Summary      Update country
+// @Tags         countries
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Country ID"
+// @Param        name  body  string  false  "Name"
+// @Param        code  body  string  false  "ISO 3166-1 alpha-2 code"
+// @Param        metadata  body  object  false  "Free form metadata, replaces the current one"
+// @Success      200  {object}  models.CountryResponse
+// @Failure      400  "Invalid request"
+// @Failure      404  "Country not found"
+// @Failure      409  "Country already exists"
+// @Failure      500  "Unable to update country"
+// @Router       /countries/{id} [patch]
+func (CountryController *CountryController) UpdateCountry(c *gin.Context) {
+	country, ok := CountryController.findCountry(c)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		Name     *string                `json:"name"`
+		Code     *string                `json:"code"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	if requestBody.Name != nil {
+		country.Name = *requestBody.Name
+	}
+	if requestBody.Code != nil {
+		country.Code = requestBody.Code
+	}
+	if requestBody.Metadata != nil {
+		country.Metadata = requestBody.Metadata
+	}
+	if !CountryController.validateCountry(c, &country) {
+		return
+	}
+	// The checkpoints follow the rename through the ON UPDATE CASCADE of their foreign key
+	if err := auth.RequestDb(c, CountryController.Db).Save(&country).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to update country"))
+		return
+	}
+	c.JSON(http.StatusOK, country.ToResponse())
+}
+
+// DeleteCountry : Delete a country without checkpoints
+//
+// @Summary      Delete country
+// @Tags         countries
+// @Produce      json
+// @Param        id  path  string  true  "Country ID"
+// @Success      200  {object}  models.CountryResponse
+// @Failure      400  "Invalid country ID"
+// @Failure      404  "Country not found"
+// @Failure      409  "Country still has checkpoints"
+// @Failure      500  "Unable to delete country"
+// @Router       /countries/{id} [delete]
+func (CountryController *CountryController) DeleteCountry(c *gin.Context) {
+	countryId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("country_id"))
+		return
+	}
+	country, err := services.DeleteCountry(auth.RequestDb(c, CountryController.Db), countryId)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	c.JSON(http.StatusOK, country.ToResponse())
+}
+
+// findCountry : Load the country of the path
+func (CountryController *CountryController) findCountry(c *gin.Context) (models.Country, bool) {
+	var country models.Country
+	countryId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("country_id"))
+		return country, false
+	}
+	if err := country.FindById(CountryController.Db, countryId); err != nil {
+		apierror.Abort(c, apierror.ErrCountryNotFound)
+		return country, false
+	}
+	return country, true
+}
+
+// validateCountry : Normalise the code to upper case and check that no other country has the same name or code
+func (CountryController *CountryController) validateCountry(c *gin.Context, country *models.Country) bool {
+	if country.Name == "" {
+		apierror.Abort(c, apierror.InvalidParameter("name").WithMessage("name must not be empty"))
+		return false
+	}
+	if country.Code != nil {
+		if *country.Code == "" {
+			country.Code = nil
+		} else {
+			code := strings.ToUpper(*country.Code)
+			if len(code) != 2 {
+				apierror.Abort(c, apierror.InvalidParameter("code").WithMessage("code must be an ISO 3166-1 alpha-2 code"))
+				return false
+			}
+			country.Code = &code
+		}
+	}
+	var existing models.Country
+	if err := existing.FindByName(CountryController.Db, country.Name); err == nil && existing.Id != country.Id {
+		apierror.Abort(c, apierror.ErrCountryExists)
+		return false
+	}
+	if country.Code != nil {
+		if err := existing.FindByCode(CountryController.Db, *country.Code); err == nil && existing.Id != country.Id {
+			apierror.Abort(c, apierror.ErrCountryExists)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expansion : Associations to embed in the response, from ?expand=start_checkpoint,owner. The request is aborted on unknown ones
+func expansion(c *gin.Context, allowed map[string]string) (models.Expansion, bool) {
+	expansion := models.Expansion{}
+	raw := c.Query("expand")
+	if raw == "" {
+		return expansion, true
+	}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := allowed[name]
+		if !ok {
+			names := make([]string, 0, len(allowed))
+			for allowedName := range allowed {
+				names = append(names, allowedName)
+			}
+			sort.Strings(names)
+			apierror.Abort(c, apierror.InvalidParameter("expand").WithMessage("Invalid expand "+name).
+				WithDetails(gin.H{"parameter": "expand", "allowed": names}))
+			return nil, false
+		}
+		expansion[name] = field
+	}
+	return expansion, true
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"tms-backend/apierror"
+	"tms-backend/geojson"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// byTrafficManager : Keep the rows of the traffic manager given in the query, if any
+func byTrafficManager(c *gin.Context, db *gorm.DB) (*gorm.DB, bool) {
+	raw := c.Query("traffic_manager_id")
+	if raw == "" {
+		return db, true
+	}
+	trafficManagerId, err := uuid.Parse(raw)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
+		return nil, false
+	}
+	return db.Where("traffic_manager_id = ?", trafficManagerId), true
+}
+
+// routeFeatures : Each route as a line through its checkpoints by position
+func routeFeatures(db *gorm.DB, routes []models.Route) ([]geojson.Feature, error) {
+	routeIds := make([]uuid.UUID, len(routes))
+	for i, route := range routes {
+		routeIds[i] = route.Id
+	}
+	var routeCheckpoints []models.RouteCheckpoint
+	if err := db.Preload("Checkpoint").Where("route_id IN ?", routeIds).Order("position").Find(&routeCheckpoints).Error; err != nil {
+		return nil, err
+	}
+	stops := map[uuid.UUID][]models.Checkpoint{}
+	for _, routeCheckpoint := range routeCheckpoints {
+		stops[routeCheckpoint.RouteId] = append(stops[routeCheckpoint.RouteId], routeCheckpoint.Checkpoint)
+	}
+
+	features := make([]geojson.Feature, len(routes))
+	for i, route := range routes {
+		checkpointIds := make([]uuid.UUID, len(stops[route.Id]))
+		for j, checkpoint := range stops[route.Id] {
+			checkpointIds[j] = checkpoint.Id
+		}
+		features[i] = geojson.NewFeature(route.Id.String(), geojson.LineString(stops[route.Id]), map[string]interface{}{
+			"name":               route.Name,
+			"traffic_manager_id": route.TrafficManagerId,
+			"checkpoint_ids":     checkpointIds,
+		})
+	}
+	return features, nil
+}
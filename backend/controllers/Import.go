@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/importer"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxImportBytes : Largest CSV file accepted, well above importer.MaxRows rows
+const maxImportBytes = 10 << 20
+
+// importFile : Import the CSV file of the request, sent as the body or as the file field of a form. With ?dry_run=true
+// the rows are only checked and the report lists their errors
+func importFile(c *gin.Context, db *gorm.DB, kind importer.Kind) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("dry_run"))
+		return
+	}
+	owner, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter("file").WithMessage("file is required"))
+			return
+		}
+		opened, err := header.Open()
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	report, err := importer.Import(db, kind, owner, file, dryRun)
+	if errors.Is(err, importer.ErrInvalidOwner) {
+		apierror.Abort(c, apierror.ErrForbidden.WithMessage(err.Error()))
+		return
+	}
+	if errors.Is(err, importer.ErrInvalidFile) {
+		apierror.Abort(c, apierror.InvalidParameter("file").WithMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to import "+string(kind)))
+		return
+	}
+	switch {
+	case dryRun:
+		c.JSON(http.StatusOK, report)
+	case !report.Valid():
+		apierror.Abort(c, apierror.ErrImportInvalid.WithDetails(report))
+	default:
+		c.JSON(http.StatusCreated, report)
+	}
+}
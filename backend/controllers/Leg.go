@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/distance"
+	"tms-backend/listing"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LegController struct {
+	Db *gorm.DB
+}
+
+// legListSpec : Filters and sorts of the leg list
+var legListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"from_checkpoint_id": {Column: "from_checkpoint_id", Kind: listing.KindUUID},
+		"to_checkpoint_id":   {Column: "to_checkpoint_id", Kind: listing.KindUUID},
+		"closed":             {Column: "closed", Kind: listing.KindBool},
+		"distance_km":        {Column: "distance_km", Kind: listing.KindNumber},
+		"duration_minutes":   {Column: "duration_minutes", Kind: listing.KindNumber},
+		"toll_cost":          {Column: "toll_cost", Kind: listing.KindNumber},
+	},
+	Sorts: map[string]string{
+		"distance_km":      "distance_km",
+		"duration_minutes": "duration_minutes",
+		"toll_cost":        "toll_cost",
+		"updated_at":       "updated_at",
+	},
+	DefaultSort: "distance_km",
+	Key:         "id",
+}
+
+// GetLegs : Get the legs of the road network
+//
+// @Summary      List legs
+// @Tags         legs
+// @Produce      json
+// @Param        checkpoint_id  query  string  false  "Only the legs starting or ending at this checkpoint"
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of legs to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Param        expand  query  string  false  "Associations to embed: from_checkpoint, to_checkpoint"
+// @Success      200  {array}   models.LegResponse
+// @Header       200  {integer}  X-Total-Count  "Number of legs matching the filters"
+// @Failure      400  "Invalid filter"
+// @Failure      500  "Unable to retrieve legs"
+// @Router       /legs [get]
+func (LegController *LegController) GetLegs(c *gin.Context) {
+	var legs []models.Leg
+	query, ok := listing.FromRequest(c, legListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.LegExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(LegController.Db)
+	if raw := c.Query("checkpoint_id"); raw != "" {
+		checkpointId, err := uuid.Parse(raw)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter("checkpoint_id"))
+			return
+		}
+		db = db.Where("? IN (from_checkpoint_id, to_checkpoint_id)", checkpointId)
+	}
+	total, err := listing.Find(db, query, &legs)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve legs"))
+		return
+	}
+	listing.Respond(c, query, total, models.LegResponses(legs, expand))
+}
+
+// GetLeg : Get a leg
+//
+// @Summary      Get leg by id
+// @Tags         legs
+// @Produce      json
+// @Param        leg_id  path  string  true  "Leg ID"
+// @Param        expand  query  string  false  "Associations to embed: from_checkpoint, to_checkpoint"
+// @Success      200  {object}  models.LegResponse
+// @Failure      400  "Invalid leg ID"
+// @Failure      404  "Leg not found"
+// @Router       /legs/{leg_id} [get]
+func (LegController *LegController) GetLeg(c *gin.Context) {
+	expand, ok := expansion(c, models.LegExpansions)
+	if !ok {
+		return
+	}
+	leg, ok := LegController.findLeg(c, expand.Preload(LegController.Db))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, leg.ToResponse(expand))
+}
+
+// CreateLeg : Connect two checkpoints
+//
+// @Summary      Create leg
+// @Description  the distance defaults to the road distance between the checkpoints, the duration to the distance at the configured average speed
+// @Tags         legs
+// @Accept       json
+// @Produce      json
+// @Param        from_checkpoint_id  body  string  true  "Checkpoint ID"
+// @Param        to_checkpoint_id  body  string  true  "Checkpoint ID"
+// @Param        distance_km  body  number  false  "Road distance"
+// @Param        duration_minutes  body  int  false  "Typical travel time"
+// @Param        toll_cost  body  number  false  "Tolls paid along the leg"
+// @Param        closed  body  bool  false  "Closed legs can not be used by new routes"
+// @Param        expand  query  string  false  "Associations to embed: from_checkpoint, to_checkpoint"
+// @Success      201  {object}  models.LegResponse
+// @Failure      400  "Invalid request"
+// @Failure      404  "Checkpoint not found"
+// @Failure      409  "Leg already exists"
+// @Failure      500  "Unable to create leg"
+// @Router       /legs [post]
+func (LegController *LegController) CreateLeg(c *gin.Context) {
+	expand, ok := expansion(c, models.LegExpansions)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		FromCheckpointId uuid.UUID `json:"from_checkpoint_id" binding:"required"`
+		ToCheckpointId   uuid.UUID `json:"to_checkpoint_id" binding:"required"`
+		DistanceKm       *float64  `json:"distance_km"`
+		DurationMinutes  *int64    `json:"duration_minutes"`
+		TollCost         float64   `json:"toll_cost"`
+		Closed           bool      `json:"closed"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	if requestBody.FromCheckpointId == requestBody.ToCheckpointId {
+		apierror.Abort(c, apierror.InvalidParameter("to_checkpoint_id").WithMessage("A leg connects two different checkpoints"))
+		return
+	}
+	var from, to models.Checkpoint
+	if err := from.FindById(LegController.Db, requestBody.FromCheckpointId); err != nil {
+		apierror.Abort(c, apierror.ErrCheckpointNotFound.WithDetails(gin.H{"checkpoint_id": requestBody.FromCheckpointId}))
+		return
+	}
+	if err := to.FindById(LegController.Db, requestBody.ToCheckpointId); err != nil {
+		apierror.Abort(c, apierror.ErrCheckpointNotFound.WithDetails(gin.H{"checkpoint_id": requestBody.ToCheckpointId}))
+		return
+	}
+	var existing models.Leg
+	if err := existing.FindBetween(LegController.Db, from.Id, to.Id); err == nil {
+		apierror.Abort(c, apierror.ErrLegExists.WithDetails(gin.H{"leg_id": existing.Id}))
+		return
+	}
+
+	leg := models.Leg{
+		FromCheckpointId: from.Id,
+		ToCheckpointId:   to.Id,
+		DistanceKm:       distance.RoadKm(from, to),
+		TollCost:         requestBody.TollCost,
+		Closed:           requestBody.Closed,
+	}
+	if requestBody.DistanceKm != nil {
+		leg.DistanceKm = *requestBody.DistanceKm
+	}
+	leg.DurationMinutes = distance.EstimatedMinutes(leg.DistanceKm)
+	if requestBody.DurationMinutes != nil {
+		leg.DurationMinutes = *requestBody.DurationMinutes
+	}
+	if !validateLeg(c, leg) {
+		return
+	}
+	if err := auth.RequestDb(c, LegController.Db).Create(&leg).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to create leg"))
+		return
+	}
+	if err := expand.Load(LegController.Db, &leg); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, leg.ToResponse(expand))
+}
+
+// UpdateLeg : Update a leg, only the fields sent are changed. Its checkpoints can not change, delete it and create another
+//
+// @Summary      Update leg
+// @Tags         legs
+// @Accept       json
+// @Produce      json
+// @Param        leg_id  path  string  true  "Leg ID"
+// @Param        distance_km  body  number  false  "Road distance"
+// @Param        duration_minutes  body  int  false  "Typical travel time"
+// @Param        toll_cost  body  number  false  "Tolls paid along the leg"
+// @Param        closed  body  bool  false  "Closed legs can not be used by new routes"
+// @Param        expand  query  string  false  "Associations to embed: from_checkpoint, to_checkpoint"
+// @Success      200  {object}  models.LegResponse
+// @Failure      400  "Invalid request"
+// @Failure      404  "Leg not found"
+// @Failure      500  "Unable to update leg"
+// @Router       /legs/{leg_id} [patch]
+func (LegController *LegController) UpdateLeg(c *gin.Context) {
+	expand, ok := expansion(c, models.LegExpansions)
+	if !ok {
+		return
+	}
+	leg, ok := LegController.findLeg(c, LegController.Db)
+	if !ok {
+		return
+	}
+	var requestBody struct {
+		DistanceKm      *float64 `json:"distance_km"`
+		DurationMinutes *int64   `json:"duration_minutes"`
+		TollCost        *float64 `json:"toll_cost"`
+		Closed          *bool    `json:"closed"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	if requestBody.DistanceKm != nil {
+		leg.DistanceKm = *requestBody.DistanceKm
+	}
+	if requestBody.DurationMinutes != nil {
+		leg.DurationMinutes = *requestBody.DurationMinutes
+	}
+	if requestBody.TollCost != nil {
+		leg.TollCost = *requestBody.TollCost
+	}
+	if requestBody.Closed != nil {
+		leg.Closed = *requestBody.Closed
+	}
+	if !validateLeg(c, leg) {
+		return
+	}
+	if err := auth.RequestDb(c, LegController.Db).Save(&leg).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to update leg"))
+		return
+	}
+	if err := expand.Load(LegController.Db, &leg); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, leg.ToResponse(expand))
+}
+
+// DeleteLeg : Delete a leg, existing routes keep going along it. Close it instead to keep it in the network history
+//
+// @Summary      Delete leg
+// @Tags         legs
+// @Produce      json
+// @Param        leg_id  path  string  true  "Leg ID"
+// @Success      200  {object}  models.LegResponse
+// @Failure      400  "Invalid leg ID"
+// @Failure      404  "Leg not found"
+// @Failure      500  "Unable to delete leg"
+// @Router       /legs/{leg_id} [delete]
+func (LegController *LegController) DeleteLeg(c *gin.Context) {
+	leg, ok := LegController.findLeg(c, LegController.Db)
+	if !ok {
+		return
+	}
+	if err := auth.RequestDb(c, LegController.Db).Delete(&leg).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to delete leg"))
+		return
+	}
+	c.JSON(http.StatusOK, leg.ToResponse(nil))
+}
+
+// findLeg : Load the leg of the path
+func (LegController *LegController) findLeg(c *gin.Context, db *gorm.DB) (models.Leg, bool) {
+	var leg models.Leg
+	legId, err := uuid.Parse(c.Param("leg_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("leg_id"))
+		return leg, false
+	}
+	if err := leg.FindById(db, legId); err != nil {
+		apierror.Abort(c, apierror.ErrLegNotFound)
+		return leg, false
+	}
+	return leg, true
+}
+
+// validateLeg : Check the distance, the duration and the toll cost
+func validateLeg(c *gin.Context, leg models.Leg) bool {
+	if leg.DistanceKm <= 0 {
+		apierror.Abort(c, apierror.InvalidParameter("distance_km").WithMessage("distance_km must be positive"))
+		return false
+	}
+	if leg.DurationMinutes <= 0 {
+		apierror.Abort(c, apierror.InvalidParameter("duration_minutes").WithMessage("duration_minutes must be positive"))
+		return false
+	}
+	if leg.TollCost < 0 {
+		apierror.Abort(c, apierror.InvalidParameter("toll_cost").WithMessage("toll_cost must not be negative"))
+		return false
+	}
+	return true
+}
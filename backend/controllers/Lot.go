@@ -3,7 +3,13 @@ package controllers
 import (
 	"net/http"
 	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/geojson"
+	"tms-backend/importer"
+	"tms-backend/listing"
 	"tms-backend/models"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,6 +20,56 @@ type LotController struct {
 	Db *gorm.DB
 }
 
+// lotListSpec : Filters and sorts of the lot lists
+var lotListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"state":                 {Column: "state", Kind: listing.KindString},
+		"resource_type":         {Column: "resource_type", Kind: listing.KindString},
+		"start_checkpoint_id":   {Column: "start_checkpoint_id", Kind: listing.KindUUID},
+		"end_checkpoint_id":     {Column: "end_checkpoint_id", Kind: listing.KindUUID},
+		"current_checkpoint_id": {Column: "current_checkpoint_id", Kind: listing.KindUUID},
+		"tractor_id":            {Column: "tractor_id", Kind: listing.KindUUID},
+		"in_tractor":            {Column: "in_tractor", Kind: listing.KindBool},
+		"volume":                {Column: "volume", Kind: listing.KindNumber},
+		"max_price_by_km":       {Column: "max_price_by_km", Kind: listing.KindNumber},
+		"created_at":            {Column: "created_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"created_at":      "created_at",
+		"state":           "state",
+		"resource_type":   "resource_type",
+		"volume":          "volume",
+		"max_price_by_km": "max_price_by_km",
+	},
+	DefaultSort: "created_at",
+	Key:         "id",
+}
+
+// lotBid is a bid of a client on lots, grouped by offer limit date and bid state
+type lotBid struct {
+	LimitDate    time.Time `json:"limit_date"`
+	MaxPriceByKm float64   `json:"max_price_by_km"`
+	CurrentPrice float64   `json:"current_price"`
+	State        string    `json:"state"`
+}
+
+// lotBidListSpec : Filters and sorts of the bids on lots, a date and a state identify a row
+var lotBidListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"state":           {Column: "state", Kind: listing.KindString},
+		"limit_date":      {Column: "limit_date", Kind: listing.KindTime},
+		"current_price":   {Column: "current_price", Kind: listing.KindNumber},
+		"max_price_by_km": {Column: "max_price_by_km", Kind: listing.KindNumber},
+	},
+	Sorts: map[string]string{
+		"limit_date":      "limit_date",
+		"current_price":   "current_price",
+		"max_price_by_km": "max_price_by_km",
+	},
+	DefaultSort: "limit_date",
+	Key:         "state",
+}
+
 // CreateLot : Create a new Lot
 //
 // @Summary      Create a new Lot
@@ -24,13 +80,14 @@ type LotController struct {
 // @Param        volume  body  float64  true  "Volume"
 // @Param        start_checkpoint_id  body  string  true  "Start Checkpoint Id"
 // @Param        end_checkpoint_id  body  string  true  "End Checkpoint Id"
-// @Param        owner_id  body  string  true  "Owner Id"
 // @Param        current_checkpoint_id  body  string  false  "Current Checkpoint Id"
 // @Param        state  body  string  true  "State"
 // @Param        max_price_by_km  body  float64  true  "Max Price By Km"
-// @Success      201  {object}  models.Lot
+// @Success      201  {object}  models.LotResponse
 // @Failure      400  "Invalid request payload"
+// @Failure      401  "Unauthorized"
 // @Failure      500  "Unable to create lot"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots [post]
 func (LotController *LotController) CreateLot(c *gin.Context) {
 	var requestBody struct {
@@ -38,20 +95,29 @@ func (LotController *LotController) CreateLot(c *gin.Context) {
 		Volume              float64             `json:"volume" binding:"required"`
 		StartCheckpointId   uuid.UUID           `json:"start_checkpoint_id" binding:"required"`
 		EndCheckpointId     uuid.UUID           `json:"end_checkpoint_id" binding:"required"`
-		OwnerId             uuid.UUID           `json:"owner_id" binding:"required"`
 		CurrentCheckpointId uuid.UUID           `json:"current_checkpoint_id"`
 		State               models.State        `json:"state" binding:"required"`
 		MaxPriceByKm        float64             `json:"max_price_by_km" binding:"required"`
 	}
 
+	owner, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
 		return
 	}
 
 	var simulation models.Simulation
-	if err := LotController.Db.First(&simulation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch simulation date"})
+	if err := auth.TenantDb(c, LotController.Db).First(&simulation).Error; err != nil {
+		apierror.Abort(c, apierror.ErrSimulationNotFound.WithCause(err))
 		return
 	}
 
@@ -62,22 +128,43 @@ func (LotController *LotController) CreateLot(c *gin.Context) {
 		EndCheckpointId:     &requestBody.EndCheckpointId,
 		CurrentCheckpointId: &requestBody.CurrentCheckpointId,
 		CreatedAt:           simulation.SimulationDate,
-		OwnerId:             requestBody.OwnerId,
+		OwnerId:             owner.Id,
 		State:               requestBody.State,
 		MaxPriceByKm:        requestBody.MaxPriceByKm,
 	}
 
-	if err := LotController.Db.Create(&LotModel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.TenantDb(c, LotController.Db).Create(&LotModel).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	if err := LotController.Db.Preload("StartCheckpoint").Preload("EndCheckpoint").Preload("Tractor").Preload("Owner").Preload("TrafficManager").Preload("Trader").First(&LotModel, "id = ?", LotModel.Id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := expand.Load(auth.TenantDb(c, LotController.Db), &LotModel); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, LotModel)
+	c.JSON(http.StatusCreated, LotModel.ToResponse(expand))
+}
+
+// ImportLots : Create lots owned by the user from a CSV file
+//
+// @Summary      Import lots from CSV
+// @Description  checks every row as CreateLot does, checkpoints are given by id, by name, by \"name, country\" or by latitude and longitude within 10 km. The first line names the columns: resource_type, volume, state, max_price_by_km, start_checkpoint, end_checkpoint and optionally current_checkpoint, the start by default. Each checkpoint column may be replaced by <prefix>_latitude and <prefix>_longitude. Every row is checked first, the file is imported in one transaction only when all of them are valid, otherwise nothing is and the error details list the problems by line
+// @Tags         lots
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  false  "CSV file, or send it as the body"
+// @Param        dry_run  query  bool  false  "Only check the rows and report their errors"
+// @Success      200  {object}  importer.Report  "Dry run"
+// @Success      201  {object}  importer.Report  "Rows imported"
+// @Failure      400  "Invalid file, or IMPORT_INVALID with the report as details"
+// @Failure      401  "Unauthorized"
+// @Failure      403  "The caller is not a client of an organization"
+// @Failure      500  "Unable to import lots"
+// @Router       /lots/import [post]
+func (LotController *LotController) ImportLots(c *gin.Context) {
+	importFile(c, auth.TenantDb(c, LotController.Db), importer.KindLots)
 }
 
 // ListLotsByOwner : List all lots by owner
@@ -87,29 +174,48 @@ func (LotController *LotController) CreateLot(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        owner_id  path  string  true  "Owner Id"
-// @Success      200  {array}  models.Lot
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of lots to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order, like -volume,created_at"
+// @Success      200  {array}  models.LotResponse
+// @Header       200  {integer}  X-Total-Count  "Number of lots matching the filters"
 // @Failure      400  "Invalid owner_id"
 // @Failure      500  "Unable to retrieve lots"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/owner/{owner_id} [get]
 func (LotController *LotController) ListLotsByOwner(c *gin.Context) {
 	var lots []models.Lot
-	var lotModel models.Lot
 	ownerId := c.Param("owner_id")
 	ownerIdUUID, errIdUUID := uuid.Parse(ownerId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
 		return
 	}
 
-	lots, err := lotModel.GetLotsByOwner(LotController.Db, ownerIdUUID)
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, ownerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
 
+	query, ok := listing.FromRequest(c, lotListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, LotController.Db)).
+		Where("owner_id = ?", ownerIdUUID)
+	total, err := listing.Find(db, query, &lots)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, lots)
+	listing.Respond(c, query, total, models.LotResponses(lots, expand))
 }
 
 // IsCompatible : Check if a lot is compatible with a tractor
@@ -133,32 +239,33 @@ func (LotController *LotController) IsCompatible(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	// Get the Lot
 	var lot models.Lot
-	if err := LotController.Db.First(&lot, "id = ?", requestBody.LotId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+	if err := auth.TenantDb(c, LotController.Db).First(&lot, "id = ?", requestBody.LotId).Error; err != nil {
+		apierror.Abort(c, apierror.ErrLotNotFound)
 		return
 	}
 
 	// Get the tractor
 	var tractor models.Tractor
-	if err := LotController.Db.First(&tractor, "id = ?", requestBody.TractorId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+	if err := auth.TenantDb(c, LotController.Db).First(&tractor, "id = ?", requestBody.TractorId).Error; err != nil {
+		apierror.Abort(c, apierror.ErrTractorNotFound)
 		return
 	}
 
 	// Check if there is enough space
 	if lot.Volume > (tractor.MaxVolume - tractor.CurrentVolume) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lot exceeds tractor's capacity"})
+		apierror.Abort(c, apierror.ErrTractorCapacityExceeded.WithDetails(gin.H{"volume": lot.Volume, "remaining_volume": tractor.MaxVolume - tractor.CurrentVolume}))
 		return
 	}
 
 	if lot.ResourceType != tractor.ResourceType {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lot is not the same resource type as the tractor"})
+		apierror.Abort(c, apierror.ErrResourceTypeMismatch.WithDetails(gin.H{"lot_resource_type": lot.ResourceType, "tractor_resource_type": tractor.ResourceType}))
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Lot is compatible with the tractor"})
@@ -172,10 +279,15 @@ func (LotController *LotController) IsCompatible(c *gin.Context) {
 // @Produce      json
 // @Param        lot_id  body  string  true  "Lot Id"
 // @Param        state  body  string  true  "State"
-// @Success      200  {object}  models.Lot
+// @Success      200  {object}  models.LotResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Lot not found"
 // @Failure      500  "Unable to update lot state"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the lot"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/state [put]
 func (LotController *LotController) UpdateLotState(c *gin.Context) {
 	var requestBody struct {
@@ -184,25 +296,48 @@ func (LotController *LotController) UpdateLotState(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
 		return
 	}
 
 	// Get the Lot
 	var lot models.Lot
-	if err := LotController.Db.First(&lot, "id = ?", requestBody.LotId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+	if err := auth.TenantDb(c, LotController.Db).First(&lot, "id = ?", requestBody.LotId).Error; err != nil {
+		apierror.Abort(c, apierror.ErrLotNotFound)
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanChangeLotState(user, lot) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if err := models.ExpectVersion("lots", lot.Id, lot.Version, version); err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
 	lot.State = requestBody.State
-	// Change the state of the Lot
-	if err := LotController.Db.Save(&lot).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Change the state of the Lot, refused when it changed since it was read
+	if err := auth.TenantDb(c, LotController.Db).Save(&lot).Error; err != nil {
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, lot)
+	if err := expand.Load(auth.TenantDb(c, LotController.Db), &lot); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, lot.Version)
+	c.JSON(http.StatusOK, lot.ToResponse(expand))
 }
 
 // AssociateToTrafficManager : Associate a lot to a traffic manager
@@ -213,12 +348,18 @@ func (LotController *LotController) UpdateLotState(c *gin.Context) {
 // @Produce      json
 // @Param        lot_id  body  string  true  "Lot Id"
 // @Param        traffic_manager_id  body  string  true  "Traffic Manager Id"
-// @Success      200  {object}  models.Lot
+// @Success      200  {object}  models.LotResponse
 // @Failure      400  "Invalid lot_id"
 // @Failure      400  "Invalid traffic_manager_id"
 // @Failure      404  "Lot not found"
+// @Failure      404  "Traffic manager not found in the organization of the lot"
 // @Failure      500  "Unable to update traffic_manager"
 // @Failure      500  "Unable to update state"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the lot"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/traffic_manager [post]
 func (LotController *LotController) AssociateToTrafficManager(c *gin.Context) {
 	var requestBody struct {
@@ -226,34 +367,43 @@ func (LotController *LotController) AssociateToTrafficManager(c *gin.Context) {
 		TrafficManagerId string `json:"traffic_manager_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 	var lotIdUUID, errIdUUID = uuid.Parse(requestBody.LotId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lot_id"})
+		apierror.Abort(c, apierror.InvalidParameter("lot_id"))
 		return
 	}
 	var trafficManagerIdUUID, errTrafficManagerIdUUID = uuid.Parse(requestBody.TrafficManagerId)
 	if errTrafficManagerIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid traffic_manager_id"})
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
 		return
 	}
 
-	var lot models.Lot
-	lot, err := lot.FindById(LotController.Db, lotIdUUID)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+		apierror.Abort(c, err)
 		return
 	}
-	lot.TrafficManagerId = &trafficManagerIdUUID
-	lot.State = models.StatePending
-	if err := lot.Save(LotController.Db); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error updating traffic_manager": err.Error()})
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, lot)
+	user, _ := auth.CurrentUser(c)
+	lot, err := services.AssociateLotToTrafficManager(auth.TenantDb(c, LotController.Db), user, lotIdUUID, trafficManagerIdUUID, version)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+
+	if err := expand.Load(auth.TenantDb(c, LotController.Db), &lot); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, lot.Version)
+	c.JSON(http.StatusOK, lot.ToResponse(expand))
 }
 
 // DeleteLot : Delete a lot
@@ -266,25 +416,31 @@ func (LotController *LotController) AssociateToTrafficManager(c *gin.Context) {
 // @Failure      400  "Invalid lot_id"
 // @Failure      404  "Lot not found"
 // @Failure      500  "Unable to delete lot"
+// @Failure      403  "Forbidden"
 // @Router       /lots/{lot_id} [delete]
 func (LotController *LotController) DeleteLot(c *gin.Context) {
 	lotId := c.Param("lot_id")
 	lotIdUUID, errIdUUID := uuid.Parse(lotId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lot_id"})
+		apierror.Abort(c, apierror.InvalidParameter("lot_id"))
 		return
 	}
 
 	var lot models.Lot
-	lot, err := lot.FindById(LotController.Db, lotIdUUID)
+	lot, err := lot.FindById(auth.TenantDb(c, LotController.Db), lotIdUUID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lot not found"})
+		apierror.Abort(c, apierror.ErrLotNotFound)
 		return
 	}
 
-	if err := LotController.Db.Delete(&lot).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if user, _ := auth.CurrentUser(c); !auth.CanManageLot(user, lot) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	if err := auth.TenantDb(c, LotController.Db).Delete(&lot).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
@@ -298,36 +454,143 @@ func (LotController *LotController) DeleteLot(c *gin.Context) {
 //	@Accept       json
 //	@Produce      json
 //	@Param        traffic_manager_id  path  string  true  "Traffic Manager ID"
-//	@Success      200  {array}  models.Lot
+//	@Param        limit  query  int  false  "Page size, 100 by default"
+//	@Param        offset  query  int  false  "Number of lots to skip"
+//	@Param        sort  query  string  false  "Sort keys, - for a descending order"
+//	@Success      200  {array}  models.LotResponse
+//	@Header       200  {integer}  X-Total-Count  "Number of lots matching the filters"
 //	@Failure      400  {object}  error
 //	@Failure      404  {object}  error
 //	@Failure      500  {object}  error
+//	@Failure      403  {object}  error
+//	@Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 //	@Router       /lots/traffic_manager/{traffic_manager_id} [get]
 func (LotController *LotController) ListLotsByTrafficManager(c *gin.Context) {
 	var lots []models.Lot
-	var lotModel models.Lot
 	trafficManagerId := c.Param("traffic_manager_id")
 	ownerIdUUID, errIdUUID := uuid.Parse(trafficManagerId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid traffic_manager_id"})
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, ownerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
 	var trafficManager models.User
-	if err := LotController.Db.First(&trafficManager, "id = ? AND role = ?", ownerIdUUID, "traffic_manager").Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Traffic Manager not found"})
+	if err := auth.TenantDb(c, LotController.Db).First(&trafficManager, "id = ? AND role = ?", ownerIdUUID, "traffic_manager").Error; err != nil {
+		apierror.Abort(c, apierror.ErrTrafficManagerNotFound)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, lotListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, LotController.Db)).
+		Where("traffic_manager_id = ?", ownerIdUUID)
+	total, err := listing.Find(db, query, &lots)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	lots, err := lotModel.GetLotsByTrafficManager(LotController.Db, ownerIdUUID)
+	listing.Respond(c, query, total, models.LotResponses(lots, expand))
+}
 
+// ListLotsWithin : List the lots currently within a radius of a point
+//
+//	@Summary      List the lots within a radius
+//	@Description  lots whose current checkpoint is at most radius_km from a point by great-circle distance, among those the user owns, manages or sells
+//	@Tags         lots
+//	@Produce      json
+//	@Param        latitude  query  number  true  "Latitude, between -90 and 90"
+//	@Param        longitude  query  number  true  "Longitude, between -180 and 180"
+//	@Param        radius_km  query  number  true  "Radius in km, up to 5000"
+//	@Param        limit  query  int  false  "Page size, 100 by default"
+//	@Param        offset  query  int  false  "Number of lots to skip"
+//	@Param        sort  query  string  false  "Sort keys, - for a descending order"
+//	@Success      200  {array}  models.LotResponse
+//	@Header       200  {integer}  X-Total-Count  "Number of lots matching the filters"
+//	@Failure      400  "Invalid coordinates or radius"
+//	@Failure      500  "Unable to retrieve lots"
+//	@Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
+//	@Router       /lots/within [get]
+func (LotController *LotController) ListLotsWithin(c *gin.Context) {
+	checkpointIds, ok := checkpointsWithin(c, auth.RequestDb(c, LotController.Db))
+	if !ok {
+		return
+	}
+	query, ok := listing.FromRequest(c, lotListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
+		return
+	}
+	user, _ := auth.CurrentUser(c)
+	var lots []models.Lot
+	db := involving(expand.Preload(auth.TenantDb(c, LotController.Db)), user).
+		Where("current_checkpoint_id IN ?", checkpointIds)
+	total, err := listing.Find(db, query, &lots)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve lots"))
+		return
+	}
+
+	listing.Respond(c, query, total, models.LotResponses(lots, expand))
+}
+
+// GetLotFlowsGeoJSON : Get the lots in transit as GeoJSON lines
+//
+//	@Summary      Get the lot flows as GeoJSON
+//	@Description  a FeatureCollection of LineString features from the start to the end checkpoint of every lot in transit the user owns, manages or sells. Properties are state, resource_type, volume, tractor_id, traffic_manager_id and the start, end and current checkpoint ids
+//	@Tags         lots
+//	@Produce      json
+//	@Param        traffic_manager_id  query  string  false  "Only the lots of this traffic manager"
+//	@Success      200  {object}  geojson.FeatureCollection
+//	@Failure      400  "Invalid traffic_manager_id"
+//	@Failure      500  "Unable to retrieve lots"
+//	@Router       /lots/geojson [get]
+func (LotController *LotController) GetLotFlowsGeoJSON(c *gin.Context) {
+	user, _ := auth.CurrentUser(c)
+	db, ok := byTrafficManager(c, involving(auth.TenantDb(c, LotController.Db), user))
+	if !ok {
+		return
+	}
+	var lots []models.Lot
+	if err := db.Preload("StartCheckpoint").Preload("EndCheckpoint").Where("state = ?", models.StateInTransit).
+		Order("created_at, id").Find(&lots).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve lots"))
 		return
 	}
 
-	c.JSON(http.StatusOK, lots)
+	features := make([]geojson.Feature, 0, len(lots))
+	for _, lot := range lots {
+		if lot.StartCheckpoint == nil || lot.EndCheckpoint == nil {
+			continue
+		}
+		line := geojson.LineString([]models.Checkpoint{*lot.StartCheckpoint, *lot.EndCheckpoint})
+		features = append(features, geojson.NewFeature(lot.Id.String(), line, map[string]interface{}{
+			"state":                 lot.State,
+			"resource_type":         lot.ResourceType,
+			"volume":                lot.Volume,
+			"tractor_id":            lot.TractorId,
+			"traffic_manager_id":    lot.TrafficManagerId,
+			"start_checkpoint_id":   lot.StartCheckpointId,
+			"end_checkpoint_id":     lot.EndCheckpointId,
+			"current_checkpoint_id": lot.CurrentCheckpointId,
+		}))
+	}
+	c.JSON(http.StatusOK, geojson.Collection(features))
 }
 
 // ListCompatibleTractorsForLot : Get all compatible tractors for a lot
@@ -338,12 +601,18 @@ func (LotController *LotController) ListLotsByTrafficManager(c *gin.Context) {
 // @Produce      json
 // @Param        lot_id  path  string  true  "Lot Id"
 // @Param        traffic_manager_id  path  string  true  "Traffic Manager Id"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of compatible tractors matching the filters"
 // @Failure      400  "Invalid lot_id"
 // @Failure      400  "Invalid traffic_manager_id"
 // @Failure      404  "Lot not found"
 // @Failure      404  "Traffic Manager not found"
 // @Failure      500  "Unable to retrieve tractors"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /lots/tractors/compatible/{traffic_manager_id}/{lot_id} [get]
 func (LotController *LotController) ListCompatibleTractorsForLot(c *gin.Context) {
 	lotId := c.Param("lot_id")
@@ -351,80 +620,63 @@ func (LotController *LotController) ListCompatibleTractorsForLot(c *gin.Context)
 
 	lotIdUUID, errLotId := uuid.Parse(lotId)
 	if errLotId != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lot_id"})
+		apierror.Abort(c, apierror.InvalidParameter("lot_id"))
 		return
 	}
 
 	trafficManagerIdUUID, errTrafficManagerId := uuid.Parse(trafficManagerId)
 	if errTrafficManagerId != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid traffic_manager_id"})
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
 		return
 	}
 
-	var lot models.Lot
-	if err := LotController.Db.First(&lot, "id = ?", lotIdUUID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, trafficManagerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
-	var trafficManager models.User
-	if err := LotController.Db.First(&trafficManager, "id = ? AND role = ?", trafficManagerIdUUID, "traffic_manager").Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Traffic Manager not found"})
+	var lot models.Lot
+	if err := auth.TenantDb(c, LotController.Db).First(&lot, "id = ?", lotIdUUID).Error; err != nil {
+		apierror.Abort(c, apierror.ErrLotNotFound)
 		return
 	}
 
-	var tractors []models.Tractor
-	if err := LotController.Db.Preload("EndCheckpoint").Preload("Route").Preload("StartCheckpoint").Preload("CurrentCheckpoint").Preload("TrafficManager").Where("traffic_manager_id = ?", trafficManagerIdUUID).Find(&tractors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve tractors"})
+	if user, _ := auth.CurrentUser(c); !auth.CanManageLot(user, lot) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
-	var compatibleTractors []models.Tractor
-	for _, tractor := range tractors {
-		if LotController.checkCompatibility(lot, tractor) {
-			compatibleTractors = append(compatibleTractors, tractor)
-		}
+	var trafficManager models.User
+	if err := auth.TenantDb(c, LotController.Db).First(&trafficManager, "id = ? AND role = ?", trafficManagerIdUUID, "traffic_manager").Error; err != nil {
+		apierror.Abort(c, apierror.ErrTrafficManagerNotFound)
+		return
 	}
 
-	c.JSON(http.StatusOK, compatibleTractors)
-}
-
-// checkCompatibility : Check if a lot is compatible with a tractor
-func (LotController *LotController) checkCompatibility(lot models.Lot, tractor models.Tractor) bool {
-	if tractor.RouteId == nil {
-		return false
-	}
-	if lot.ResourceType != tractor.ResourceType {
-		return false
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
 	}
-	if tractor.State != models.StatePending {
-		return false
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
 	}
 
-	return LotController.checkTractorCheckpointCompatibility(lot, tractor)
-}
-
-func (LotController *LotController) checkTractorCheckpointCompatibility(lot models.Lot, tractor models.Tractor) bool {
-	var currentRouteCheckpoint models.RouteCheckpoint
-	var lotRouteCheckpoint models.RouteCheckpoint
-	if err := currentRouteCheckpoint.GetRouteCheckpoint(LotController.Db, *tractor.RouteId, *tractor.CurrentCheckpointId); err != nil {
-		return false
-	}
-	if lot.ResourceType != tractor.ResourceType {
-		return false
-	}
-	if err := lotRouteCheckpoint.GetRouteCheckpoint(LotController.Db, *tractor.RouteId, *lot.CurrentCheckpointId); err != nil {
-		return false
-	}
-	if currentRouteCheckpoint.Position > lotRouteCheckpoint.Position {
-		return false
+	// Compatibility is checked in Go, so the filters and the order run in SQL and the page is cut afterwards
+	var tractors []models.Tractor
+	db := expand.Preload(auth.TenantDb(c, LotController.Db)).Where("traffic_manager_id = ?", trafficManagerIdUUID)
+	if err := query.Sort(query.Filter(db)).Find(&tractors).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve tractors"))
+		return
 	}
-	volumAtCheckpoint, err := tractor.GetVolumeAtCheckpoint(LotController.Db, *lot.StartCheckpointId)
-	if err != nil {
-		return false
+
+	compatibleTractors := []models.TractorResponse{}
+	for _, tractor := range tractors {
+		if services.IsCompatible(auth.TenantDb(c, LotController.Db), lot, tractor) {
+			compatibleTractors = append(compatibleTractors, tractor.ToResponse(expand))
+		}
 	}
-	var remainingVolume = tractor.MaxVolume - volumAtCheckpoint
-	return remainingVolume >= lot.Volume
+
+	listing.Respond(c, query, int64(len(compatibleTractors)), listing.Slice(compatibleTractors, query))
 }
 
 // AssignTractorToLot : Assign a tractor to a lot
@@ -434,11 +686,16 @@ func (LotController *LotController) checkTractorCheckpointCompatibility(lot mode
 // @Produce      json
 // @Param        lot_id  body  string  true  "Lot Id"
 // @Param        tractor_id  body  string  true  "Tractor Id"
-// @Success      200  {object}  models.Lot
+// @Success      200  {object}  models.LotResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Lot not found"
 // @Failure      404  "Tractor not found"
 // @Failure      500  "Unable to assign tractor to lot"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the lot"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/assign [put]
 func (LotController *LotController) AssignTractorToLot(c *gin.Context) {
 	var requestBody struct {
@@ -447,97 +704,31 @@ func (LotController *LotController) AssignTractorToLot(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Get the Lot
-	var lot models.Lot
-	if err := LotController.Db.First(&lot, "id = ?", requestBody.LotId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
-		return
-	}
-
-	// Get the tractor
-	var tractor models.Tractor
-	if err := LotController.Db.First(&tractor, "id = ?", requestBody.TractorId).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tractor not found"})
-		return
-	}
-
-	if !LotController.checkCompatibility(lot, tractor) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lot is not compatible with the tractor"})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-
-	var transactionIn models.Transaction
-	var transactionOut models.Transaction
-
-	var routeCheckpointStart models.RouteCheckpoint
-	var routeCheckpointEnd models.RouteCheckpoint
-	if err := routeCheckpointStart.GetRouteCheckpoint(LotController.Db, *tractor.RouteId, *lot.StartCheckpointId); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
-	if err := routeCheckpointEnd.GetRouteCheckpoint(LotController.Db, *tractor.RouteId, *lot.EndCheckpointId); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
 		return
 	}
 
-	if err := transactionIn.CreateTransaction(LotController.Db, models.TransactionState(models.TransactionStateIn), lot.Id, tractor.Id, *tractor.RouteId, *lot.StartCheckpointId, *lot.TrafficManagerId, routeCheckpointStart.Id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if err := transactionOut.CreateTransaction(LotController.Db, models.TransactionState(models.TransactionStateOut), lot.Id, tractor.Id, *tractor.RouteId, *lot.EndCheckpointId, *lot.TrafficManagerId, routeCheckpointEnd.Id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, _ := auth.CurrentUser(c)
+	lot, err := services.AssignTractorToLot(auth.TenantDb(c, LotController.Db), user, requestBody.LotId, requestBody.TractorId, version)
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
-
-	if err := lot.AssociateTractor(LotController.Db, tractor.Id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := expand.Load(auth.TenantDb(c, LotController.Db), &lot); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	if lot.StartCheckpointId.String() == tractor.CurrentCheckpointId.String() {
-		lot.InTractor = true
-		lot.State = models.StateInTransit
-		tractor.CurrentVolume += lot.Volume
-		lot.Update(LotController.Db)
-		tractor.Update(LotController.Db)
-	}
-	c.JSON(http.StatusOK, lot)
-}
-
-func (LotController *LotController) GetAvailableTrader(c *gin.Context) (models.User, error) {
-	var user models.User
-	traders, err := user.FindByRole(LotController.Db, models.RoleTrader)
-	if err != nil {
-		return models.User{}, err
-	}
-
-	var lots []models.Lot
-	if err := LotController.Db.Where("state = ?", models.StateAtTrader).Find(&lots).Error; err != nil {
-		return models.User{}, err
-	}
-
-	traderCounts := make(map[uuid.UUID]int)
-	for _, lot := range lots {
-		if lot.TraderId != nil {
-			traderCounts[*lot.TraderId]++
-		}
-	}
-
-	var selectedTrader models.User
-	minCount := len(lots)
-
-	for _, trader := range traders {
-		count := traderCounts[trader.Id]
-		if count <= minCount {
-			minCount = count
-			selectedTrader = trader
-		}
-	}
-
-	return selectedTrader, nil
+	setETag(c, lot.Version)
+	c.JSON(http.StatusOK, lot.ToResponse(expand))
 }
 
 // AssignTraderToLot : Assign a trader to a lot
@@ -548,36 +739,22 @@ func (LotController *LotController) GetAvailableTrader(c *gin.Context) (models.U
 // @Produce      json
 // @Param        lot_id  body  string  true  "Lot Id"
 // @Param        trader_id  body  string  true  "Trader Id"
-// @Success      200  {object}  models.Lot
+// @Success      200  {object}  models.LotResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Lot not found"
 // @Failure      404  "Trader not found"
 // @Failure      500  "Unable to assign trader to lot"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the lot"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/assign/{lot_id}/trader [post]
 func (LotController *LotController) AssignTraderToLot(c *gin.Context) {
 	lotId := c.Param("lot_id")
 	lotIdUUID, errIdUUID := uuid.Parse(lotId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lot_id"})
-		return
-	}
-
-	var lot models.Lot
-	if err := LotController.Db.First(&lot, "id = ?", lotIdUUID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
-		return
-	}
-
-	trader, err := LotController.GetAvailableTrader(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	lot.TraderId = &trader.Id
-	lot.State = models.StateAtTrader
-	if err := LotController.Db.Save(&lot).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidParameter("lot_id"))
 		return
 	}
 
@@ -586,22 +763,38 @@ func (LotController *LotController) AssignTraderToLot(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-	var offer models.Offer
 	parsedDate, err := time.Parse(time.RFC3339, requestBody.Date)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		apierror.Abort(c, apierror.InvalidParameter("limit_date"))
 		return
 	}
-	_, err = offer.CreateOfferLot(LotController.Db, parsedDate, lot.Id)
+
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
+		return
+	}
+
+	user, _ := auth.CurrentUser(c)
+	lot, err := services.AssignTraderToLot(auth.TenantDb(c, LotController.Db), user, lotIdUUID, parsedDate, version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, lot)
+	if err := expand.Load(auth.TenantDb(c, LotController.Db), &lot); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, lot.Version)
+	c.JSON(http.StatusOK, lot.ToResponse(expand))
 }
 
 // GetAllLotTraderId : Get all lots with trader id
@@ -611,10 +804,16 @@ func (LotController *LotController) AssignTraderToLot(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        trader_id  path  string  true  "Trader Id"
-// @Success      200  {array}  models.Lot
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of lots to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.LotResponse
+// @Header       200  {integer}  X-Total-Count  "Number of lots matching the filters"
 // @Failure      400  "Invalid trader_id"
 // @Failure      404  "Trader not found"
 // @Failure      500  "Unable to retrieve lots"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, tractor, owner, traffic_manager, trader"
 // @Router       /lots/trader/{trader_id} [get]
 func (LotController *LotController) GetAllLotTraderId(c *gin.Context) {
 	var lots []models.Lot
@@ -623,37 +822,51 @@ func (LotController *LotController) GetAllLotTraderId(c *gin.Context) {
 
 	// Validate trader_id
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trader_id"})
+		apierror.Abort(c, apierror.InvalidParameter("trader_id"))
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, traderIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
 	// Check if the trader exists
 	var trader models.User
-	if err := LotController.Db.First(&trader, "id = ? AND role = ?", traderIdUUID, "trader").Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Trader not found"})
+	if err := auth.TenantDb(c, LotController.Db).First(&trader, "id = ? AND role = ?", traderIdUUID, "trader").Error; err != nil {
+		apierror.Abort(c, apierror.ErrTraderNotFound)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, lotListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.LotExpansions)
+	if !ok {
 		return
 	}
 
 	// Retrieve lots for the trader
-	if err := LotController.Db.Preload("StartCheckpoint").
-		Preload("EndCheckpoint").
-		Where("trader_id = ?", traderIdUUID).
-		Find(&lots).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve lots", "details": err.Error()})
+	db := expand.Preload(auth.TenantDb(c, LotController.Db)).
+		Where("trader_id = ?", traderIdUUID)
+	total, err := listing.Find(db, query, &lots)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve lots"))
 		return
 	}
 
-	// Enrich the lots with current prices and limit_date from associated offers
+	// Enrich the lots of the page with current prices and limit_date from associated offers
 	for i := range lots {
 		var maxBid float64
 		var offer models.Offer
-		if err := LotController.Db.First(&offer, "lot_id = ?", lots[i].Id).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve offer", "details": err.Error()})
+		if err := auth.TenantDb(c, LotController.Db).First(&offer, "lot_id = ?", lots[i].Id).Error; err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve offer"))
 			return
 		}
 		// Get the maximum bid for the offer
-		if err := LotController.Db.Raw("SELECT COALESCE(MAX(bid), 0) FROM bids WHERE offer_id = ?", offer.Id).Scan(&maxBid).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve max bid", "details": err.Error()})
+		if err := auth.TenantDb(c, LotController.Db).Raw("SELECT COALESCE(MAX(bid), 0) FROM bids WHERE offer_id = ?", offer.Id).Scan(&maxBid).Error; err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve max bid"))
 			return
 		}
 		lots[i].CurrentPrice = maxBid
@@ -661,7 +874,7 @@ func (LotController *LotController) GetAllLotTraderId(c *gin.Context) {
 	}
 
 	// Return the enriched lots in the JSON response
-	c.JSON(http.StatusOK, lots)
+	listing.Respond(c, query, total, models.LotResponses(lots, expand))
 }
 
 // GetLotBidByOwnerId : Get all bids for a lot by owner id
@@ -671,27 +884,38 @@ func (LotController *LotController) GetAllLotTraderId(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        client_id  path  string  true  "Client Id"
-// @Success      200  {array}  models.Bid
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of bids to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  lotBid
+// @Header       200  {integer}  X-Total-Count  "Number of bids matching the filters"
 // @Failure      400  "Invalid client_id"
 // @Failure      500  "Unable to retrieve bids"
+// @Failure      403  "Forbidden"
 // @Router       /lots/bids/{client_id} [get]
 func (LotController *LotController) GetLotBidByOwnerId(c *gin.Context) {
-	var result []struct {
-		LimitDate    time.Time `json:"limit_date"`
-		MaxPriceByKm float64   `json:"max_price_by_km"`
-		CurrentPrice float64   `json:"current_price"`
-		State        string    `json:"state"`
-	}
+	var result []lotBid
 
 	clientId := c.Param("owner_id")
 	ownerID, errIdUUID := uuid.Parse(clientId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
 		return
 	}
 
-	query := `
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, ownerID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, lotBidListSpec)
+	if !ok {
+		return
+	}
+
+	// The grouped bids are a subquery so the filters apply to its columns
+	bids := `
     SELECT offers.limit_date AS limit_date,
            MAX(lots.max_price_by_km) AS max_price_by_km,
            MAX(bids.bid) AS current_price,
@@ -699,30 +923,16 @@ func (LotController *LotController) GetLotBidByOwnerId(c *gin.Context) {
     FROM bids
     JOIN offers ON offers.id = bids.offer_id
     JOIN lots ON offers.lot_id = lots.id
-    WHERE bids.owner_id = $1
-    GROUP BY offers.limit_date, bids.state;
+    WHERE bids.owner_id = ?
+    GROUP BY offers.limit_date, bids.state
 `
 
-	rows, err := LotController.Db.Raw(query, ownerID).Rows()
+	db := auth.TenantDb(c, LotController.Db)
+	total, err := listing.Find(db.Table("(?) AS lot_bids", db.Raw(bids, ownerID)), query, &result)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve lots"})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve lots"))
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var record struct {
-			LimitDate    time.Time `json:"limit_date"`
-			MaxPriceByKm float64   `json:"max_price_by_km"`
-			CurrentPrice float64   `json:"current_price"`
-			State        string    `json:"state"`
-		}
-		if err := rows.Scan(&record.LimitDate, &record.MaxPriceByKm, &record.CurrentPrice, &record.State); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to scan lot results"})
-			return
-		}
-		result = append(result, record)
-	}
 
-	c.JSON(http.StatusOK, result)
+	listing.Respond(c, query, total, result)
 }
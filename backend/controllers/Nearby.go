@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"strconv"
+	"tms-backend/apierror"
+	"tms-backend/distance"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxRadiusKm : Largest radius searched, enough to cross the continent
+	maxRadiusKm = 5000
+	// defaultNearest, maxNearest : Number of checkpoints the nearest search answers by default and at most
+	defaultNearest = 5
+	maxNearest     = 100
+)
+
+// nearbyCheckpoint : A checkpoint with its great-circle distance to the point searched
+type nearbyCheckpoint struct {
+	models.CheckpointResponse
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// point : Read the latitude and longitude query parameters
+func point(c *gin.Context) (float64, float64, bool) {
+	latitude, ok := coordinate(c, "latitude", 90)
+	if !ok {
+		return 0, 0, false
+	}
+	longitude, ok := coordinate(c, "longitude", 180)
+	if !ok {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
+
+func coordinate(c *gin.Context, param string, bound float64) (float64, bool) {
+	value, err := strconv.ParseFloat(c.Query(param), 64)
+	if err != nil || value < -bound || value > bound {
+		apierror.Abort(c, apierror.InvalidParameter(param).
+			WithMessage(param+" is required, between "+strconv.FormatFloat(-bound, 'f', -1, 64)+" and "+strconv.FormatFloat(bound, 'f', -1, 64)))
+		return 0, false
+	}
+	return value, true
+}
+
+// radius : Read the radius_km query parameter
+func radius(c *gin.Context) (float64, bool) {
+	radiusKm, err := strconv.ParseFloat(c.Query("radius_km"), 64)
+	if err != nil || radiusKm <= 0 || radiusKm > maxRadiusKm {
+		apierror.Abort(c, apierror.InvalidParameter("radius_km").
+			WithMessage("radius_km is required, above 0 and up to "+strconv.Itoa(maxRadiusKm)))
+		return 0, false
+	}
+	return radiusKm, true
+}
+
+// checkpointsWithin : The ids of the checkpoints within the radius of the point of the query
+func checkpointsWithin(c *gin.Context, db *gorm.DB) ([]uuid.UUID, bool) {
+	latitude, longitude, ok := point(c)
+	if !ok {
+		return nil, false
+	}
+	radiusKm, ok := radius(c)
+	if !ok {
+		return nil, false
+	}
+	var checkpoints []models.Checkpoint
+	if err := db.Find(&checkpoints).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+		return nil, false
+	}
+	ids := []uuid.UUID{}
+	for _, nearby := range distance.Around(checkpoints, latitude, longitude, radiusKm) {
+		ids = append(ids, nearby.Checkpoint.Id)
+	}
+	return ids, true
+}
+
+// involving : Keep the lots or tractors the user owns, manages or sells, admins keep all of them
+func involving(db *gorm.DB, user models.User) *gorm.DB {
+	if user.Role == models.RoleAdmin {
+		return db
+	}
+	return db.Where("(owner_id = ? OR traffic_manager_id = ? OR trader_id = ?)", user.Id, user.Id, user.Id)
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrganizationController struct {
+	Db *gorm.DB
+}
+
+// organizationListSpec : Filters and sorts of the organization list
+var organizationListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name":       {Column: "name", Kind: listing.KindString},
+		"created_at": {Column: "created_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "name",
+	Key:         "id",
+}
+
+// GetOrganizations : Get organizations
+//
+// @Summary      Get all organizations
+// @Tags         organizations
+// @Produce      json
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of organizations to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.OrganizationResponse
+// @Header       200  {integer}  X-Total-Count  "Number of organizations matching the filters"
+// @Failure      500  "Unable to retrieve organizations"
+// @Router       /organizations [get]
+func (OrganizationController *OrganizationController) GetOrganizations(c *gin.Context) {
+	var organizations []models.Organization
+	query, ok := listing.FromRequest(c, organizationListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(auth.RequestDb(c, OrganizationController.Db), query, &organizations)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve organizations"))
+		return
+	}
+	listing.Respond(c, query, total, models.ToResponses(organizations, (*models.Organization).ToResponse))
+}
+
+// CreateOrganization : Create an organization
+//
+// @Summary      Create organization
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization  body  models.Organization  true  "Organization"
+// @Success      201  {object}  models.OrganizationResponse
+// @Failure      400  "Invalid request"
+// @Failure      409  "Organization already exists"
+// @Failure      500  "Unable to create organization"
+// @Router       /organizations [post]
+func (OrganizationController *OrganizationController) CreateOrganization(c *gin.Context) {
+	var requestBody struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+
+	var organization models.Organization
+	if err := organization.FindByName(auth.RequestDb(c, OrganizationController.Db), requestBody.Name); err == nil {
+		apierror.Abort(c, apierror.ErrOrganizationExists)
+		return
+	}
+	organization = models.Organization{Name: requestBody.Name}
+	if err := organization.Create(auth.RequestDb(c, OrganizationController.Db)); err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to create organization"))
+		return
+	}
+	c.JSON(http.StatusCreated, organization.ToResponse())
+}
+
+// GetOrganization : Get an organization
+//
+// @Summary      Get organization by id
+// @Tags         organizations
+// @Produce      json
+// @Param        id  path  string  true  "Organization ID"
+// @Success      200  {object}  models.OrganizationResponse
+// @Failure      400  "Invalid organization ID"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Organization not found"
+// @Router       /organizations/{id} [get]
+func (OrganizationController *OrganizationController) GetOrganization(c *gin.Context) {
+	organization, ok := OrganizationController.findOrganization(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, organization.ToResponse())
+}
+
+// GetOrganizationMembers : Get the users of an organization
+//
+// @Summary      Get organization members
+// @Tags         organizations
+// @Produce      json
+// @Param        id  path  string  true  "Organization ID"
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of members to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.UserResponse
+// @Header       200  {integer}  X-Total-Count  "Number of members matching the filters"
+// @Failure      400  "Invalid organization ID"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Organization not found"
+// @Failure      500  "Unable to retrieve members"
+// @Router       /organizations/{id}/members [get]
+func (OrganizationController *OrganizationController) GetOrganizationMembers(c *gin.Context) {
+	organization, ok := OrganizationController.findOrganization(c)
+	if !ok {
+		return
+	}
+	query, ok := listing.FromRequest(c, userListSpec)
+	if !ok {
+		return
+	}
+	var members []models.User
+	total, err := listing.Find(auth.RequestDb(c, OrganizationController.Db).Where("organization_id = ?", organization.Id), query, &members)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve members"))
+		return
+	}
+	listing.Respond(c, query, total, models.ToResponses(members, (*models.User).ToResponse))
+}
+
+// findOrganization : Load the organization of the path, only its members and admins may see it
+func (OrganizationController *OrganizationController) findOrganization(c *gin.Context) (models.Organization, bool) {
+	var organization models.Organization
+	organizationId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("organization_id"))
+		return organization, false
+	}
+	if user, _ := auth.CurrentUser(c); !auth.CanAccessOrganization(user, organizationId) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return organization, false
+	}
+	if err := organization.FindById(auth.RequestDb(c, OrganizationController.Db), organizationId); err != nil {
+		apierror.Abort(c, apierror.ErrOrganizationNotFound)
+		return organization, false
+	}
+	return organization, true
+}
@@ -2,7 +2,15 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/geojson"
+	"tms-backend/listing"
 	"tms-backend/models"
+	"tms-backend/routing"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,28 +21,95 @@ type RouteController struct {
 	Db *gorm.DB
 }
 
+// routeListSpec : Filters and sorts of the route lists
+var routeListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name":               {Column: "name", Kind: listing.KindString},
+		"traffic_manager_id": {Column: "traffic_manager_id", Kind: listing.KindUUID},
+	},
+	Sorts: map[string]string{
+		"name": "name",
+	},
+	DefaultSort: "name",
+	Key:         "id",
+}
+
 type checkpointPosition struct {
 	CheckpointId string `json:"checkpoint_id" binding:"required"`
 	Position     uint   `json:"position" binding:"required"`
 }
 
+// routeSuggestion : Best route found, Route is ready to be sent to CreateRoute
+type routeSuggestion struct {
+	Objective       routing.Objective     `json:"objective"`
+	Route           []checkpointPosition  `json:"route"`
+	LegIds          []uuid.UUID           `json:"leg_ids"`
+	DistanceKm      float64               `json:"distance_km"`
+	DurationMinutes int64                 `json:"duration_minutes"`
+	TollCost        float64               `json:"toll_cost"`
+	SavedRoute      *models.RouteResponse `json:"saved_route,omitempty"`
+}
+
+// routePlan : Routes proposed to the traffic manager, posting Routes back to AcceptPlan carries the plan out
+type routePlan struct {
+	Routes         []plannedRoute  `json:"routes"`
+	UnassignedLots []unassignedLot `json:"unassigned_lots"`
+	DistanceKm     float64         `json:"distance_km"`
+}
+
+// plannedRoute : Route of a tractor in a plan, Stops tell what is picked up and dropped off along Route
+type plannedRoute struct {
+	TractorId       uuid.UUID            `json:"tractor_id"`
+	Route           []checkpointPosition `json:"route"`
+	LotIds          []uuid.UUID          `json:"lot_ids"`
+	Stops           []plannedStop        `json:"stops"`
+	DistanceKm      float64              `json:"distance_km"`
+	DurationMinutes int64                `json:"duration_minutes"`
+}
+
+// plannedStop : Load is the volume the tractor carries when it leaves the checkpoint
+type plannedStop struct {
+	CheckpointId   uuid.UUID   `json:"checkpoint_id"`
+	PickupLotIds   []uuid.UUID `json:"pickup_lot_ids"`
+	DeliveryLotIds []uuid.UUID `json:"delivery_lot_ids"`
+	Load           float64     `json:"load"`
+}
+
+type unassignedLot struct {
+	LotId  uuid.UUID      `json:"lot_id"`
+	Reason routing.Reason `json:"reason"`
+}
+
 // GetAllRoutes : Get all routes
 //
 // @Summary      Get all routes
 // @Tags         routes
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   models.Route
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of routes to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.RouteResponse
+// @Header       200  {integer}  X-Total-Count  "Number of routes matching the filters"
 // @Failure      400  "Unable to retrieve routes"
+// @Param        expand  query  string  false  "Associations to embed: traffic_manager"
 // @Router       /routes [get]
 func (RouteController *RouteController) GetAllRoutes(c *gin.Context) {
-	var routeModel models.Route
 	var routes []models.Route
-	routes, err := routeModel.GetAllRoutes(RouteController.Db)
+	query, ok := listing.FromRequest(c, routeListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.RouteExpansions)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(expand.Preload(auth.TenantDb(c, RouteController.Db)), query, &routes)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
+		return
 	}
-	c.JSON(http.StatusOK, routes)
+	listing.Respond(c, query, total, models.RouteResponses(routes, expand))
 }
 
 type routePayload struct {
@@ -50,20 +125,40 @@ type routePayload struct {
 // @Accept       json
 // @Produce      json
 // @Param        traffic_manager_id  path  string  true  "Traffic Manager Id"
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of routes to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
 // @Success      200  {array}   routePayload
+// @Header       200  {integer}  X-Total-Count  "Number of routes matching the filters"
 // @Failure      400  "Unable to retrieve routes"
+// @Failure      403  "Forbidden"
 // @Router       /routes/traffic_manager/{traffic_manager_id} [get]
 func (RouteController *RouteController) GetRouteStringByTrafficManagerId(c *gin.Context) {
-	var allRoutes []routePayload
-	var routeModel models.Route
+	allRoutes := []routePayload{}
 	var routes []models.Route
 
 	trafficManagerId := c.Param("traffic_manager_id")
-	trafficManagerIdUUID := uuid.MustParse(trafficManagerId)
-	routes, err := routeModel.GetRoutesByTrafficManagerId(RouteController.Db, trafficManagerIdUUID)
+	trafficManagerIdUUID, errIdUUID := uuid.Parse(trafficManagerId)
+	if errIdUUID != nil {
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
+		return
+	}
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, trafficManagerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	query, ok := listing.FromRequest(c, routeListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(auth.TenantDb(c, RouteController.Db).Where("traffic_manager_id = ?", trafficManagerIdUUID), query, &routes)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
 
 	for _, route := range routes {
-		var path_string = route.GetRouteString(RouteController.Db)
+		var path_string = route.GetRouteString(auth.TenantDb(c, RouteController.Db))
 		var route_payload = routePayload{
 			Id:         route.Id.String(),
 			Name:       route.Name,
@@ -71,10 +166,7 @@ func (RouteController *RouteController) GetRouteStringByTrafficManagerId(c *gin.
 		}
 		allRoutes = append(allRoutes, route_payload)
 	}
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	}
-	c.JSON(http.StatusOK, allRoutes)
+	listing.Respond(c, query, total, allRoutes)
 }
 
 // GetRouteByTrafficManagerId : Get route by traffic manager id
@@ -82,12 +174,17 @@ func (RouteController *RouteController) GetRouteByTrafficManagerId(c *gin.Contex
 	var routeModel models.Route
 	var routes []models.Route
 	trafficManagerId := c.Param("traffic_manager_id")
-	trafficManagerIdUUID := uuid.MustParse(trafficManagerId)
-	routes, err := routeModel.GetRoutesByTrafficManagerId(RouteController.Db, trafficManagerIdUUID)
+	trafficManagerIdUUID, errIdUUID := uuid.Parse(trafficManagerId)
+	if errIdUUID != nil {
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
+		return
+	}
+	routes, err := routeModel.GetRoutesByTrafficManagerId(auth.TenantDb(c, RouteController.Db), trafficManagerIdUUID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
+		return
 	}
-	c.JSON(http.StatusOK, routes)
+	c.JSON(http.StatusOK, models.RouteResponses(routes, nil))
 }
 
 // CreateRoute : Create a new Route
@@ -96,38 +193,441 @@ func (RouteController *RouteController) GetRouteByTrafficManagerId(c *gin.Contex
 // @Tags         routes
 // @Accept       json
 // @Produce      json
+// @Description  the route belongs to the caller. traffic_manager_id may only name the caller, except for admins who create routes for any traffic manager, the route then joins the organization of that traffic manager
 // @Param        name  body  string  true  "Name"
-// @Param        traffic_manager_id  body  string  true  "Traffic Manager Id"
-// @Param        route  body  []checkpointPosition  true  "Route"
+// @Param        traffic_manager_id  body  string  false  "Traffic manager owning the route, the caller by default"
+// @Param        route  body  []checkpointPosition  true  "Route, at least two distinct checkpoints at consecutive positions"
 // @Success      201  "Route created"
-// @Failure      400  "Invalid request payload"
+// @Failure      400  "Invalid request payload, invalid stops, or no open leg between two consecutive checkpoints"
+// @Failure      401  "Unauthorized"
+// @Failure      403  "traffic_manager_id is another user and the caller is not an admin"
+// @Failure      404  "Checkpoint or traffic manager not found"
 // @Router       /routes [post]
 func (RouteController *RouteController) CreateRoute(c *gin.Context) {
 	var requestBody struct {
 		Name             string               `json:"name" binding:"required"`
-		TrafficManagerId uuid.UUID            `json:"traffic_manager_id" binding:"required"`
+		TrafficManagerId *uuid.UUID           `json:"traffic_manager_id"`
 		Route            []checkpointPosition `json:"route" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	stops, ok := routeStops(c, requestBody.Route)
+	if !ok {
 		return
 	}
 	var routeModel models.Route
-	routeModel.Id = uuid.New()
 	routeModel.Name = requestBody.Name
-	routeModel.TrafficManagerId = requestBody.TrafficManagerId
-	routeModel.SaveRoute(RouteController.Db)
-	for _, checkpoint := range requestBody.Route {
-		var routeCheckpointModel models.RouteCheckpoint
-		routeCheckpointModel.RouteId = routeModel.Id
-		var checkpointId uuid.UUID = uuid.MustParse(checkpoint.CheckpointId)
-		routeCheckpointModel.CheckpointId = checkpointId
-		routeCheckpointModel.Position = checkpoint.Position
-		routeCheckpointModel.SaveRouteCheckpoint(RouteController.Db)
+	routeModel.TrafficManagerId = user.Id
+	if requestBody.TrafficManagerId != nil && *requestBody.TrafficManagerId != user.Id {
+		if !auth.CanActAs(user, *requestBody.TrafficManagerId) {
+			apierror.Abort(c, apierror.ErrForbidden)
+			return
+		}
+		var trafficManager models.User
+		if err := auth.TenantDb(c, RouteController.Db).First(&trafficManager, "id = ? AND role = ?", *requestBody.TrafficManagerId, models.RoleTrafficManager).Error; err != nil {
+			apierror.Abort(c, apierror.ErrTrafficManagerNotFound)
+			return
+		}
+		// Admins are not scoped to a tenant, the route joins the one of its traffic manager
+		routeModel.TrafficManagerId = trafficManager.Id
+		routeModel.OrganizationId = trafficManager.OrganizationId
+	}
+	if _, err := services.CreateRoute(auth.TenantDb(c, RouteController.Db), routeModel, stops); err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
 	}
 	c.Status(http.StatusCreated)
 }
 
+// UpdateRoute : Rename a route or replace its stops
+//
+// @Summary      Update a route
+// @Description  only the fields sent are changed. The stops of a route followed by a tractor or referenced by transactions can not change, unbind the tractors or create another route
+// @Tags         routes
+// @Accept       json
+// @Produce      json
+// @Param        route_id  path  string  true  "Route ID"
+// @Param        name  body  string  false  "Name"
+// @Param        route  body  []checkpointPosition  false  "New stops, at least two distinct checkpoints at consecutive positions"
+// @Success      200  {object}  models.RouteResponse
+// @Failure      400  "Invalid request payload, invalid stops, or no open leg between two consecutive checkpoints"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route or checkpoint not found"
+// @Failure      409  "Route in use, the details hold its usage"
+// @Router       /routes/{route_id} [patch]
+func (RouteController *RouteController) UpdateRoute(c *gin.Context) {
+	routeId, err := uuid.Parse(c.Param("route_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("route_id"))
+		return
+	}
+	var requestBody struct {
+		Name  *string              `json:"name"`
+		Route []checkpointPosition `json:"route" binding:"omitempty,dive"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	trafficManager, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	var stops []services.RouteStop
+	if requestBody.Route != nil {
+		if stops, ok = routeStops(c, requestBody.Route); !ok {
+			return
+		}
+	}
+
+	route, err := services.UpdateRoute(auth.TenantDb(c, RouteController.Db), trafficManager, routeId, requestBody.Name, stops)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	c.JSON(http.StatusOK, route.ToResponse(nil))
+}
+
+// DeleteRoute : Delete a route no tractor follows and no transaction refers to
+//
+// @Summary      Delete a route
+// @Tags         routes
+// @Produce      json
+// @Param        route_id  path  string  true  "Route ID"
+// @Success      200  {object}  models.RouteResponse
+// @Failure      400  "Invalid route_id"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route not found"
+// @Failure      409  "Route in use, the details hold its usage"
+// @Router       /routes/{route_id} [delete]
+func (RouteController *RouteController) DeleteRoute(c *gin.Context) {
+	routeId, err := uuid.Parse(c.Param("route_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("route_id"))
+		return
+	}
+	trafficManager, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+	route, err := services.DeleteRoute(auth.TenantDb(c, RouteController.Db), trafficManager, routeId)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	c.JSON(http.StatusOK, route.ToResponse(nil))
+}
+
+// routeStops : The stops of the request, services.CreateRoute and services.UpdateRoute check their positions
+func routeStops(c *gin.Context, route []checkpointPosition) ([]services.RouteStop, bool) {
+	stops := make([]services.RouteStop, len(route))
+	for i, checkpoint := range route {
+		checkpointId, err := uuid.Parse(checkpoint.CheckpointId)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter("checkpoint_id"))
+			return nil, false
+		}
+		stops[i] = services.RouteStop{CheckpointId: checkpointId, Position: checkpoint.Position}
+	}
+	return stops, true
+}
+
+// SuggestRoute : Propose the best route between two checkpoints, and save it when asked
+//
+// @Summary      Suggest a route
+// @Description  best route over the open legs from one checkpoint to another through the stops, in the best order unless keep_order is set. The objective is distance (default), duration or cost (tolls). With save, the route is created under name in the same call
+// @Tags         routes
+// @Accept       json
+// @Produce      json
+// @Param        from_checkpoint_id  body  string  true  "Start checkpoint ID"
+// @Param        to_checkpoint_id  body  string  true  "End checkpoint ID"
+// @Param        stops  body  []string  false  "Checkpoint IDs the route must go through"
+// @Param        keep_order  body  bool  false  "Visit the stops in the order given"
+// @Param        objective  body  string  false  "distance, duration or cost"
+// @Param        save  body  bool  false  "Create the route"
+// @Param        name  body  string  false  "Name of the route, required to save it"
+// @Success      200  {object}  routeSuggestion
+// @Success      201  {object}  routeSuggestion  "Route created"
+// @Failure      400  "Invalid request payload, or no route connects the checkpoints"
+// @Failure      401  "Unauthorized"
+// @Failure      404  "Checkpoint not found"
+// @Router       /routes/suggestions [post]
+func (RouteController *RouteController) SuggestRoute(c *gin.Context) {
+	var requestBody struct {
+		FromCheckpointId uuid.UUID         `json:"from_checkpoint_id" binding:"required"`
+		ToCheckpointId   uuid.UUID         `json:"to_checkpoint_id" binding:"required"`
+		Stops            []uuid.UUID       `json:"stops"`
+		KeepOrder        bool              `json:"keep_order"`
+		Objective        routing.Objective `json:"objective"`
+		Save             bool              `json:"save"`
+		Name             string            `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	if requestBody.Objective == "" {
+		requestBody.Objective = routing.ObjectiveDistance
+	}
+	if !slices.Contains(routing.Objectives, requestBody.Objective) {
+		apierror.Abort(c, apierror.InvalidParameter("objective").WithMessage("objective must be one of distance, duration, cost"))
+		return
+	}
+	if requestBody.FromCheckpointId == requestBody.ToCheckpointId {
+		apierror.Abort(c, apierror.InvalidParameter("to_checkpoint_id").WithMessage("A route ends at another checkpoint than its start"))
+		return
+	}
+	if !requestBody.KeepOrder && len(requestBody.Stops) > routing.MaxStops {
+		apierror.Abort(c, apierror.InvalidParameter("stops").
+			WithMessage("The order of at most "+strconv.Itoa(routing.MaxStops)+" stops is optimised, set keep_order for more"))
+		return
+	}
+	if requestBody.Save && requestBody.Name == "" {
+		apierror.Abort(c, apierror.InvalidParameter("name").WithMessage("name is required to save the route"))
+		return
+	}
+	trafficManager, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	path, err := services.SuggestRoute(auth.RequestDb(c, RouteController.Db), requestBody.FromCheckpointId, requestBody.ToCheckpointId,
+		requestBody.Stops, requestBody.Objective, requestBody.KeepOrder)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	suggestion := routeSuggestion{
+		Objective:       requestBody.Objective,
+		Route:           make([]checkpointPosition, len(path.Checkpoints)),
+		LegIds:          make([]uuid.UUID, len(path.Legs)),
+		DistanceKm:      path.DistanceKm(),
+		DurationMinutes: path.DurationMinutes(),
+		TollCost:        path.TollCost(),
+	}
+	stops := make([]services.RouteStop, len(path.Checkpoints))
+	for i, checkpointId := range path.Checkpoints {
+		// Positions start at 1, CreateRoute requires them
+		suggestion.Route[i] = checkpointPosition{CheckpointId: checkpointId.String(), Position: uint(i + 1)}
+		stops[i] = services.RouteStop{CheckpointId: checkpointId, Position: uint(i + 1)}
+	}
+	for i, leg := range path.Legs {
+		suggestion.LegIds[i] = leg.Id
+	}
+	if !requestBody.Save {
+		c.JSON(http.StatusOK, suggestion)
+		return
+	}
+
+	route := models.Route{Name: requestBody.Name, TrafficManagerId: trafficManager.Id}
+	route, err = services.CreateRoute(auth.TenantDb(c, RouteController.Db), route, stops)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	savedRoute := route.ToResponse(nil)
+	suggestion.SavedRoute = &savedRoute
+	c.JSON(http.StatusCreated, suggestion)
+}
+
+// PlanRoutes : Propose routes and lot assignments for the pending lots on the pending tractors without a route
+//
+// @Summary      Plan routes for the pending lots
+// @Description  gives the pending lots of the traffic manager to its pending tractors without a route, minimising the total distance. A tractor carries only its resource type, never more than its capacity, picks a lot up before dropping it off and goes once through a checkpoint. Nothing is saved, post the routes to /routes/plans/accept to carry the plan out
+// @Tags         routes
+// @Produce      json
+// @Success      200  {object}  routePlan
+// @Failure      401  "Unauthorized"
+// @Failure      500  "Unable to plan routes"
+// @Router       /routes/plans [post]
+func (RouteController *RouteController) PlanRoutes(c *gin.Context) {
+	trafficManager, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	plan, err := services.PlanRoutes(auth.TenantDb(c, RouteController.Db), trafficManager.Id)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to plan routes"))
+		return
+	}
+	response := routePlan{
+		Routes:         make([]plannedRoute, len(plan.Assignments)),
+		UnassignedLots: make([]unassignedLot, len(plan.Unplanned)),
+		DistanceKm:     plan.DistanceKm(),
+	}
+	for i, assignment := range plan.Assignments {
+		route := plannedRoute{
+			TractorId:       assignment.VehicleId,
+			Route:           make([]checkpointPosition, len(assignment.Path.Checkpoints)),
+			LotIds:          []uuid.UUID{},
+			Stops:           make([]plannedStop, len(assignment.Stops)),
+			DistanceKm:      assignment.Path.DistanceKm(),
+			DurationMinutes: assignment.Path.DurationMinutes(),
+		}
+		for j, checkpointId := range assignment.Path.Checkpoints {
+			route.Route[j] = checkpointPosition{CheckpointId: checkpointId.String(), Position: uint(j + 1)}
+		}
+		for j, stop := range assignment.Stops {
+			route.LotIds = append(route.LotIds, stop.Pickups...)
+			route.Stops[j] = plannedStop{CheckpointId: stop.CheckpointId, PickupLotIds: stop.Pickups, DeliveryLotIds: stop.Deliveries, Load: stop.Load}
+		}
+		response.Routes[i] = route
+	}
+	for i, unplanned := range plan.Unplanned {
+		response.UnassignedLots[i] = unassignedLot{LotId: unplanned.ShipmentId, Reason: unplanned.Reason}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AcceptPlan : Carry out a plan, every route is created and bound to its tractor which gets its lots
+//
+// @Summary      Accept a plan
+// @Description  takes the routes of a plan from /routes/plans, edited or not. Everything is checked again and saved at once, or nothing is when a check fails
+// @Tags         routes
+// @Accept       json
+// @Produce      json
+// @Param        routes  body  []plannedRoute  true  "Routes of the plan, each with tractor_id, route, lot_ids and an optional name"
+// @Success      201  {array}  models.RouteResponse
+// @Failure      400  "Invalid request payload, or a lot does not fit its route"
+// @Failure      401  "Unauthorized"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Tractor or lot not found"
+// @Failure      409  "A tractor or a lot changed since the plan was made"
+// @Router       /routes/plans/accept [post]
+func (RouteController *RouteController) AcceptPlan(c *gin.Context) {
+	var requestBody struct {
+		Routes []struct {
+			TractorId uuid.UUID            `json:"tractor_id" binding:"required"`
+			Name      string               `json:"name"`
+			Route     []checkpointPosition `json:"route" binding:"required,dive"`
+			LotIds    []uuid.UUID          `json:"lot_ids"`
+		} `json:"routes" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	trafficManager, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	plannedRoutes := make([]services.PlannedRoute, len(requestBody.Routes))
+	for i, route := range requestBody.Routes {
+		slices.SortStableFunc(route.Route, func(a, b checkpointPosition) int { return int(a.Position) - int(b.Position) })
+		plannedRoutes[i] = services.PlannedRoute{TractorId: route.TractorId, Name: route.Name, LotIds: route.LotIds}
+		for _, checkpoint := range route.Route {
+			checkpointId, err := uuid.Parse(checkpoint.CheckpointId)
+			if err != nil {
+				apierror.Abort(c, apierror.InvalidParameter("checkpoint_id"))
+				return
+			}
+			plannedRoutes[i].CheckpointIds = append(plannedRoutes[i].CheckpointIds, checkpointId)
+		}
+	}
+
+	routes, err := services.AcceptPlan(auth.TenantDb(c, RouteController.Db), trafficManager, plannedRoutes)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err))
+		return
+	}
+	c.JSON(http.StatusCreated, models.RouteResponses(routes, nil))
+}
+
+// GetRouteGeoJSON : Get a route as a GeoJSON line
+//
+// @Summary      Get a route as GeoJSON
+// @Description  a LineString feature through the checkpoints of the route by position, with its name, traffic_manager_id and checkpoint_ids as properties
+// @Tags         routes
+// @Produce      json
+// @Param        route_id  path  string  true  "Route ID"
+// @Success      200  {object}  geojson.Feature
+// @Failure      400  "Invalid route_id"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route not found"
+// @Failure      500  "Unable to retrieve checkpoints"
+// @Router       /routes/{route_id}/geojson [get]
+func (RouteController *RouteController) GetRouteGeoJSON(c *gin.Context) {
+	routeIdUUID, err := uuid.Parse(c.Param("route_id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("route_id"))
+		return
+	}
+	var route models.Route
+	if err := route.GetById(auth.TenantDb(c, RouteController.Db), routeIdUUID); err != nil {
+		apierror.Abort(c, apierror.ErrRouteNotFound)
+		return
+	}
+	if !RouteController.canView(c, route) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	features, err := routeFeatures(auth.TenantDb(c, RouteController.Db), []models.Route{route})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve checkpoints"))
+		return
+	}
+	c.JSON(http.StatusOK, features[0])
+}
+
+// GetRoutesGeoJSON : Get routes as GeoJSON lines
+//
+// @Summary      Get routes as GeoJSON
+// @Description  a FeatureCollection of the routes the user manages or follows with a tractor, each a LineString as in /routes/{route_id}/geojson
+// @Tags         routes
+// @Produce      json
+// @Param        traffic_manager_id  query  string  false  "Only the routes of this traffic manager"
+// @Success      200  {object}  geojson.FeatureCollection
+// @Failure      400  "Invalid traffic_manager_id"
+// @Failure      500  "Unable to retrieve routes"
+// @Router       /routes/geojson [get]
+func (RouteController *RouteController) GetRoutesGeoJSON(c *gin.Context) {
+	db, ok := byTrafficManager(c, auth.TenantDb(c, RouteController.Db))
+	if !ok {
+		return
+	}
+	if user, _ := auth.CurrentUser(c); user.Role != models.RoleAdmin {
+		db = db.Where("(traffic_manager_id = ? OR id IN (SELECT route_id FROM tractors WHERE owner_id = ?))", user.Id, user.Id)
+	}
+	var routes []models.Route
+	if err := db.Order("name, id").Find(&routes).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve routes"))
+		return
+	}
+
+	features, err := routeFeatures(auth.TenantDb(c, RouteController.Db), routes)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve routes"))
+		return
+	}
+	c.JSON(http.StatusOK, geojson.Collection(features))
+}
+
+// canView : Traffic managers see their routes, clients only the routes followed by their own tractors
+func (RouteController *RouteController) canView(c *gin.Context, route models.Route) bool {
+	user, _ := auth.CurrentUser(c)
+	if auth.CanManageRoute(user, route) {
+		return true
+	}
+	var boundTractors int64
+	auth.TenantDb(c, RouteController.Db).Model(&models.Tractor{}).Where("route_id = ? AND owner_id = ?", route.Id, user.Id).Count(&boundTractors)
+	return boundTractors > 0
+}
+
 // GetCheckpointsByRouteId : Get checkpoints by route id
 //
 // @Summary      Get checkpoints by route id
@@ -135,23 +635,36 @@ func (RouteController *RouteController) CreateRoute(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        route_id  path  string  true  "Route ID"
-// @Success      200  {array}   models.RouteCheckpoint
+// @Success      200  {array}   models.RouteCheckpointResponse
 // @Failure      400  "Unable to retrieve checkpoints"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route not found"
 // @Router       /routes/{route_id}/checkpoints [get]
 func (RouteController *RouteController) GetCheckpointsByRouteId(c *gin.Context) {
 	routeId := c.Param("route_id")
 	routeIdUUID, err := uuid.Parse(routeId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route ID"})
+		apierror.Abort(c, apierror.InvalidParameter("route_id"))
+		return
+	}
+
+	var route models.Route
+	if err := route.GetById(auth.TenantDb(c, RouteController.Db), routeIdUUID); err != nil {
+		apierror.Abort(c, apierror.ErrRouteNotFound)
+		return
+	}
+
+	if !RouteController.canView(c, route) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
 	var routeCheckpointModel models.RouteCheckpoint
-	checkpoints, err := routeCheckpointModel.GetRouteCheckpointsByRouteId(RouteController.Db, routeIdUUID)
+	checkpoints, err := routeCheckpointModel.GetRouteCheckpointsByRouteId(auth.TenantDb(c, RouteController.Db), routeIdUUID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, checkpoints)
+	c.JSON(http.StatusOK, models.ToResponses(checkpoints, (*models.RouteCheckpoint).ToResponse))
 }
\ No newline at end of file
@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
 	"tms-backend/models"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -27,8 +29,8 @@ func (SimulationController *SimulationController) GetSimulationDate(c *gin.Conte
 	var simulation models.Simulation
 
 	// Get simulation date from database
-	if err := SimulationController.Db.First(&simulation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch simulation date"})
+	if err := auth.RequestDb(c, SimulationController.Db).First(&simulation).Error; err != nil {
+		apierror.Abort(c, apierror.ErrSimulationNotFound.WithCause(err))
 		return
 	}
 
@@ -47,27 +49,12 @@ func (SimulationController *SimulationController) GetSimulationDate(c *gin.Conte
 // @Failure      500  "Unable to update simulation date"
 // @Router       /simulation/date [put]
 func (SimulationController *SimulationController) UpdateSimulationDate(c *gin.Context) {
-	var simulation models.Simulation
-
-	// Get the current simulation date from the database
-	if err := SimulationController.Db.First(&simulation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch simulation date"})
-		return
-	}
-
-	// Increase simulation date by one day
-	newDate := simulation.SimulationDate.AddDate(0, 0, 1)
-
-	// Update simulation date in database with ID-based WHERE condition
-	if err := SimulationController.Db.Model(&simulation).Where("id = ?", simulation.ID).Update("simulation_date", newDate).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update simulation date"})
+	newDate, err := services.AdvanceSimulationDate(auth.RequestDb(c, SimulationController.Db))
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	// Update the state of the offers
-	stockExchangeController := StockExchangeController{Db: SimulationController.Db}
-	stockExchangeController.ChangeStateToReturnFromMarket2(c)
-
 	// Return the new updated date
 	c.JSON(http.StatusOK, gin.H{
 		"message":         "Simulation date updated successfully",
@@ -76,80 +63,9 @@ func (SimulationController *SimulationController) UpdateSimulationDate(c *gin.Co
 }
 
 func (SimulationController *SimulationController) MoveTractorForward(c *gin.Context) {
-	var tractorModel models.Tractor
-	var tractors []models.Tractor
-	var err error
-	tractors, err = tractorModel.GetByState(SimulationController.Db, "in_transit")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch tractors"})
-	}
-	for _, tractor := range tractors {
-
-		// get les trucs a get
-		var currentRouteCheckpoint models.RouteCheckpoint
-		if err := currentRouteCheckpoint.GetRouteCheckpoint(SimulationController.Db, *tractor.RouteId, *tractor.CurrentCheckpointId); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch route checkpoint"})
-			return
-		}
-		var nextRouteCheckpoint models.RouteCheckpoint
-		if err := nextRouteCheckpoint.GetNextCheckpoint(SimulationController.Db, *tractor.RouteId, currentRouteCheckpoint.Position); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch next route checkpoint"})
-			return
-		}
-
-		UpdateTractorCheckpoint(SimulationController.Db, c, tractor, currentRouteCheckpoint, nextRouteCheckpoint)
-		UpdateLotCheckpoint(SimulationController.Db, c, tractor.Id, nextRouteCheckpoint.CheckpointId)
-		ExecAllTransactions(SimulationController.Db, nextRouteCheckpoint.CheckpointId, tractor.Id, *tractor.RouteId, c)
-
-		// update lot checkpoint
-
-	}
-}
-
-func UpdateTractorCheckpoint(db *gorm.DB, c *gin.Context, tractor models.Tractor, currentRouteCheckpoint models.RouteCheckpoint, nextRouteCheckpoint models.RouteCheckpoint) {
-	tractor.CurrentCheckpointId = &nextRouteCheckpoint.CheckpointId
-	var lastCheckpointPosition uint
-	db.Raw("select max(position) from route_checkpoints where route_id = ?", tractor.RouteId).Scan(&lastCheckpointPosition)
-	if nextRouteCheckpoint.Position == lastCheckpointPosition {
-		tractor.State = models.StateArchive
-	}
-	if err := tractor.Update(db); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save tractor"})
-		return
-	}
-}
-
-func UpdateLotCheckpoint(db *gorm.DB, c *gin.Context, tractorId uuid.UUID, newCheckpointId uuid.UUID) {
-	var lot models.Lot
-	var lots []models.Lot
-	var err error
-	lots, err = lot.GetAllInTractorByTracorId(db, tractorId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch lots"})
+	if err := services.MoveTractors(auth.RequestDb(c, SimulationController.Db)); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	for _, lot := range lots {
-		if lot.InTractor {
-			lot.CurrentCheckpointId = &newCheckpointId
-			if err := lot.Update(db); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save lot"})
-				return
-			}
-		}
-	}
-}
-
-func ExecAllTransactions(db *gorm.DB, checkpointId uuid.UUID, tractorId uuid.UUID, routeId uuid.UUID, c *gin.Context) {
-	var transactionModel models.Transaction
-	var transactions []models.Transaction
-	var err error
-	transactions, err = transactionModel.FindByRouteIdAndCheckpointIdAndTractorId(db, routeId, checkpointId, tractorId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch transactions"})
-	}
-	for _, transaction := range transactions {
-		if err := transaction.ExecTransaction(db); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to execute transaction"})
-		}
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tractors moved forward"})
 }
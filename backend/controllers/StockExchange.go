@@ -1,10 +1,13 @@
 package controllers
 
 import (
-	"log"
 	"net/http"
 	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
 	"tms-backend/models"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -23,10 +26,13 @@ type StockExchangeController struct {
 // @Produce json
 // @Param       LimitDate body time.Time true "Limit date"
 // @Param       LotID body uuid.UUID true "Lot ID"
-// @Success 201 {object} models.Offer
+// @Success 201 {object} models.OfferResponse
 // @Failure 400 "Invalid request body"
 // @Failure 404 "Lot not found"
 // @Failure 500 "Unable to create offer"
+// @Failure 403 "Forbidden"
+// @Param If-Match header string false "Version the change is based on, from the ETag"
+// @Failure 409 "Modified since it was read"
 // @Router /stock_exchange/lot_offers [post]
 func (sec *StockExchangeController) CreateLotOffer(c *gin.Context) {
 	var requestBody struct {
@@ -35,39 +41,29 @@ func (sec *StockExchangeController) CreateLotOffer(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-
-	// Check if the lot exists
-	var lot models.Lot
-	if result := sec.Db.First(&lot, "id = ?", requestBody.LotId); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
-		return
-	}
-
-	// Update the state of the lot
-	lot.State = models.StateOnMarket
-	if err := sec.Db.Save(&lot).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update lot state"})
+	parsedDate, err := time.Parse(time.RFC3339, requestBody.LimitDate)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("limit_date"))
 		return
 	}
 
-	// Create the offer
-	var offer models.Offer
-	parsedDate, err := time.Parse(time.RFC3339, requestBody.LimitDate)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		apierror.Abort(c, err)
 		return
 	}
 
-	_, err = offer.CreateOfferLot(sec.Db, parsedDate, lot.Id)
+	user, _ := auth.CurrentUser(c)
+	offer, err := services.PutLotOnMarket(auth.TenantDb(c, sec.Db), user, requestBody.LotId, parsedDate, version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, offer)
+	c.JSON(http.StatusCreated, offer.ToResponse())
 }
 
 // CreateTractorOffer creates an offer for a tractor
@@ -78,10 +74,13 @@ func (sec *StockExchangeController) CreateLotOffer(c *gin.Context) {
 // @Produce json
 // @Param       LimitDate body time.Time true "Limit date"
 // @Param       TractorId body uuid.UUID true "Tractor ID"
-// @Success 201 {object} models.Offer
+// @Success 201 {object} models.OfferResponse
 // @Failure 400 "Invalid request body"
 // @Failure 404 "Tractor not found"
 // @Failure 500 "Unable to create offer"
+// @Failure 403 "Forbidden"
+// @Param If-Match header string false "Version the change is based on, from the ETag"
+// @Failure 409 "Modified since it was read"
 // @Router /stock_exchange/tractor_offers [post]
 func (sec *StockExchangeController) CreateTractorOffer(c *gin.Context) {
 	var requestBody struct {
@@ -90,37 +89,69 @@ func (sec *StockExchangeController) CreateTractorOffer(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Check if the lot exists
-	var tractor models.Tractor
-	if result := sec.Db.First(&tractor, "id = ?", requestBody.TractorId); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-
-	tractor.State = models.StateOnMarket
-	if err := sec.Db.Save(&tractor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update tractor state"})
+	parsedDate, err := time.Parse(time.RFC3339, requestBody.LimitDate)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("limit_date"))
 		return
 	}
 
-	// Create the offer
-	var offer models.Offer
-	parsedDate, err := time.Parse(time.RFC3339, requestBody.LimitDate)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		apierror.Abort(c, err)
 		return
 	}
 
-	_, err = offer.CreateOfferTractor(sec.Db, parsedDate, tractor.Id)
+	user, _ := auth.CurrentUser(c)
+	offer, err := services.PutTractorOnMarket(auth.TenantDb(c, sec.Db), user, requestBody.TractorId, parsedDate, version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, offer)
+	c.JSON(http.StatusCreated, offer.ToResponse())
+}
+
+// tractorOfferListSpec : Filters and sorts of the tractor offers on the market
+var tractorOfferListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"resource_type":   {Column: "resource_type", Kind: listing.KindString},
+		"limit_date":      {Column: "limit_date", Kind: listing.KindTime},
+		"current_units":   {Column: "current_units", Kind: listing.KindNumber},
+		"max_units":       {Column: "max_units", Kind: listing.KindNumber},
+		"min_price_by_km": {Column: "min_price_by_km", Kind: listing.KindNumber},
+		"current_price":   {Column: "current_price", Kind: listing.KindNumber},
+	},
+	Sorts: map[string]string{
+		"limit_date":      "limit_date",
+		"resource_type":   "resource_type",
+		"max_units":       "max_units",
+		"min_price_by_km": "min_price_by_km",
+		"current_price":   "current_price",
+	},
+	DefaultSort: "limit_date",
+	Key:         "offer_id",
+}
+
+// lotOfferListSpec : Filters and sorts of the lot offers on the market
+var lotOfferListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"resource_type":   {Column: "resource_type", Kind: listing.KindString},
+		"limit_date":      {Column: "limit_date", Kind: listing.KindTime},
+		"volume":          {Column: "volume", Kind: listing.KindNumber},
+		"max_price_by_km": {Column: "max_price_by_km", Kind: listing.KindNumber},
+		"current_price":   {Column: "current_price", Kind: listing.KindNumber},
+	},
+	Sorts: map[string]string{
+		"limit_date":      "limit_date",
+		"resource_type":   "resource_type",
+		"volume":          "volume",
+		"max_price_by_km": "max_price_by_km",
+		"current_price":   "current_price",
+	},
+	DefaultSort: "limit_date",
+	Key:         "offer_id",
 }
 
 // GetAllTractorOnMarket returns all tractor offers
@@ -128,37 +159,37 @@ func (sec *StockExchangeController) CreateTractorOffer(c *gin.Context) {
 // @Summary Get all tractor offers
 // @Tags Stock Exchange
 // @Produce json
-// @Success 200 {array} models.Offer
+// @Param limit query int false "Page size, 100 by default"
+// @Param offset query int false "Number of offers to skip"
+// @Param sort query string false "Sort keys, - for a descending order"
+// @Success 200 {array} models.MarketTractorOffer
+// @Header 200 {integer} X-Total-Count "Number of offers matching the filters"
 // @Failure 500 "Unable to fetch offers"
 // @Router /stock_exchange/tractor_offers [get]
 func (sec *StockExchangeController) GetAllTractorOnMarket(c *gin.Context) {
-	var offers []struct {
-		LimitDate    time.Time           `json:"limit_date"`
-		TractorId    uuid.UUID           `json:"tractor_id"`
-		ResourceType models.ResourceType `json:"resource_type"`
-		CurrentUnits float64             `json:"current_units"`
-		MaxUnits     float64             `json:"max_units"`
-		MinPriceByKm float64             `json:"min_price_by_km"`
-		CurrentPrice float64             `json:"current_price"`
-		OfferId      uuid.UUID           `json:"offer_id"`
+	var offers []models.MarketTractorOffer
+	query, ok := listing.FromRequest(c, tractorOfferListSpec)
+	if !ok {
+		return
 	}
 
-	query := `
+	market := `
 		SELECT o.id as offer_id, o.limit_date, t.id as tractor_id, t.resource_type, t.current_volume as current_units, t.max_volume as max_units, t.min_price_by_km, MAX(b.bid) as current_price
 		FROM tractors t
 		JOIN offers o ON t.id = o.tractor_id
 		LEFT JOIN bids b ON o.id = b.offer_id
 		WHERE o.limit_date > (SELECT simulation_date FROM simulations LIMIT 1)
 		GROUP BY o.id, o.limit_date, t.id, t.resource_type, t.current_volume, t.max_volume, t.min_price_by_km
-		ORDER BY o.limit_date
 	`
 
-	if err := sec.Db.Raw(query).Scan(&offers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch offers"})
+	db := auth.RequestDb(c, sec.Db)
+	total, err := listing.Find(db.Table("(?) AS tractor_offers", db.Raw(market)), query, &offers)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to fetch offers"))
 		return
 	}
 
-	c.JSON(http.StatusOK, offers)
+	listing.Respond(c, query, total, offers)
 
 }
 
@@ -167,70 +198,68 @@ func (sec *StockExchangeController) GetAllTractorOnMarket(c *gin.Context) {
 // @Summary Get all lot offers
 // @Tags Stock Exchange
 // @Produce json
-// @Success 200 {array} models.Offer
+// @Param limit query int false "Page size, 100 by default"
+// @Param offset query int false "Number of offers to skip"
+// @Param sort query string false "Sort keys, - for a descending order"
+// @Success 200 {array} models.MarketLotOffer
+// @Header 200 {integer} X-Total-Count "Number of offers matching the filters"
 // @Failure 500 "Unable to fetch offers"
 // @Router /stock_exchange/lot_offers [get]
 func (sec *StockExchangeController) GetAllLotsOnMarket(c *gin.Context) {
 
-	var offers []struct {
-		LimitDate    time.Time           `json:"limit_date"`
-		LotId        uuid.UUID           `json:"lot_id"`
-		ResourceType models.ResourceType `json:"resource_type"`
-		Volume       float64             `json:"volume"`
-		MaxPriceByKm float64             `json:"max_price_by_km"`
-		CurrentPrice float64             `json:"current_price"`
-		OfferId      uuid.UUID           `json:"offer_id"`
+	var offers []models.MarketLotOffer
+	query, ok := listing.FromRequest(c, lotOfferListSpec)
+	if !ok {
+		return
 	}
 
-	query := `
+	market := `
 		SELECT o.id as offer_id, o.limit_date, l.id as lot_id, l.resource_type, l.volume, l.max_price_by_km, MIN(b.bid) as current_price
 		FROM lots l
 		JOIN offers o ON l.id = o.lot_id
 		LEFT JOIN bids b ON o.id = b.offer_id
 		WHERE o.limit_date > (SELECT simulation_date FROM simulations LIMIT 1)
 		GROUP BY o.id, o.limit_date, l.id, l.resource_type, l.volume, l.max_price_by_km
-		ORDER BY o.limit_date
 	`
 
-	if err := sec.Db.Raw(query).Scan(&offers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch offers"})
+	db := auth.RequestDb(c, sec.Db)
+	total, err := listing.Find(db.Table("(?) AS lot_offers", db.Raw(market)), query, &offers)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to fetch offers"))
 		return
 	}
 
-	c.JSON(http.StatusOK, offers)
+	listing.Respond(c, query, total, offers)
 }
 
 func (StockExchangeController *StockExchangeController) CreateBidLot(c *gin.Context) {
 	var requestBody struct {
 		Bid     float64   `json:"bid" binding:"required"`
 		OfferId uuid.UUID `json:"offer_id" binding:"required"`
-		OwnerId uuid.UUID `json:"owner_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-
-	// Parse the UUID of the offer ID
-	offerUUID, err := uuid.Parse(requestBody.OfferId.String())
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID format"})
+	if requestBody.Bid <= 0 {
+		apierror.Abort(c, apierror.InvalidParameter("bid").WithMessage("The bid must be positive"))
 		return
 	}
 
-	var bid models.Bid
-	bid.Bid = requestBody.Bid
-	bid.OfferId = offerUUID
-	bid.State = "in_progress"
-	bid.OwnerId = requestBody.OwnerId
+	owner, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
 
-	if err := StockExchangeController.Db.Create(&bid).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"errorrr": err.Error()})
+	bid, err := services.BidOnLot(auth.RequestDb(c, StockExchangeController.Db), owner, requestBody.OfferId, requestBody.Bid)
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, bid)
+	c.JSON(http.StatusCreated, bid.ToMarket())
 }
 
 func (StockExchangeController *StockExchangeController) CreateBidTractor(c *gin.Context) {
@@ -238,89 +267,34 @@ func (StockExchangeController *StockExchangeController) CreateBidTractor(c *gin.
 		Bid     float64   `json:"bid" binding:"required"`
 		OfferId uuid.UUID `json:"offer_id" binding:"required"`
 		Volume  float64   `json:"volume" binding:"required"`
-		OwnerId uuid.UUID `json:"owner_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-
-	// Parse the UUID of the offer ID
-	offerUUID, err := uuid.Parse(requestBody.OfferId.String())
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID format"})
+	if requestBody.Bid <= 0 {
+		apierror.Abort(c, apierror.InvalidParameter("bid").WithMessage("The bid must be positive"))
 		return
 	}
-
-	var bid models.Bid
-	bid.Bid = requestBody.Bid
-	bid.OfferId = offerUUID
-	bid.State = "in_progress"
-	bid.Volume = requestBody.Volume
-	bid.OwnerId = requestBody.OwnerId
-
-	if err := StockExchangeController.Db.Create(&bid).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"errorrr": err.Error()})
+	if requestBody.Volume <= 0 {
+		apierror.Abort(c, apierror.InvalidParameter("volume").WithMessage("The volume must be positive"))
 		return
 	}
 
-	c.JSON(http.StatusCreated, bid)
-}
-
-// ReturnFromMarket : Return a tractor or a lot from the market Deso on utilise plus la fonction daniel
-// @Summary After getting all the offers on morket with the limit date passed, the state of the tractor/lot is changed to return_from_market
-// @Tags Stock Exchange
-// @Produce json
-// @Success 200 {string} string "Tractors and lots returned from market"
-// @Failure 500 "Unable to fetch simulation date"
-// @Failure 500 "Unable to fetch offers"
-// @Router /stock_exchange/return_from_market [put]
-func (sec *StockExchangeController) ChangeStateToReturnFromMarket(c *gin.Context) {
-	// get offers with the state "on_market" and the LimitDate higher than the current date
-	var simulation models.Simulation
-	if err := sec.Db.First(&simulation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch simulation date"})
-		return
-	}
-	log.Println("similationDate:", simulation.SimulationDate)
-	var offers []models.Offer
-	if err := sec.Db.Joins("LEFT JOIN tractors ON offers.tractor_id = tractors.id").
-		Joins("LEFT JOIN lots ON offers.lot_id = lots.id").
-		Where("(tractors.state = ? OR lots.state = ?) AND offers.limit_date <= ?", models.StateOnMarket, models.StateOnMarket, simulation.SimulationDate).
-		Find(&offers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch offers"})
+	owner, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
 		return
 	}
 
-	// for each offer change the state of the tractor/lot to "return_from_market"
-	for _, offer := range offers {
-		if offer.TractorId != nil {
-			var tractor models.Tractor
-			if err := sec.Db.First(&tractor, "id = ?", offer.TractorId).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch tractor"})
-				return
-			}
-			tractor.State = models.StateReturnFromMarket
-			if err := tractor.Save(sec.Db); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update tractor state"})
-				return
-			}
-		} else if offer.LotId != nil {
-			var lot models.Lot
-			if err := sec.Db.First(&lot, "id = ?", offer.LotId).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch lot"})
-				return
-			}
-			lot.State = models.StateReturnFromMarket
-			if err := lot.Save(sec.Db); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update lot state"})
-				return
-			}
-		}
+	bid, err := services.BidOnTractor(auth.RequestDb(c, StockExchangeController.Db), owner, requestBody.OfferId, requestBody.Bid, requestBody.Volume)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tractors and lots returned from market"})
+	c.JSON(http.StatusCreated, bid.ToMarket())
 }
 
 // ReturnFromMarket : Return a tractor or a lot from the market
@@ -331,148 +305,11 @@ func (sec *StockExchangeController) ChangeStateToReturnFromMarket(c *gin.Context
 // @Failure 500 "Unable to fetch simulation date"
 // @Failure 500 "Unable to fetch offers"
 // @Router /stock_exchange/return_from_market [put]
-func (sec *StockExchangeController) ChangeStateToReturnFromMarket2(c *gin.Context) {
-
-	if err := sec.UpdateLotsBids(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := sec.UpdateTractorsBids(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := sec.updateLotsOffers(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if err := sec.updateTractorsOffers(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func (sec *StockExchangeController) ChangeStateToReturnFromMarket(c *gin.Context) {
+	// The market runs with the request db so its changes are audited with the caller
+	if err := services.CloseExpiredOffers(auth.RequestDb(c, sec.Db)); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-
-}
-
-func (sec *StockExchangeController) UpdateLotsBids() error {
-	var offers []models.Offer
-	query := `
-		SELECT offers.*
-		FROM offers
-		JOIN lots l ON offers.lot_id = l.id
-		WHERE offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1)
-		AND offers.lot_id IS NOT NULL AND offers.tractor_id IS NULL
-		AND l.state = 'on_market'
-	`
-
-	if err := sec.Db.Raw(query).Scan(&offers).Error; err != nil {
-		return err
-	}
-
-	for _, offer := range offers {
-		var bids []models.Bid
-		if err := sec.Db.Where("offer_id = ?", offer.Id).Order("bid asc").Find(&bids).Error; err != nil {
-			return err
-		}
-		for i, bid := range bids {
-			if i == 0 {
-				bid.State = "accepted"
-			} else {
-				bid.State = "rejected"
-			}
-			if err := sec.Db.Save(&bid).Error; err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-func (sec *StockExchangeController) UpdateTractorsBids() error {
-	var offers []models.Offer
-	query := `
-		SELECT offers.*
-		FROM offers
-		JOIN tractors t ON offers.tractor_id = t.id
-		WHERE offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1)
-		AND offers.tractor_id IS NOT NULL AND offers.lot_id IS NULL
-		AND t.state = 'on_market'
-	`
-	if err := sec.Db.Raw(query).Scan(&offers).Error; err != nil {
-		return err
-	}
-	for _, offer := range offers {
-		var bids []models.Bid
-		if err := sec.Db.Where("offer_id = ?", offer.Id).Order("bid desc").Find(&bids).Error; err != nil {
-			return err
-		}
-		for _, bid := range bids {
-			if bid.State != "in_progress" {
-				continue
-			}
-			var tractor models.Tractor
-			tractor, err := tractor.FindById(sec.Db, *offer.TractorId)
-			if err != nil {
-				return err
-			}
-			if tractor.MaxVolume-tractor.CurrentVolume < bid.Volume {
-				bid.State = "rejected"
-				if err := sec.Db.Save(&bid).Error; err != nil {
-					return err
-				}
-				continue
-			}
-			bid.State = "accepted"
-			if err := sec.Db.Save(&bid).Error; err != nil {
-				return err
-			}
-			tractor.CurrentVolume += bid.Volume
-			if err := sec.Db.Save(&tractor).Error; err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func (sec *StockExchangeController) updateLotsOffers() error {
-	query := `
-		WITH random_trafic_manager AS (
-			SELECT id
-			FROM users
-			WHERE role = 'traffic_manager'
-			ORDER BY RANDOM()
-			LIMIT 1
-		)
-		UPDATE lots
-		SET
-			state = 'return_from_market'
-		FROM offers
-		WHERE offers.lot_id = lots.id AND offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1) AND lots.state = 'on_market'
-	`
-	if err := sec.Db.Exec(query).Error; err != nil {
-		return err
-	}
-	return nil
-}
-func (sec *StockExchangeController) updateTractorsOffers() error {
-	query := `
-		WITH random_trafic_manager AS (
-			SELECT id
-			FROM users
-			WHERE role = 'traffic_manager'
-			ORDER BY RANDOM()
-			LIMIT 1
-		)
-		UPDATE tractors
-		SET
-			state = 'return_from_market'
-		FROM offers
-		WHERE offers.tractor_id = tractors.id AND offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1) AND tractors.state = 'on_market'
-	`
-	if err := sec.Db.Exec(query).Error; err != nil {
-		return err
-	}
-	return nil
+	c.JSON(http.StatusOK, gin.H{"message": "Tractors and lots returned from market"})
 }
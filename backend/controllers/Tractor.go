@@ -3,7 +3,13 @@ package controllers
 import (
 	"net/http"
 	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/geojson"
+	"tms-backend/importer"
+	"tms-backend/listing"
 	"tms-backend/models"
+	"tms-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,6 +20,59 @@ type TractorController struct {
 	Db *gorm.DB
 }
 
+// tractorListSpec : Filters and sorts of the tractor lists, named after the JSON fields
+var tractorListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"name":                  {Column: "name", Kind: listing.KindString},
+		"state":                 {Column: "state", Kind: listing.KindString},
+		"resource_type":         {Column: "resource_type", Kind: listing.KindString},
+		"start_checkpoint_id":   {Column: "start_checkpoint_id", Kind: listing.KindUUID},
+		"end_checkpoint_id":     {Column: "end_checkpoint_id", Kind: listing.KindUUID},
+		"current_checkpoint_id": {Column: "current_checkpoint_id", Kind: listing.KindUUID},
+		"route_id":              {Column: "route_id", Kind: listing.KindUUID},
+		"max_units":             {Column: "max_volume", Kind: listing.KindNumber},
+		"current_units":         {Column: "current_volume", Kind: listing.KindNumber},
+		"min_price_by_km":       {Column: "min_price_by_km", Kind: listing.KindNumber},
+		"created_at":            {Column: "created_at", Kind: listing.KindTime},
+	},
+	Sorts: map[string]string{
+		"name":            "name",
+		"created_at":      "created_at",
+		"state":           "state",
+		"resource_type":   "resource_type",
+		"max_units":       "max_volume",
+		"current_units":   "current_volume",
+		"min_price_by_km": "min_price_by_km",
+	},
+	DefaultSort: "created_at",
+	Key:         "id",
+}
+
+// tractorBid is a bid of a client on tractors, grouped by offer limit date, minimum price and bid state
+type tractorBid struct {
+	ExpirationDate time.Time `json:"limit_date" gorm:"column:limit_date"`
+	CurrentPrice   float64   `json:"current_price"`
+	MinPrice       float64   `json:"min_price_by_km"`
+	State          string    `json:"state"`
+}
+
+// tractorBidListSpec : Filters and sorts of the bids on tractors
+var tractorBidListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"state":           {Column: "state", Kind: listing.KindString},
+		"limit_date":      {Column: "limit_date", Kind: listing.KindTime},
+		"current_price":   {Column: "current_price", Kind: listing.KindNumber},
+		"min_price_by_km": {Column: "min_price", Kind: listing.KindNumber},
+	},
+	Sorts: map[string]string{
+		"limit_date":      "limit_date",
+		"current_price":   "current_price",
+		"min_price_by_km": "min_price",
+	},
+	DefaultSort: "limit_date,min_price_by_km",
+	Key:         "state",
+}
+
 //var tractorModel = models.Tractor{}
 
 // CreateTractor : Create a new tractor
@@ -27,13 +86,14 @@ type TractorController struct {
 // @Param        volume               body  float64 true  "Volume"
 // @Param        start_checkpoint_id   body  string  true  "Start Checkpoint Id"
 // @Param        end_checkpoint_id     body  string  true  "End Checkpoint Id"
-// @Param        owner_id             body  string  true  "Owner Id"
 // @Param        current_checkpoint_id body  string  false "Current Checkpoint Id"
 // @Param        state                body  string  true  "State"
 // @Param        min_price_by_km      body  float64 true  "Min Price By Km"
-// @Success      201  {object}  models.Tractor
+// @Success      201  {object}  models.TractorResponse
 // @Failure      400  "Invalid request payload"
+// @Failure      401  "Unauthorized"
 // @Failure      500  "Unable to create tractor"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors [post]
 func (TractorController *TractorController) CreateTractor(c *gin.Context) {
 	var requestBody struct {
@@ -42,20 +102,29 @@ func (TractorController *TractorController) CreateTractor(c *gin.Context) {
 		MaxVolume           float64             `json:"volume" binding:"required"`
 		StartCheckpointId   uuid.UUID           `json:"start_checkpoint_id" binding:"required"`
 		EndCheckpointId     uuid.UUID           `json:"end_checkpoint_id" binding:"required"`
-		OwnerId             uuid.UUID           `json:"owner_id" binding:"required"`
 		CurrentCheckpointId uuid.UUID           `json:"current_checkpoint_id"`
 		State               models.State        `json:"state" binding:"required"`
 		MinPriceByKm        float64             `json:"min_price_by_km" binding:"required"`
 	}
 
+	owner, ok := auth.CurrentUser(c)
+	if !ok {
+		apierror.Abort(c, apierror.ErrUnauthorized)
+		return
+	}
+
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
 	var simulation models.Simulation
-	if err := TractorController.Db.First(&simulation).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to fetch simulation date"})
+	if err := auth.TenantDb(c, TractorController.Db).First(&simulation).Error; err != nil {
+		apierror.Abort(c, apierror.ErrSimulationNotFound.WithCause(err))
 		return
 	}
 
@@ -67,22 +136,43 @@ func (TractorController *TractorController) CreateTractor(c *gin.Context) {
 		EndCheckpointId:     &requestBody.EndCheckpointId,
 		CurrentCheckpointId: &requestBody.CurrentCheckpointId,
 		CreatedAt:           simulation.SimulationDate,
-		OwnerId:             requestBody.OwnerId,
+		OwnerId:             owner.Id,
 		State:               requestBody.State,
 		MinPriceByKm:        requestBody.MinPriceByKm,
 	}
 
-	if err := TractorController.Db.Create(&TractorModel).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.TenantDb(c, TractorController.Db).Create(&TractorModel).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	if err := TractorController.Db.Preload("StartCheckpoint").Preload("EndCheckpoint").Preload("Owner").Preload("TrafficManager").Preload("Trader").First(&TractorModel, "id = ?", TractorModel.Id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &TractorModel); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, TractorModel)
+	c.JSON(http.StatusCreated, TractorModel.ToResponse(expand))
+}
+
+// ImportTractors : Create tractors owned by the user from a CSV file
+//
+// @Summary      Import tractors from CSV
+// @Description  checks every row as CreateTractor does, checkpoints are given by id, by name, by \"name, country\" or by latitude and longitude within 10 km. The first line names the columns: name, resource_type, max_units, state, min_price_by_km, start_checkpoint, end_checkpoint and optionally current_checkpoint, the start by default. Each checkpoint column may be replaced by <prefix>_latitude and <prefix>_longitude. Every row is checked first, the file is imported in one transaction only when all of them are valid, otherwise nothing is and the error details list the problems by line
+// @Tags         tractors
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  false  "CSV file, or send it as the body"
+// @Param        dry_run  query  bool  false  "Only check the rows and report their errors"
+// @Success      200  {object}  importer.Report  "Dry run"
+// @Success      201  {object}  importer.Report  "Rows imported"
+// @Failure      400  "Invalid file, or IMPORT_INVALID with the report as details"
+// @Failure      401  "Unauthorized"
+// @Failure      403  "The caller is not a client of an organization"
+// @Failure      500  "Unable to import tractors"
+// @Router       /tractors/import [post]
+func (TractorController *TractorController) ImportTractors(c *gin.Context) {
+	importFile(c, auth.TenantDb(c, TractorController.Db), importer.KindTractors)
 }
 
 // GoToNextCheckpoint : Update the current checkpoint of the tractors
@@ -91,25 +181,25 @@ func (TractorController *TractorController) CreateTractor(c *gin.Context) {
 // @Tags         tractors
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}  models.Tractor
+// @Success      200  {array}  models.TractorResponse
 // @Failure      500  "Unable to fetch tractors"
 // @Router       /tractors/next_checkpoint [put]
 func (TractorController *TractorController) GoToNextCheckpoint(c *gin.Context) {
 	var tractors []models.Tractor
-	if err := TractorController.Db.Find(&tractors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.TenantDb(c, TractorController.Db).Find(&tractors).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
 	// for each tractor
 	for _, tractor := range tractors {
 
-		tractor.UpdateNextCheckpoint(TractorController.Db)
+		tractor.UpdateNextCheckpoint(auth.TenantDb(c, TractorController.Db))
 
-		TractorController.Db.Save(&tractor)
+		auth.TenantDb(c, TractorController.Db).Save(&tractor)
 	}
 
-	c.JSON(http.StatusOK, tractors)
+	c.JSON(http.StatusOK, models.TractorResponses(tractors, nil))
 }
 
 // ListTractorsByOwner : List all tractors by owner id
@@ -119,29 +209,48 @@ func (TractorController *TractorController) GoToNextCheckpoint(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        owner_id  path  string  true  "Owner Id"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
 // @Failure      400  "Invalid owner_id"
 // @Failure      500  "Unable to retrieve tractors"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/owner/{owner_id} [get]
 func (TractorController *TractorController) ListTractorsByOwner(c *gin.Context) {
 	var tractors []models.Tractor
-	var tractorModel models.Tractor
 	ownerId := c.Param("ownerId")
 	ownerIdUUID, errIdUUID := uuid.Parse(ownerId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
 		return
 	}
 
-	tractors, err := tractorModel.GetByOwnerId(TractorController.Db, ownerIdUUID)
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, ownerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
 
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, TractorController.Db)).
+		Where("owner_id = ?", ownerIdUUID)
+	total, err := listing.Find(db, query, &tractors)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractors)
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
 }
 
 // ListTractorsByTrafficManagerId : List all tractors by traffic manager id
@@ -151,29 +260,135 @@ func (TractorController *TractorController) ListTractorsByOwner(c *gin.Context)
 // @Accept       json
 // @Produce      json
 // @Param        traffic_manager_id  path  string  true  "Traffic Manager Id"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
 // @Failure      400  "Invalid owner_id"
 // @Failure      500  "Unable to retrieve tractors"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/traffic_manager/{traffic_manager_id} [get]
 func (TractorController *TractorController) ListTractorsByTrafficManagerId(c *gin.Context) {
 	var tractors []models.Tractor
-	var tractorModel models.Tractor
 	trafficManagerId := c.Param("trafficManagerId")
 	trafficManagerIdUUID, errIdUUID := uuid.Parse(trafficManagerId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
 		return
 	}
 
-	tractors, err := tractorModel.GetByTrafficManagerId(TractorController.Db, trafficManagerIdUUID)
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, trafficManagerIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, TractorController.Db)).
+		Where("traffic_manager_id = ?", trafficManagerIdUUID)
+	total, err := listing.Find(db, query, &tractors)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
+}
 
+// ListTractorsWithin : List the tractors currently within a radius of a point
+//
+// @Summary      List the tractors within a radius
+// @Description  tractors whose current checkpoint is at most radius_km from a point by great-circle distance, among those the user owns, manages or sells
+// @Tags         tractors
+// @Produce      json
+// @Param        latitude  query  number  true  "Latitude, between -90 and 90"
+// @Param        longitude  query  number  true  "Longitude, between -180 and 180"
+// @Param        radius_km  query  number  true  "Radius in km, up to 5000"
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
+// @Failure      400  "Invalid coordinates or radius"
+// @Failure      500  "Unable to retrieve tractors"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
+// @Router       /tractors/within [get]
+func (TractorController *TractorController) ListTractorsWithin(c *gin.Context) {
+	checkpointIds, ok := checkpointsWithin(c, auth.RequestDb(c, TractorController.Db))
+	if !ok {
+		return
+	}
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
+	}
+	user, _ := auth.CurrentUser(c)
+	var tractors []models.Tractor
+	db := involving(expand.Preload(auth.TenantDb(c, TractorController.Db)), user).
+		Where("current_checkpoint_id IN ?", checkpointIds)
+	total, err := listing.Find(db, query, &tractors)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve tractors"))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractors)
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
+}
+
+// GetFleetGeoJSON : Get the positions of the tractors as GeoJSON points
+//
+// @Summary      Get the fleet as GeoJSON
+// @Description  a FeatureCollection of Point features at the current checkpoint of every tractor the user owns, manages or sells, archived ones left out. Properties are name, state, resource_type, current_units, max_units, route_id, traffic_manager_id and current_checkpoint_id
+// @Tags         tractors
+// @Produce      json
+// @Param        traffic_manager_id  query  string  false  "Only the tractors of this traffic manager"
+// @Success      200  {object}  geojson.FeatureCollection
+// @Failure      400  "Invalid traffic_manager_id"
+// @Failure      500  "Unable to retrieve tractors"
+// @Router       /tractors/geojson [get]
+func (TractorController *TractorController) GetFleetGeoJSON(c *gin.Context) {
+	user, _ := auth.CurrentUser(c)
+	db, ok := byTrafficManager(c, involving(auth.TenantDb(c, TractorController.Db), user))
+	if !ok {
+		return
+	}
+	var tractors []models.Tractor
+	if err := db.Preload("CurrentCheckpoint").Where("current_checkpoint_id IS NOT NULL AND state <> ?", models.StateArchive).
+		Order("name, id").Find(&tractors).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve tractors"))
+		return
+	}
+
+	features := make([]geojson.Feature, 0, len(tractors))
+	for _, tractor := range tractors {
+		if tractor.CurrentCheckpoint == nil {
+			continue
+		}
+		features = append(features, geojson.NewFeature(tractor.Id.String(), geojson.Point(*tractor.CurrentCheckpoint), map[string]interface{}{
+			"name":                  tractor.Name,
+			"state":                 tractor.State,
+			"resource_type":         tractor.ResourceType,
+			"current_units":         tractor.CurrentVolume,
+			"max_units":             tractor.MaxVolume,
+			"route_id":              tractor.RouteId,
+			"traffic_manager_id":    tractor.TrafficManagerId,
+			"current_checkpoint_id": tractor.CurrentCheckpointId,
+		}))
+	}
+	c.JSON(http.StatusOK, geojson.Collection(features))
 }
 
 // ListTractorsByState : List all tractors by state
@@ -183,23 +398,35 @@ func (TractorController *TractorController) ListTractorsByTrafficManagerId(c *gi
 // @Accept       json
 // @Produce      json
 // @Param        state  path  string  true  "State"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
 // @Failure      400  "Invalid state"
 // @Failure      500  "Unable to retrieve tractors"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/state/{state} [get]
 func (TractorController *TractorController) ListTractorsByState(c *gin.Context) {
 	var tractors []models.Tractor
-	var tractorModel models.Tractor
 	state := c.Param("state")
 
-	tractors, err := tractorModel.GetByState(TractorController.Db, state)
-
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, TractorController.Db)).Where("state = ?", state)
+	total, err := listing.Find(db, query, &tractors)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractors)
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
 }
 
 // ListTractorsByRouteId : List all tractors by route id
@@ -209,29 +436,54 @@ func (TractorController *TractorController) ListTractorsByState(c *gin.Context)
 // @Accept       json
 // @Produce      json
 // @Param        route_id  path  string  true  "Route Id"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
 // @Failure      400  "Invalid route_id"
 // @Failure      500  "Unable to retrieve tractors"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route not found"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/route/{route_id} [get]
 func (TractorController *TractorController) ListTractorsByRouteId(c *gin.Context) {
 	var tractors []models.Tractor
-	var tractorModel models.Tractor
 	routeId := c.Param("routeId")
 	routeIdUUID, errIdUUID := uuid.Parse(routeId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
+		return
+	}
+
+	var route models.Route
+	if err := route.GetById(auth.TenantDb(c, TractorController.Db), routeIdUUID); err != nil {
+		apierror.Abort(c, apierror.ErrRouteNotFound)
 		return
 	}
 
-	tractors, err := tractorModel.GetByRouteId(TractorController.Db, routeIdUUID)
+	if user, _ := auth.CurrentUser(c); !auth.CanManageRoute(user, route) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
 
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
+		return
+	}
+	db := expand.Preload(auth.TenantDb(c, TractorController.Db)).Where("route_id = ?", routeIdUUID)
+	total, err := listing.Find(db, query, &tractors)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractors)
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
 }
 
 // AssociateToTrafficManager : Associate a tractor to a traffic manager
@@ -242,12 +494,18 @@ func (TractorController *TractorController) ListTractorsByRouteId(c *gin.Context
 // @Produce      json
 // @Param        tractor_id  body  string  true  "Tractor Id"
 // @Param        traffic_manager_id  body  string  true  "Traffic Manager Id"
-// @Success      200  {object}  models.Tractor
+// @Success      200  {object}  models.TractorResponse
 // @Failure      400  "Invalid tractor_id"
 // @Failure      400  "Invalid traffic_manager_id"
 // @Failure      404  "Tractor not found"
+// @Failure      404  "Traffic manager not found in the organization of the tractor"
 // @Failure      500  "Unable to update traffic_manager"
 // @Failure      500  "Unable to update state"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the tractor"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/associate [put]
 func (TractorController *TractorController) AssociateToTrafficManager(c *gin.Context) {
 	var requestBody struct {
@@ -256,38 +514,44 @@ func (TractorController *TractorController) AssociateToTrafficManager(c *gin.Con
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	var tractorIdUUID, errIdUUID = uuid.Parse(requestBody.TractorId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
 		return
 	}
 	var trafficManagerIdUUID, errTrafficManagerIdUUID = uuid.Parse(requestBody.TrafficManagerId)
 	if errTrafficManagerIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid traffic_manager_id"})
+		apierror.Abort(c, apierror.InvalidParameter("traffic_manager_id"))
 		return
 	}
 
-	var tractor models.Tractor
-	tractor, err := tractor.FindById(TractorController.Db, tractorIdUUID)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, err)
 		return
 	}
-	if err := tractor.AssociateTraficManager(TractorController.Db, trafficManagerIdUUID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error updating traffic_manager": err.Error()})
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
-	if err := tractor.UpdateState(TractorController.Db, models.StatePending); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, _ := auth.CurrentUser(c)
+	tractor, err := services.AssociateTractorToTrafficManager(auth.TenantDb(c, TractorController.Db), user, tractorIdUUID, trafficManagerIdUUID, version)
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, tractor)
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &tractor); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, tractor.Version)
+	c.JSON(http.StatusOK, tractor.ToResponse(expand))
 }
 
 // UpdateTractorState : Update the state of a tractor
@@ -298,10 +562,15 @@ func (TractorController *TractorController) AssociateToTrafficManager(c *gin.Con
 // @Produce      json
 // @Param        id     body  string  true  "Id"
 // @Param        state  body  string  true  "State"
-// @Success      200  {object}  models.Tractor
+// @Success      200  {object}  models.TractorResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Tractor not found"
 // @Failure      500  "Unable to update tractor"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the tractor"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/state [put]
 func (TractorController *TractorController) UpdateTractorState(c *gin.Context) {
 	var requestBody struct {
@@ -309,35 +578,59 @@ func (TractorController *TractorController) UpdateTractorState(c *gin.Context) {
 		State models.State `json:"state" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	var tractorIdUUID, errIdUUID = uuid.Parse(requestBody.Id)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
+
 	var tractor models.Tractor
-	tractor, err := tractor.FindById(TractorController.Db, tractorIdUUID)
+	tractor, err = tractor.FindById(auth.TenantDb(c, TractorController.Db), tractorIdUUID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, apierror.ErrTractorNotFound)
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanChangeTractorState(user, tractor) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, version); err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 	tractor.State = requestBody.State
 
-	if err := TractorController.Db.Save(&tractor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.TenantDb(c, TractorController.Db).Save(&tractor).Error; err != nil {
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 	if requestBody.State == models.StateInTransit {
 		var lot models.Lot
-		lot.UpdateStateByTractorId(TractorController.Db, tractorIdUUID, models.StateInTransit)
+		lot.UpdateStateByTractorId(auth.TenantDb(c, TractorController.Db), tractorIdUUID, models.StateInTransit)
 	} else if requestBody.State == models.StatePending {
 		var lot models.Lot
-		lot.UpdateStateByTractorId(TractorController.Db, tractorIdUUID, models.StatePending)
+		lot.UpdateStateByTractorId(auth.TenantDb(c, TractorController.Db), tractorIdUUID, models.StatePending)
+	}
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &tractor); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
 	}
-	c.JSON(http.StatusOK, tractor)
+	setETag(c, tractor.Version)
+	c.JSON(http.StatusOK, tractor.ToResponse(expand))
 }
 
 // BindRoute : Bind a route to a tractor
@@ -348,10 +641,16 @@ func (TractorController *TractorController) UpdateTractorState(c *gin.Context) {
 // @Produce      json
 // @Param        tractor_id  body  string  true  "Tractor Id"
 // @Param        route_id    body  string  true  "Route Id"
-// @Success      200  {object}  models.Tractor
+// @Success      200  {object}  models.TractorResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Tractor not found"
 // @Failure      500  "Unable to update tractor"
+// @Failure      403  "Forbidden"
+// @Failure      404  "Route not found"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the tractor"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/bind_route [put]
 func (TractorController *TractorController) BindRoute(c *gin.Context) {
 	var requestBody struct {
@@ -360,35 +659,65 @@ func (TractorController *TractorController) BindRoute(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	var tractorIdUUID, errIdUUID = uuid.Parse(requestBody.TractorId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
 		return
 	}
 	var routeIdUUID, errRouteIdUUID = uuid.Parse(requestBody.RouteId)
 	if errRouteIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route_id"})
+		apierror.Abort(c, apierror.InvalidParameter("route_id"))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
 	var tractor models.Tractor
-	tractor, err := tractor.FindById(TractorController.Db, tractorIdUUID)
+	tractor, err = tractor.FindById(auth.TenantDb(c, TractorController.Db), tractorIdUUID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, apierror.ErrTractorNotFound)
+		return
+	}
+
+	var route models.Route
+	if err := route.GetById(auth.TenantDb(c, TractorController.Db), routeIdUUID); err != nil {
+		apierror.Abort(c, apierror.ErrRouteNotFound)
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanManageTractor(user, tractor) || !auth.CanManageRoute(user, route) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, version); err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
 	tractor.RouteId = &routeIdUUID
-	if err := tractor.Save(TractorController.Db); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := tractor.Save(auth.TenantDb(c, TractorController.Db)); err != nil {
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractor)
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &tractor); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, tractor.Version)
+	c.JSON(http.StatusOK, tractor.ToResponse(expand))
 }
 
 // UnbindRoute : Unbind a route from a tractor
@@ -398,10 +727,15 @@ func (TractorController *TractorController) BindRoute(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        tractor_id  body  string  true  "Tractor Id"
-// @Success      200  {object}  models.Tractor
+// @Success      200  {object}  models.TractorResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Tractor not found"
 // @Failure      500  "Unable to update tractor"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the tractor"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/unbind_route [put]
 func (TractorController *TractorController) UnbindRoute(c *gin.Context) {
 	var requestBody struct {
@@ -409,30 +743,54 @@ func (TractorController *TractorController) UnbindRoute(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
 
 	var tractorIdUUID, errIdUUID = uuid.Parse(requestBody.TractorId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
 	var tractor models.Tractor
-	tractor, err := tractor.FindById(TractorController.Db, tractorIdUUID)
+	tractor, err = tractor.FindById(auth.TenantDb(c, TractorController.Db), tractorIdUUID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, apierror.ErrTractorNotFound)
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanManageTractor(user, tractor) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, version); err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
 	tractor.RouteId = nil
-	if err := tractor.Save(TractorController.Db); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := tractor.Save(auth.TenantDb(c, TractorController.Db)); err != nil {
+		apierror.Abort(c, apierror.From(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, tractor)
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &tractor); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, tractor.Version)
+	c.JSON(http.StatusOK, tractor.ToResponse(expand))
 }
 
 // DeleteTractor : Delete a tractor
@@ -445,62 +803,35 @@ func (TractorController *TractorController) UnbindRoute(c *gin.Context) {
 // @Failure      400  "Invalid tractor_id"
 // @Failure      404  "Tractor not found"
 // @Failure      500  "Unable to delete tractor"
+// @Failure      403  "Forbidden"
 // @Router       /tractors/{tractor_id} [delete]
 func (TractorController *TractorController) DeleteTractor(c *gin.Context) {
 	tractorId := c.Param("tractor_id")
 	tractorIdUUID, errIdUUID := uuid.Parse(tractorId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
 		return
 	}
 
 	var tractor models.Tractor
-	tractor, err := tractor.FindById(TractorController.Db, tractorIdUUID)
+	tractor, err := tractor.FindById(auth.TenantDb(c, TractorController.Db), tractorIdUUID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tractor not found"})
+		apierror.Abort(c, apierror.ErrTractorNotFound)
 		return
 	}
 
-	if err := TractorController.Db.Delete(&tractor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if user, _ := auth.CurrentUser(c); !auth.CanManageTractor(user, tractor) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tractor \"" + tractorId + "\" deleted successfully"})
-}
-
-func (TractorController *TractorController) GetAvailableTrader(c *gin.Context) (models.User, error) {
-	var user models.User
-	traders, err := user.FindByRole(TractorController.Db, models.RoleTrader)
-	if err != nil {
-		return models.User{}, err
-	}
-
-	var tractors []models.Tractor
-	if err := TractorController.Db.Where("state = ?", models.StateAtTrader).Find(&tractors).Error; err != nil {
-		return models.User{}, err
-	}
-
-	traderCounts := make(map[uuid.UUID]int)
-	for _, tractor := range tractors {
-		if tractor.TraderId != nil {
-			traderCounts[*tractor.TraderId]++
-		}
-	}
-
-	var selectedTrader models.User
-	minCount := len(tractors)
-
-	for _, trader := range traders {
-		count := traderCounts[trader.Id]
-		if count <= minCount {
-			minCount = count
-			selectedTrader = trader
-		}
+	if err := auth.TenantDb(c, TractorController.Db).Delete(&tractor).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
 	}
 
-	return selectedTrader, nil
+	c.JSON(http.StatusOK, gin.H{"message": "Tractor \"" + tractorId + "\" deleted successfully"})
 }
 
 // AssignTraderToTractor : Assign a trader to a tractor
@@ -511,36 +842,22 @@ func (TractorController *TractorController) GetAvailableTrader(c *gin.Context) (
 // @Produce      json
 // @Param        tractor_id  body  string  true  "Tractor Id"
 // @Param        trader_id  body  string  true  "Trader Id"
-// @Success      200  {object}  models.Tractor
+// @Success      200  {object}  models.TractorResponse
 // @Failure      400  "Invalid request payload"
 // @Failure      404  "Tractor not found"
 // @Failure      404  "Trader not found"
 // @Failure      500  "Unable to assign trader to tractor"
+// @Failure      403  "Forbidden"
+// @Param        If-Match  header  string  false  "Version the change is based on, from the ETag"
+// @Header       200  {string}  ETag  "Version of the tractor"
+// @Failure      409  "Modified since it was read"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/assign/{tractor_id}/trader [put]
 func (TractorController *TractorController) AssignTraderToTractor(c *gin.Context) {
 	tractorId := c.Param("tractor_id")
 	tractorIdUUID, errIdUUID := uuid.Parse(tractorId)
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tractor_id"})
-		return
-	}
-
-	var tractor models.Tractor
-	if err := TractorController.Db.First(&tractor, "id = ?", tractorIdUUID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Tractor not found"})
-		return
-	}
-
-	trader, err := TractorController.GetAvailableTrader(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	tractor.TraderId = &trader.Id
-	tractor.State = models.StateAtTrader
-	if err := TractorController.Db.Save(&tractor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidParameter("tractor_id"))
 		return
 	}
 
@@ -549,27 +866,38 @@ func (TractorController *TractorController) AssignTraderToTractor(c *gin.Context
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-	var offer models.Offer
 	parsedDate, err := time.Parse(time.RFC3339, requestBody.Date)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		apierror.Abort(c, apierror.InvalidParameter("limit_date"))
 		return
 	}
-	_, err = offer.CreateOfferTractor(TractorController.Db, parsedDate, tractor.Id)
+
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, err)
 		return
 	}
-	tractor.LimitDate = parsedDate
-	if err := TractorController.Db.Save(&tractor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, tractor)
+	user, _ := auth.CurrentUser(c)
+	tractor, err := services.AssignTraderToTractor(auth.TenantDb(c, TractorController.Db), user, tractorIdUUID, parsedDate, version)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	if err := expand.Load(auth.TenantDb(c, TractorController.Db), &tractor); err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	setETag(c, tractor.Version)
+	c.JSON(http.StatusOK, tractor.ToResponse(expand))
 }
 
 // GetAllTractorTraderId : Get all tractors with trader id
@@ -579,10 +907,16 @@ func (TractorController *TractorController) AssignTraderToTractor(c *gin.Context
 // @Accept       json
 // @Produce      json
 // @Param        trader_id  path  string  true  "Trader Id"
-// @Success      200  {array}  models.Tractor
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of tractors to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  models.TractorResponse
+// @Header       200  {integer}  X-Total-Count  "Number of tractors matching the filters"
 // @Failure      400  "Invalid trader_id"
 // @Failure      404  "Trader not found"
 // @Failure      500  "Unable to retrieve tractors"
+// @Failure      403  "Forbidden"
+// @Param        expand  query  string  false  "Associations to embed: start_checkpoint, end_checkpoint, current_checkpoint, route, owner, traffic_manager, trader"
 // @Router       /tractors/trader/{trader_id} [get]
 func (TractorController *TractorController) GetAllTractorTraderId(c *gin.Context) {
 	var tractors []models.Tractor
@@ -591,40 +925,53 @@ func (TractorController *TractorController) GetAllTractorTraderId(c *gin.Context
 
 	// Validate trader_id
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trader_id"})
+		apierror.Abort(c, apierror.InvalidParameter("trader_id"))
+		return
+	}
+
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, traderIdUUID) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
 	// Check if the trader exists
 	var trader models.User
-	if err := TractorController.Db.First(&trader, "id = ? AND role = ?", traderIdUUID, "trader").Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Trader not found"})
+	if err := auth.TenantDb(c, TractorController.Db).First(&trader, "id = ? AND role = ?", traderIdUUID, "trader").Error; err != nil {
+		apierror.Abort(c, apierror.ErrTraderNotFound)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, tractorListSpec)
+	if !ok {
+		return
+	}
+	expand, ok := expansion(c, models.TractorExpansions)
+	if !ok {
 		return
 	}
 
 	// Retrieve tractors for the trader with associated offers
-	if err := TractorController.Db.Preload("StartCheckpoint").
-		Preload("EndCheckpoint").
-		Preload("Route").
-		Where("trader_id = ?", traderIdUUID).
-		Find(&tractors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve tractors", "details": err.Error()})
+	db := expand.Preload(auth.TenantDb(c, TractorController.Db)).
+		Where("trader_id = ?", traderIdUUID)
+	total, err := listing.Find(db, query, &tractors)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve tractors"))
 		return
 	}
 	for i := range tractors {
 		var maxBid float64
 		var offer models.Offer
-		if err := TractorController.Db.First(&offer, "tractor_id = ?", tractors[i].Id).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve offer", "details": err.Error()})
+		if err := auth.TenantDb(c, TractorController.Db).First(&offer, "tractor_id = ?", tractors[i].Id).Error; err != nil {
+			apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve offer"))
 			return
 		}
-		TractorController.Db.Raw("SELECT COALESCE(MAX(bid), 0) FROM bids WHERE offer_id = ?", offer.Id).Scan(&maxBid)
+		auth.TenantDb(c, TractorController.Db).Raw("SELECT COALESCE(MAX(bid), 0) FROM bids WHERE offer_id = ?", offer.Id).Scan(&maxBid)
 		tractors[i].CurrentPrice = maxBid
 		tractors[i].LimitDate = offer.LimitDate
 	}
 
 	// Return the enriched tractors in the JSON response
-	c.JSON(http.StatusOK, tractors)
+	listing.Respond(c, query, total, models.TractorResponses(tractors, expand))
 }
 
 // GetTractorBidByOwnerId : Get all bids for a lot by owner id
@@ -634,27 +981,38 @@ func (TractorController *TractorController) GetAllTractorTraderId(c *gin.Context
 // @Accept       json
 // @Produce      json
 // @Param        client_id  path  string  true  "Client Id"
-// @Success      200  {array}  models.Bid
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of bids to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}  tractorBid
+// @Header       200  {integer}  X-Total-Count  "Number of bids matching the filters"
 // @Failure      400  "Invalid client_id"
 // @Failure      500  "Unable to retrieve bids"
+// @Failure      403  "Forbidden"
 // @Router       /tractors/bids/{client_id} [get]
 func (TractorController *TractorController) GetTractorBidByOwnerId(c *gin.Context) {
-	var result []struct {
-		ExpirationDate time.Time `json:"limit_date"`
-		CurrentPrice   float64   `json:"current_price"`
-		MinPrice       float64   `json:"min_price_by_km"`
-		State          string    `json:"state"`
-	}
+	var result []tractorBid
 
 	clientId := c.Param("owner_id")
 	ownerID, errIdUUID := uuid.Parse(clientId)
 
 	if errIdUUID != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner_id"})
+		apierror.Abort(c, apierror.InvalidParameter("owner_id"))
 		return
 	}
 
-	query := `
+	if user, _ := auth.CurrentUser(c); !auth.CanActAs(user, ownerID) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	query, ok := listing.FromRequest(c, tractorBidListSpec)
+	if !ok {
+		return
+	}
+
+	// The grouped bids are a subquery so the filters apply to its columns
+	bids := `
     SELECT offers.limit_date AS limit_date,
            MAX(bids.bid) AS current_price,
            tractors.min_price_by_km AS min_price,
@@ -662,30 +1020,16 @@ func (TractorController *TractorController) GetTractorBidByOwnerId(c *gin.Contex
     FROM bids
     JOIN offers ON offers.id = bids.offer_id
     JOIN tractors ON offers.tractor_id = tractors.id
-    WHERE bids.owner_id = $1
-    GROUP BY offers.limit_date, tractors.min_price_by_km, bids.state;
+    WHERE bids.owner_id = ?
+    GROUP BY offers.limit_date, tractors.min_price_by_km, bids.state
 `
 
-	rows, err := TractorController.Db.Raw(query, ownerID).Rows()
+	db := auth.TenantDb(c, TractorController.Db)
+	total, err := listing.Find(db.Table("(?) AS tractor_bids", db.Raw(bids, ownerID)), query, &result)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve bids"})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to retrieve bids"))
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var record struct {
-			ExpirationDate time.Time `json:"limit_date"`
-			CurrentPrice   float64   `json:"current_price"`
-			MinPrice       float64   `json:"min_price_by_km"`
-			State          string    `json:"state"`
-		}
-		if err := rows.Scan(&record.ExpirationDate, &record.CurrentPrice, &record.MinPrice, &record.State); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to scan bid results"})
-			return
-		}
-		result = append(result, record)
-	}
 
-	c.JSON(http.StatusOK, result)
+	listing.Respond(c, query, total, result)
 }
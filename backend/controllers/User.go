@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/listing"
 	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
@@ -13,23 +17,46 @@ type UserController struct {
 	Db *gorm.DB
 }
 
+// userListSpec : Filters and sorts of the user lists
+var userListSpec = listing.Spec{
+	Filters: map[string]listing.Field{
+		"username":        {Column: "username", Kind: listing.KindString},
+		"role":            {Column: "role", Kind: listing.KindString},
+		"organization_id": {Column: "organization_id", Kind: listing.KindUUID},
+	},
+	Sorts: map[string]string{
+		"username": "username",
+		"role":     "role",
+	},
+	DefaultSort: "username",
+	Key:         "id",
+}
+
 // GetUsers : Get users
 //
 // @Summary      Get all users
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   models.User
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of users to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.UserResponse
+// @Header       200  {integer}  X-Total-Count  "Number of users matching the filters"
 // @Failure      500  "Unable to retrieve users"
 // @Router       /users [get]
 func (UserController *UserController) GetUsers(c *gin.Context) {
-	var user models.User
-	users, err := user.GetAllUsers(UserController.Db)
+	var users []models.User
+	query, ok := listing.FromRequest(c, userListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(auth.TenantDb(c, UserController.Db), query, &users)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	listing.Respond(c, query, total, models.ToResponses(users, (*models.User).ToResponse))
 }
 
 // GetUser : Get user
@@ -38,9 +65,11 @@ func (UserController *UserController) GetUsers(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "User ID"
-// @Success      200  {object}   models.User
+// @Success      200  {object}   models.UserResponse
 // @Failure      500  "Unable to retrieve user"
 // @Failure      400  "Invalid request"
+// @Failure      403  "Forbidden"
+// @Failure      404  "User not found"
 // @Router       /user/{id} [get]
 func (UserController *UserController) GetUser(c *gin.Context) {
 	var user models.User
@@ -48,16 +77,25 @@ func (UserController *UserController) GetUser(c *gin.Context) {
 	userId, err := uuid.Parse(id)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId"})
+		apierror.Abort(c, apierror.InvalidParameter("user_id"))
+		return
+	}
+
+	if caller, _ := auth.CurrentUser(c); !auth.CanActAs(caller, userId) {
+		apierror.Abort(c, apierror.ErrForbidden)
 		return
 	}
 
-	user, err = user.FindById(UserController.Db, userId)
+	user, err = user.FindById(auth.TenantDb(c, UserController.Db), userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Abort(c, apierror.ErrUserNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.ToResponse())
 }
 
 // CreateUser : Create a user
@@ -66,21 +104,31 @@ func (UserController *UserController) GetUser(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        user  body  models.User  true  "User"
-// @Success      200  {object}   models.User
+// @Success      200  {object}   models.UserResponse
 // @Failure      500  "Unable to create user"
 // @Failure      400  "Invalid request"
 // @Router       /user [post]
 func (UserController *UserController) CreateUser(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
+		return
+	}
+	if err := auth.Policy.Validate(user.Password); err != nil {
+		apierror.Abort(c, apierror.WeakPassword(err))
 		return
 	}
-	if err := UserController.Db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	hashedPassword, err := auth.HashPassword(user.Password)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Failed to hash password"))
+		return
+	}
+	user.Password = hashedPassword
+	if err := auth.TenantDb(c, UserController.Db).Create(&user).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.ToResponse())
 }
 
 // UpdateUser : Update a user
@@ -90,34 +138,61 @@ func (UserController *UserController) CreateUser(c *gin.Context) {
 // @Produce      json
 // @Param        id  path  string  true  "User ID"
 // @Param        user  body  models.User  true  "User"
-// @Success      200  {object}   models.User
+// @Success      200  {object}   models.UserResponse
 // @Failure      500  "Unable to update user"
 // @Failure      400  "Invalid request"
+// @Failure      403  "Forbidden"
+// @Failure      404  "User not found"
 // @Router       /user/{id} [put]
 func (UserController *UserController) UpdateUser(c *gin.Context) {
 	var user models.User
 	id := c.Param("id")
 	userId, err := uuid.Parse(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId"})
+		apierror.Abort(c, apierror.InvalidParameter("user_id"))
+		return
+	}
+
+	caller, _ := auth.CurrentUser(c)
+	if !auth.CanActAs(caller, userId) {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+
+	user, err = user.FindById(auth.TenantDb(c, UserController.Db), userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Abort(c, apierror.ErrUserNotFound)
 		return
 	}
-	user, err = user.FindById(UserController.Db, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
 
 	var updateUser models.User
 	if err := c.ShouldBindJSON(&updateUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.InvalidRequest(err))
 		return
 	}
-	if err := UserController.Db.Model(&user).Updates(updateUser).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Passwords are only changed through the auth endpoints so they always get hashed
+	if updateUser.Password != "" {
+		apierror.Abort(c, apierror.ErrPasswordNotEditable)
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	// Only admins may change the role or the organization of a user
+	if updateUser.Role != "" && updateUser.Role != user.Role && caller.Role != models.RoleAdmin {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if updateUser.OrganizationId != nil && (user.OrganizationId == nil || *updateUser.OrganizationId != *user.OrganizationId) && caller.Role != models.RoleAdmin {
+		apierror.Abort(c, apierror.ErrForbidden)
+		return
+	}
+	if err := auth.TenantDb(c, UserController.Db).Model(&user).Updates(updateUser).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, user.ToResponse())
 }
 
 // DeleteUser : Detele a user
@@ -126,28 +201,33 @@ func (UserController *UserController) UpdateUser(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "User ID"
-// @Success      200  {object}   models.User
+// @Success      200  {object}   models.UserResponse
 // @Failure      500  "Unable to delete user"
 // @Failure      400  "Invalid request"
+// @Failure      404  "User not found"
 // @Router       /user/{id} [delete]
 func (UserController *UserController) DeleteUser(c *gin.Context) {
 	var user models.User
 	id := c.Param("id")
 	userId, err := uuid.Parse(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userId"})
+		apierror.Abort(c, apierror.InvalidParameter("user_id"))
+		return
+	}
+	user, err = user.FindById(auth.TenantDb(c, UserController.Db), userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Abort(c, apierror.ErrUserNotFound)
 		return
 	}
-	user, err = user.FindById(UserController.Db, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	if err := UserController.Db.Delete(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := auth.TenantDb(c, UserController.Db).Delete(&user).Error; err != nil {
+		apierror.Abort(c, apierror.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.ToResponse())
 }
 
 // GetTrafficManagers : Get traffic managers
@@ -155,16 +235,55 @@ func (UserController *UserController) DeleteUser(c *gin.Context) {
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   models.User
+// @Param        limit  query  int  false  "Page size, 100 by default"
+// @Param        offset  query  int  false  "Number of traffic managers to skip"
+// @Param        sort  query  string  false  "Sort keys, - for a descending order"
+// @Success      200  {array}   models.UserResponse
+// @Header       200  {integer}  X-Total-Count  "Number of traffic managers matching the filters"
 // @Failure      500  "Unable to retrieve traffic managers"
 // @Router 	 	 /users/traffic_managers [get]
 func (UserController *UserController) GetTrafficManagers(c *gin.Context) {
+	var users []models.User
+	query, ok := listing.FromRequest(c, userListSpec)
+	if !ok {
+		return
+	}
+	total, err := listing.Find(auth.TenantDb(c, UserController.Db).Where("role = ?", models.RoleTrafficManager), query, &users)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal(err))
+		return
+	}
+	listing.Respond(c, query, total, models.ToResponses(users, (*models.User).ToResponse))
+}
+
+// RevokeUserSessions : Revoke every session of a user
+// @Summary      Revoke all sessions of a user
+// @Tags         users
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  "Sessions revoked"
+// @Failure      400  "Invalid request"
+// @Failure      404  "User not found"
+// @Failure      500  "Unable to revoke sessions"
+// @Router       /users/{id}/sessions [delete]
+func (UserController *UserController) RevokeUserSessions(c *gin.Context) {
 	var user models.User
-	role := models.RoleTrafficManager
-	users, err := user.FindByRole(UserController.Db, role)
+	userId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter("user_id"))
+		return
+	}
+	user, err = user.FindById(auth.TenantDb(c, UserController.Db), userId)
+	if err != nil {
+		apierror.Abort(c, apierror.ErrUserNotFound)
+		return
+	}
+
+	var sessionModel models.Session
+	revoked, err := sessionModel.RevokeAllByUserId(auth.TenantDb(c, UserController.Db), user.Id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Internal(err).WithMessage("Unable to revoke sessions"))
 		return
 	}
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked", "revoked_sessions": revoked})
 }
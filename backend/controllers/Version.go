@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"tms-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ifMatch : Version the client read before writing, from the If-Match header. 0 when the header is missing or "*"
+func ifMatch(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, apierror.InvalidParameter("If-Match")
+	}
+	return version, nil
+}
+
+// setETag : Send the version of the entity, clients send it back in If-Match to update it
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
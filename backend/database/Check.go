@@ -0,0 +1,143 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Violation is a row breaking one of the data invariants
+type Violation struct {
+	Invariant  string `json:"invariant"`
+	EntityType string `json:"entity_type"`
+	EntityId   string `json:"entity_id"`
+	Detail     string `json:"detail"`
+}
+
+// invariant is a query returning the id and a description of every row breaking the rule
+type invariant struct {
+	name       string
+	entityType string
+	query      string
+}
+
+var invariants = []invariant{
+	{
+		name:       "single_simulation",
+		entityType: "simulations",
+		query: `SELECT id::text AS entity_id, 'the simulation date must be stored once, found ' || (SELECT count(*) FROM simulations) || ' rows' AS detail
+			FROM simulations WHERE (SELECT count(*) FROM simulations) > 1`,
+	},
+	{
+		name:       "tractor_volume",
+		entityType: "tractors",
+		query: `SELECT id::text AS entity_id, 'current volume ' || current_volume || ' is outside 0..' || max_volume AS detail
+			FROM tractors WHERE current_volume < 0 OR current_volume > max_volume`,
+	},
+	{
+		name:       "tractor_load",
+		entityType: "tractors",
+		query: `SELECT t.id::text AS entity_id, 'lots on board weigh ' || sum(l.volume) || ' for a capacity of ' || t.max_volume AS detail
+			FROM tractors t JOIN lots l ON l.tractor_id = t.id AND l.in_tractor
+			GROUP BY t.id, t.max_volume HAVING sum(l.volume) > t.max_volume`,
+	},
+	{
+		name:       "tractor_in_transit_route",
+		entityType: "tractors",
+		query: `SELECT t.id::text AS entity_id, 'in transit without a route or off its route' AS detail
+			FROM tractors t
+			WHERE t.state = 'in_transit' AND (t.route_id IS NULL OR t.current_checkpoint_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM route_checkpoints rc WHERE rc.route_id = t.route_id AND rc.checkpoint_id = t.current_checkpoint_id))`,
+	},
+	{
+		name:       "lot_in_tractor",
+		entityType: "lots",
+		query: `SELECT id::text AS entity_id, 'marked in a tractor but has no tractor' AS detail
+			FROM lots WHERE in_tractor AND tractor_id IS NULL`,
+	},
+	{
+		name:       "lot_checkpoint",
+		entityType: "lots",
+		query: `SELECT l.id::text AS entity_id, 'in a tractor at another checkpoint than the tractor' AS detail
+			FROM lots l JOIN tractors t ON t.id = l.tractor_id
+			WHERE l.in_tractor AND l.current_checkpoint_id IS DISTINCT FROM t.current_checkpoint_id`,
+	},
+	{
+		name:       "known_state",
+		entityType: "lots",
+		query: `SELECT id::text AS entity_id, 'unknown state ' || quote_literal(state) AS detail
+			FROM lots WHERE state NOT IN ('available', 'pending', 'in_transit', 'archive', 'on_market', 'at_trader', 'return_from_market')`,
+	},
+	{
+		name:       "known_state",
+		entityType: "tractors",
+		query: `SELECT id::text AS entity_id, 'unknown state ' || quote_literal(state) AS detail
+			FROM tractors WHERE state NOT IN ('available', 'pending', 'in_transit', 'archive', 'on_market', 'at_trader', 'return_from_market')`,
+	},
+	{
+		name:       "route_positions",
+		entityType: "routes",
+		query: `SELECT route_id::text AS entity_id, 'checkpoint position ' || position || ' is used ' || count(*) || ' times' AS detail
+			FROM route_checkpoints GROUP BY route_id, position HAVING count(*) > 1`,
+	},
+	{
+		name:       "offer_target",
+		entityType: "offers",
+		query: `SELECT id::text AS entity_id, 'an offer is either for a lot or for a tractor' AS detail
+			FROM offers WHERE (lot_id IS NULL) = (tractor_id IS NULL)`,
+	},
+	{
+		name:       "single_accepted_bid",
+		entityType: "offers",
+		query: `SELECT b.offer_id::text AS entity_id, count(*) || ' bids accepted on a lot offer' AS detail
+			FROM bids b JOIN offers o ON o.id = b.offer_id
+			WHERE o.lot_id IS NOT NULL AND b.state = 'accepted' GROUP BY b.offer_id HAVING count(*) > 1`,
+	},
+	{
+		name:       "tenant",
+		entityType: "users",
+		query: `SELECT id::text AS entity_id, 'only admins may have no organization' AS detail
+			FROM users WHERE organization_id IS NULL AND role <> 'admin'`,
+	},
+	{
+		name:       "tenant",
+		entityType: "lots",
+		query: `SELECT id::text AS entity_id, 'has no organization' AS detail
+			FROM lots WHERE organization_id IS NULL`,
+	},
+	{
+		name:       "tenant",
+		entityType: "tractors",
+		query: `SELECT id::text AS entity_id, 'has no organization' AS detail
+			FROM tractors WHERE organization_id IS NULL`,
+	},
+	{
+		name:       "tenant",
+		entityType: "routes",
+		query: `SELECT id::text AS entity_id, 'has no organization' AS detail
+			FROM routes WHERE organization_id IS NULL`,
+	},
+}
+
+// CheckInvariants : Run every invariant and list the rows breaking them
+func CheckInvariants(db *gorm.DB) ([]Violation, error) {
+	var violations []Violation
+	for _, rule := range invariants {
+		var rows []struct {
+			EntityId string
+			Detail   string
+		}
+		if err := db.Raw(rule.query).Scan(&rows).Error; err != nil {
+			return violations, fmt.Errorf("invariant %s on %s failed: %w", rule.name, rule.entityType, err)
+		}
+		for _, row := range rows {
+			violations = append(violations, Violation{
+				Invariant:  rule.name,
+				EntityType: rule.entityType,
+				EntityId:   row.EntityId,
+				Detail:     row.Detail,
+			})
+		}
+	}
+	return violations, nil
+}
@@ -2,34 +2,85 @@ package database
 
 import (
 	"log"
+	"strings"
+	"tms-backend/config"
 	"tms-backend/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func InitDb() *gorm.DB {
-	dsn := "host=localhost user=ligne8 password=secret dbname=tms_db port=5432 sslmode=disable TimeZone=Europe/Paris"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
+// Open : Connect to the database without touching the schema
+func Open(databaseConfig config.DatabaseConfig, logLevel string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(databaseConfig.DSN()), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(gormLogLevel(logLevel)),
+	})
+}
+
+func InitDb(databaseConfig config.DatabaseConfig, logLevel string) *gorm.DB {
+	db, err := Open(databaseConfig, logLevel)
 	if err != nil {
 		log.Fatal("Failed to connect to the database:", err)
 	}
-	// AutoMigrate example for creating tables automatically
-	err = db.AutoMigrate(&models.Checkpoint{}, &models.Lot{}, &models.Tractor{}, &models.User{}, &models.Route{}, &models.RouteCheckpoint{}, &models.Simulation{}, &models.Transaction{}, &models.Offer{}, &models.Bid{})
-	if err != nil {
-		log.Fatal("Failed to migrate the database:", err)
+	if databaseConfig.AutoMigrate {
+		applied, err := MigrateUp(db)
+		if err != nil {
+			log.Fatal("Failed to migrate the database:", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+	} else if pending, err := PendingMigrations(db); err != nil {
+		log.Fatal("Failed to read the migrations:", err)
+	} else if len(pending) > 0 {
+		log.Fatalf("The database has %d pending migrations, run `tms-backend migrate up` first", len(pending))
+	}
+	if err := models.AssignDefaultOrganization(db); err != nil {
+		log.Fatal("Failed to assign the default organization:", err)
+	}
+	if err := models.RegisterTenantScope(db); err != nil {
+		log.Fatal("Failed to register the tenant scope:", err)
+	}
+	if err := models.RegisterOptimisticLocking(db); err != nil {
+		log.Fatal("Failed to register the optimistic locking:", err)
+	}
+	if err := models.RegisterAuditTrail(db); err != nil {
+		log.Fatal("Failed to register the audit trail:", err)
 	}
 	DB = db
-	//SeedDB(db)
 	log.Println("Database connection established successfully.")
 	return db
 }
 
-func SeedDB(db *gorm.DB) {
-	models.CreateCheckpoints(db)
-	SeedUsers(db)
-	SeedTractors(db)
-	SeedLots(DB)
+// gormLogLevel : SQL queries are only logged in debug, slow queries and errors from warn
+func gormLogLevel(logLevel string) logger.LogLevel {
+	switch logLevel {
+	case config.LogLevelDebug:
+		return logger.Info
+	case config.LogLevelInfo, config.LogLevelWarn:
+		return logger.Warn
+	default:
+		return logger.Error
+	}
+}
+
+// ResetDB : Empty every table, the migrations and the append-only audit log are kept
+func ResetDB(db *gorm.DB) error {
+	var tables []string
+	if err := db.Raw(`SELECT tablename FROM pg_tables
+		WHERE schemaname = CURRENT_SCHEMA() AND tablename NOT IN ('schema_migrations', 'audit_logs')`).Scan(&tables).Error; err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, `"`+table+`"`)
+	}
+	return db.Exec("TRUNCATE " + strings.Join(quoted, ", ") + " CASCADE").Error
 }
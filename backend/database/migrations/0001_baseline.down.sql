@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS "audit_logs";
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "password_reset_tokens";
+DROP TABLE IF EXISTS "sessions";
+DROP TABLE IF EXISTS "bids";
+DROP TABLE IF EXISTS "offers";
+DROP TABLE IF EXISTS "transactions";
+DROP TABLE IF EXISTS "simulations";
+DROP TABLE IF EXISTS "route_checkpoints";
+DROP TABLE IF EXISTS "lots";
+DROP TABLE IF EXISTS "tractors";
+DROP TABLE IF EXISTS "routes";
+DROP TABLE IF EXISTS "users";
+DROP TABLE IF EXISTS "organizations";
+DROP TABLE IF EXISTS "checkpoints";
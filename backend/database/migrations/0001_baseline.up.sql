@@ -0,0 +1,235 @@
+-- Schema as AutoMigrate built it from models/. Tables are only created when missing and the columns added since the
+-- first AutoMigrate schema (accounts, lockout and tenants) are added when missing, so databases created before versioned
+-- migrations are brought to this baseline.
+
+CREATE TABLE IF NOT EXISTS "checkpoints" (
+    "id" uuid,
+    "name" text NOT NULL,
+    "country" text NOT NULL,
+    "longitude" decimal NOT NULL,
+    "latitude" decimal NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "organizations" (
+    "id" uuid,
+    "name" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organizations_name" ON "organizations" ("name");
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" uuid,
+    "username" text NOT NULL,
+    "password" text NOT NULL,
+    "role" text NOT NULL,
+    "email" text,
+    "failed_login_attempts" bigint NOT NULL DEFAULT 0,
+    "locked_until" timestamptz,
+    "organization_id" uuid,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "email" text;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "failed_login_attempts" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "locked_until" timestamptz;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "organization_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_users_organization_id" ON "users" ("organization_id");
+
+CREATE TABLE IF NOT EXISTS "routes" (
+    "id" uuid,
+    "name" text NOT NULL,
+    "traffic_manager_id" uuid NOT NULL,
+    "organization_id" uuid,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_routes_traffic_manager" FOREIGN KEY ("traffic_manager_id") REFERENCES "users"("id")
+);
+ALTER TABLE "routes" ADD COLUMN IF NOT EXISTS "organization_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_routes_organization_id" ON "routes" ("organization_id");
+
+CREATE TABLE IF NOT EXISTS "tractors" (
+    "id" uuid,
+    "name" text NOT NULL,
+    "resource_type" varchar(10),
+    "max_volume" decimal NOT NULL,
+    "current_volume" decimal NOT NULL,
+    "start_checkpoint_id" uuid,
+    "end_checkpoint_id" uuid,
+    "current_checkpoint_id" uuid,
+    "state" text NOT NULL,
+    "created_at" timestamptz,
+    "owner_id" uuid NOT NULL,
+    "min_price_by_km" decimal NOT NULL,
+    "traffic_manager_id" uuid,
+    "trader_id" uuid,
+    "route_id" uuid,
+    "limit_date" timestamptz,
+    "organization_id" uuid,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_tractors_start_checkpoint" FOREIGN KEY ("start_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_tractors_end_checkpoint" FOREIGN KEY ("end_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_tractors_current_checkpoint" FOREIGN KEY ("current_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_tractors_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_tractors_traffic_manager" FOREIGN KEY ("traffic_manager_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_tractors_trader" FOREIGN KEY ("trader_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_tractors_route" FOREIGN KEY ("route_id") REFERENCES "routes"("id")
+);
+ALTER TABLE "tractors" ADD COLUMN IF NOT EXISTS "organization_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_tractors_organization_id" ON "tractors" ("organization_id");
+
+CREATE TABLE IF NOT EXISTS "lots" (
+    "id" uuid,
+    "resource_type" text NOT NULL,
+    "volume" decimal NOT NULL,
+    "start_checkpoint_id" uuid,
+    "end_checkpoint_id" uuid,
+    "tractor_id" uuid,
+    "created_at" timestamptz,
+    "current_checkpoint_id" uuid,
+    "owner_id" uuid NOT NULL,
+    "state" text NOT NULL,
+    "max_price_by_km" decimal NOT NULL,
+    "traffic_manager_id" uuid,
+    "trader_id" uuid,
+    "in_tractor" boolean NOT NULL DEFAULT false,
+    "organization_id" uuid,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_lots_trader" FOREIGN KEY ("trader_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_lots_start_checkpoint" FOREIGN KEY ("start_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_lots_end_checkpoint" FOREIGN KEY ("end_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_lots_tractor" FOREIGN KEY ("tractor_id") REFERENCES "tractors"("id"),
+    CONSTRAINT "fk_lots_current_checkpoint" FOREIGN KEY ("current_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_lots_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_lots_traffic_manager" FOREIGN KEY ("traffic_manager_id") REFERENCES "users"("id")
+);
+ALTER TABLE "lots" ADD COLUMN IF NOT EXISTS "organization_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_lots_organization_id" ON "lots" ("organization_id");
+
+CREATE TABLE IF NOT EXISTS "route_checkpoints" (
+    "id" uuid,
+    "route_id" uuid NOT NULL,
+    "checkpoint_id" uuid NOT NULL,
+    "position" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_route_checkpoints_route" FOREIGN KEY ("route_id") REFERENCES "routes"("id"),
+    CONSTRAINT "fk_route_checkpoints_checkpoint" FOREIGN KEY ("checkpoint_id") REFERENCES "checkpoints"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "simulations" (
+    "id" uuid,
+    "simulation_date" timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "transactions" (
+    "id" uuid,
+    "transaction_type" text NOT NULL,
+    "create_at" timestamptz,
+    "lot_id" uuid NOT NULL,
+    "tractor_id" uuid NOT NULL,
+    "route_id" uuid NOT NULL,
+    "checkpoint_id" uuid NOT NULL,
+    "traffic_manager_id" uuid NOT NULL,
+    "route_checkpoint_id" uuid NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_transactions_lot" FOREIGN KEY ("lot_id") REFERENCES "lots"("id"),
+    CONSTRAINT "fk_transactions_tractor" FOREIGN KEY ("tractor_id") REFERENCES "tractors"("id"),
+    CONSTRAINT "fk_transactions_route" FOREIGN KEY ("route_id") REFERENCES "routes"("id"),
+    CONSTRAINT "fk_transactions_checkpoint" FOREIGN KEY ("checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_transactions_traffic_manager" FOREIGN KEY ("traffic_manager_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_transactions_route_checkpoint" FOREIGN KEY ("route_checkpoint_id") REFERENCES "route_checkpoints"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "offers" (
+    "id" uuid,
+    "limit_date" timestamptz NOT NULL,
+    "created_at" timestamptz NOT NULL,
+    "tractor_id" uuid,
+    "lot_id" uuid,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_offers_tractor" FOREIGN KEY ("tractor_id") REFERENCES "tractors"("id"),
+    CONSTRAINT "fk_offers_lot" FOREIGN KEY ("lot_id") REFERENCES "lots"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "bids" (
+    "id" uuid,
+    "created_at" timestamptz,
+    "bid" decimal NOT NULL,
+    "offer_id" uuid NOT NULL,
+    "state" text NOT NULL,
+    "volume" decimal,
+    "owner_id" uuid,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bids_offer" FOREIGN KEY ("offer_id") REFERENCES "offers"("id"),
+    CONSTRAINT "fk_bids_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "refresh_token_hash" text NOT NULL,
+    "previous_refresh_token_hash" text,
+    "user_agent" text,
+    "ip_address" text,
+    "created_at" timestamptz,
+    "last_used_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_previous_refresh_token_hash" ON "sessions" ("previous_refresh_token_hash");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sessions_refresh_token_hash" ON "sessions" ("refresh_token_hash");
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "password_reset_tokens" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "token_hash" text NOT NULL,
+    "created_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_password_reset_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_password_reset_tokens_token_hash" ON "password_reset_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_password_reset_tokens_user_id" ON "password_reset_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "api_keys" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "name" text NOT NULL,
+    "prefix" text NOT NULL,
+    "key_hash" text NOT NULL,
+    "scopes" text NOT NULL,
+    "created_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "last_used_at" timestamptz,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_keys_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_key_hash" ON "api_keys" ("key_hash");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_user_id" ON "api_keys" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" uuid,
+    "actor_id" uuid,
+    "api_key_id" uuid,
+    "endpoint" text,
+    "action" text NOT NULL,
+    "entity_type" text NOT NULL,
+    "entity_id" text NOT NULL,
+    "before" jsonb,
+    "after" jsonb,
+    "simulation_date" timestamptz,
+    "created_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_entity" ON "audit_logs" ("entity_type", "entity_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+
+-- The audit log is append-only, the database itself ignores updates and deletes
+CREATE OR REPLACE RULE audit_logs_no_update AS ON UPDATE TO audit_logs DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_logs_no_delete AS ON DELETE TO audit_logs DO INSTEAD NOTHING;
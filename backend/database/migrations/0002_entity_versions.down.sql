@@ -0,0 +1,3 @@
+ALTER TABLE "offers" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "tractors" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "lots" DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,4 @@
+-- Optimistic locking: every update of a lot, tractor or offer bumps its version and is refused when based on a stale one
+ALTER TABLE "lots" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "tractors" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "offers" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_checkpoints_name_country";
+ALTER TABLE "checkpoints" DROP CONSTRAINT IF EXISTS "fk_checkpoints_country";
+ALTER TABLE "checkpoints" DROP COLUMN IF EXISTS "metadata";
+ALTER TABLE "checkpoints" DROP COLUMN IF EXISTS "code";
+DROP TABLE IF EXISTS "countries";
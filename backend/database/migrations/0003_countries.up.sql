@@ -0,0 +1,30 @@
+-- Countries become data managed by admins instead of a hardcoded enum, checkpoints keep the name of their country
+-- and follow it when it is renamed
+CREATE TABLE IF NOT EXISTS "countries" (
+    "id" uuid,
+    "code" varchar(2),
+    "name" text NOT NULL,
+    "metadata" jsonb NOT NULL DEFAULT '{}',
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_countries_name" ON "countries" ("name");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_countries_code" ON "countries" ("code");
+
+-- Every country already used by a checkpoint, with the ISO code of the former catalogue
+INSERT INTO "countries" ("id", "name")
+SELECT gen_random_uuid(), "country" FROM "checkpoints" GROUP BY "country"
+ON CONFLICT DO NOTHING;
+UPDATE "countries" SET "code" = CASE "name"
+    WHEN 'France' THEN 'FR'
+    WHEN 'Italy' THEN 'IT'
+    WHEN 'Switzerland' THEN 'CH'
+    WHEN 'Spain' THEN 'ES'
+    WHEN 'Portugal' THEN 'PT'
+END
+WHERE "code" IS NULL;
+
+ALTER TABLE "checkpoints" ADD COLUMN IF NOT EXISTS "code" text;
+ALTER TABLE "checkpoints" ADD COLUMN IF NOT EXISTS "metadata" jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE "checkpoints" ADD CONSTRAINT "fk_checkpoints_country"
+    FOREIGN KEY ("country") REFERENCES "countries"("name") ON UPDATE CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_checkpoints_name_country" ON "checkpoints" ("name", "country");
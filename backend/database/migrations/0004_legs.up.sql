@@ -0,0 +1,52 @@
+-- Road network: a leg is a two-way road between two checkpoints, routes may only go along open legs
+CREATE TABLE IF NOT EXISTS "legs" (
+    "id" uuid,
+    "from_checkpoint_id" uuid NOT NULL,
+    "to_checkpoint_id" uuid NOT NULL,
+    "distance_km" double precision NOT NULL,
+    "duration_minutes" bigint NOT NULL,
+    "toll_cost" double precision NOT NULL DEFAULT 0,
+    "closed" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_legs_from_checkpoint" FOREIGN KEY ("from_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "fk_legs_to_checkpoint" FOREIGN KEY ("to_checkpoint_id") REFERENCES "checkpoints"("id"),
+    CONSTRAINT "chk_legs_distinct_checkpoints" CHECK ("from_checkpoint_id" <> "to_checkpoint_id"),
+    CONSTRAINT "chk_legs_distance" CHECK ("distance_km" > 0),
+    CONSTRAINT "chk_legs_duration" CHECK ("duration_minutes" > 0),
+    CONSTRAINT "chk_legs_toll_cost" CHECK ("toll_cost" >= 0)
+);
+-- At most one leg between two checkpoints, whatever its direction
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_legs_checkpoints" ON "legs" (
+    LEAST("from_checkpoint_id", "to_checkpoint_id"), GREATEST("from_checkpoint_id", "to_checkpoint_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_legs_from_checkpoint_id" ON "legs" ("from_checkpoint_id");
+CREATE INDEX IF NOT EXISTS "idx_legs_to_checkpoint_id" ON "legs" ("to_checkpoint_id");
+
+-- Existing routes stay valid: every two consecutive stops of a route get a leg, its distance is the great-circle one
+-- times the default road factor (1.3) and its duration assumes the default average speed (70 km/h). Stops follow each
+-- other by position, which may have gaps. New databases have no route yet and get the legs of the catalogue instead
+INSERT INTO "legs" ("id", "from_checkpoint_id", "to_checkpoint_id", "distance_km", "duration_minutes", "created_at", "updated_at")
+SELECT gen_random_uuid(), "from_checkpoint_id", "to_checkpoint_id", "distance_km",
+       GREATEST(1, ROUND("distance_km" / 70 * 60)), now(), now()
+FROM (
+    SELECT DISTINCT ON (LEAST(route_stop."checkpoint_id", route_stop."next_checkpoint_id"), GREATEST(route_stop."checkpoint_id", route_stop."next_checkpoint_id"))
+           route_stop."checkpoint_id" AS "from_checkpoint_id",
+           route_stop."next_checkpoint_id" AS "to_checkpoint_id",
+           ROUND((1.3 * 2 * 6371.0088 * ASIN(LEAST(1, SQRT(
+               POWER(SIN(RADIANS((arrival."latitude" - departure."latitude")::double precision) / 2), 2) +
+               COS(RADIANS(departure."latitude"::double precision)) * COS(RADIANS(arrival."latitude"::double precision)) *
+               POWER(SIN(RADIANS((arrival."longitude" - departure."longitude")::double precision) / 2), 2)
+           ))))::numeric, 3) AS "distance_km"
+    FROM (
+        SELECT "checkpoint_id", LEAD("checkpoint_id") OVER (PARTITION BY "route_id" ORDER BY "position") AS "next_checkpoint_id"
+        FROM "route_checkpoints"
+    ) AS route_stop
+    JOIN "checkpoints" departure ON departure."id" = route_stop."checkpoint_id"
+    JOIN "checkpoints" arrival ON arrival."id" = route_stop."next_checkpoint_id"
+    WHERE route_stop."checkpoint_id" <> route_stop."next_checkpoint_id"
+    ORDER BY LEAST(route_stop."checkpoint_id", route_stop."next_checkpoint_id"), GREATEST(route_stop."checkpoint_id", route_stop."next_checkpoint_id")
+) AS "route_legs"
+WHERE "distance_km" > 0
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,101 @@
+package distance
+
+import (
+	"math"
+	"tms-backend/config"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Distance between two checkpoints, RoadKm is the one prices per km and durations should use
+type Distance struct {
+	FromCheckpointId uuid.UUID `json:"from_checkpoint_id"`
+	ToCheckpointId   uuid.UUID `json:"to_checkpoint_id"`
+	GreatCircleKm    float64   `json:"great_circle_km"`
+	RoadFactor       float64   `json:"road_factor"`
+	RoadKm           float64   `json:"road_km"`
+}
+
+// Matrix of the road distances between checkpoints, RoadKm[i][j] goes from CheckpointIds[i] to CheckpointIds[j]
+type Matrix struct {
+	CheckpointIds []uuid.UUID `json:"checkpoint_ids"`
+	RoadKm        [][]float64 `json:"road_km"`
+}
+
+var (
+	roadFactor         = config.Default().Distance.RoadFactor
+	countryRoadFactors = map[string]float64{}
+	averageSpeedKmh    = config.Default().Distance.AverageSpeedKmh
+)
+
+// Configure : Apply the road factors, called once at startup
+func Configure(distanceConfig config.DistanceConfig) {
+	roadFactor = distanceConfig.RoadFactor
+	averageSpeedKmh = distanceConfig.AverageSpeedKmh
+	countryRoadFactors = map[string]float64{}
+	for country, factor := range distanceConfig.CountryRoadFactors {
+		countryRoadFactors[country] = factor
+	}
+}
+
+// Between : Distance from one checkpoint to another
+func Between(from models.Checkpoint, to models.Checkpoint) Distance {
+	greatCircle := greatCircleKm(from, to)
+	factor := RoadFactor(from.Country, to.Country)
+	return Distance{
+		FromCheckpointId: from.Id,
+		ToCheckpointId:   to.Id,
+		GreatCircleKm:    round(greatCircle),
+		RoadFactor:       factor,
+		RoadKm:           round(greatCircle * factor),
+	}
+}
+
+// RoadKm : Road distance from one checkpoint to another
+func RoadKm(from models.Checkpoint, to models.Checkpoint) float64 {
+	return Between(from, to).RoadKm
+}
+
+// MatrixOf : Road distances between every pair of checkpoints, in the order given
+func MatrixOf(checkpoints []models.Checkpoint) Matrix {
+	matrix := Matrix{
+		CheckpointIds: make([]uuid.UUID, len(checkpoints)),
+		RoadKm:        make([][]float64, len(checkpoints)),
+	}
+	for i, from := range checkpoints {
+		matrix.CheckpointIds[i] = from.Id
+		matrix.RoadKm[i] = make([]float64, len(checkpoints))
+		for j, to := range checkpoints {
+			if i != j {
+				matrix.RoadKm[i][j] = RoadKm(from, to)
+			}
+		}
+	}
+	return matrix
+}
+
+// EstimatedMinutes : Travel time of a road distance at the configured average speed, at least a minute
+func EstimatedMinutes(roadKm float64) int64 {
+	return int64(math.Max(1, math.Round(roadKm/averageSpeedKmh*60)))
+}
+
+// RoadFactor : Multiplier from the great-circle distance to the road distance between two countries
+func RoadFactor(fromCountry string, toCountry string) float64 {
+	fromFactor, ok := countryRoadFactors[fromCountry]
+	if !ok {
+		fromFactor = roadFactor
+	}
+	toFactor, ok := countryRoadFactors[toCountry]
+	if !ok {
+		toFactor = roadFactor
+	}
+	return (fromFactor + toFactor) / 2
+}
+
+func greatCircleKm(from models.Checkpoint, to models.Checkpoint) float64 {
+	if from.Id == to.Id {
+		return 0
+	}
+	return Haversine(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
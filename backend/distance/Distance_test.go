@@ -0,0 +1,132 @@
+package distance
+
+import (
+	"math"
+	"testing"
+	"tms-backend/config"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name                                                 string
+		fromLatitude, fromLongitude, toLatitude, toLongitude float64
+		want                                                 float64
+	}{
+		{"same point", 48.8566, 2.3522, 48.8566, 2.3522, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111.195},
+		{"Paris to London", 48.8566, 2.3522, 51.5074, -0.1278, 343.557},
+		{"Lyon to Marseille", 45.764, 4.8357, 43.2965, 5.3698, 277.619},
+		{"antipodes", 0, 0, 0, 180, math.Pi * EarthRadiusKm},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Haversine(test.fromLatitude, test.fromLongitude, test.toLatitude, test.toLongitude)
+			if math.Abs(got-test.want) > 0.001 {
+				t.Errorf("Haversine = %v, want %v", got, test.want)
+			}
+			back := Haversine(test.toLatitude, test.toLongitude, test.fromLatitude, test.fromLongitude)
+			if math.Abs(got-back) > 1e-9 {
+				t.Errorf("Haversine is not symmetric, %v one way and %v back", got, back)
+			}
+		})
+	}
+}
+
+// configure applies the distance config for the test and restores the default one afterwards
+func configure(t *testing.T, distanceConfig config.DistanceConfig) {
+	Configure(distanceConfig)
+	t.Cleanup(func() { Configure(config.Default().Distance) })
+}
+
+func TestRoadFactor(t *testing.T) {
+	configure(t, config.DistanceConfig{
+		RoadFactor:         1.3,
+		CountryRoadFactors: map[string]float64{"Norway": 1.7, "Netherlands": 1.1},
+		AverageSpeedKmh:    70,
+	})
+	tests := []struct {
+		name        string
+		fromCountry string
+		toCountry   string
+		want        float64
+	}{
+		{"default factor", "France", "France", 1.3},
+		{"country factor", "Norway", "Norway", 1.7},
+		{"across a border, mean of both", "Norway", "Netherlands", 1.4},
+		{"default on one side of the border", "France", "Norway", 1.5},
+		{"unknown countries", "", "Atlantis", 1.3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RoadFactor(test.fromCountry, test.toCountry); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("RoadFactor(%q, %q) = %v, want %v", test.fromCountry, test.toCountry, got, test.want)
+			}
+			if got := RoadFactor(test.toCountry, test.fromCountry); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("RoadFactor(%q, %q) = %v, want %v", test.toCountry, test.fromCountry, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	configure(t, config.DistanceConfig{RoadFactor: 1.3, CountryRoadFactors: map[string]float64{"Norway": 1.7}, AverageSpeedKmh: 70})
+	paris := models.Checkpoint{Id: uuid.New(), Country: "France", Latitude: 48.8566, Longitude: 2.3522}
+	london := models.Checkpoint{Id: uuid.New(), Country: "United Kingdom", Latitude: 51.5074, Longitude: -0.1278}
+	oslo := models.Checkpoint{Id: uuid.New(), Country: "Norway", Latitude: 59.9139, Longitude: 10.7522}
+	moved := paris
+	moved.Latitude, moved.Longitude = 51.5074, -0.1278
+
+	tests := []struct {
+		name            string
+		from, to        models.Checkpoint
+		wantGreatCircle float64
+		wantFactor      float64
+	}{
+		{"same checkpoint", paris, paris, 0, 1.3},
+		{"default factor", paris, london, 343.557, 1.3},
+		{"reverse direction", london, paris, 343.557, 1.3},
+		{"country factor", paris, oslo, round(Haversine(paris.Latitude, paris.Longitude, oslo.Latitude, oslo.Longitude)), 1.5},
+		// The coordinates given are used, a checkpoint that moved is never measured from where it was
+		{"moved checkpoint", moved, oslo, round(Haversine(london.Latitude, london.Longitude, oslo.Latitude, oslo.Longitude)), 1.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Between(test.from, test.to)
+			if got.FromCheckpointId != test.from.Id || got.ToCheckpointId != test.to.Id {
+				t.Errorf("Between goes from %v to %v, want %v to %v", got.FromCheckpointId, got.ToCheckpointId, test.from.Id, test.to.Id)
+			}
+			if math.Abs(got.GreatCircleKm-test.wantGreatCircle) > 0.001 {
+				t.Errorf("GreatCircleKm = %v, want %v", got.GreatCircleKm, test.wantGreatCircle)
+			}
+			if math.Abs(got.RoadFactor-test.wantFactor) > 1e-9 {
+				t.Errorf("RoadFactor = %v, want %v", got.RoadFactor, test.wantFactor)
+			}
+			if want := round(got.GreatCircleKm * got.RoadFactor); math.Abs(got.RoadKm-want) > 0.002 {
+				t.Errorf("RoadKm = %v, want %v", got.RoadKm, want)
+			}
+		})
+	}
+}
+
+func TestMatrixOf(t *testing.T) {
+	configure(t, config.Default().Distance)
+	checkpoints := []models.Checkpoint{
+		{Id: uuid.New(), Latitude: 48.8566, Longitude: 2.3522},
+		{Id: uuid.New(), Latitude: 51.5074, Longitude: -0.1278},
+		{Id: uuid.New(), Latitude: 45.764, Longitude: 4.8357},
+	}
+	matrix := MatrixOf(checkpoints)
+	for i, from := range checkpoints {
+		if matrix.CheckpointIds[i] != from.Id {
+			t.Errorf("CheckpointIds[%d] = %v, want %v", i, matrix.CheckpointIds[i], from.Id)
+		}
+		for j, to := range checkpoints {
+			if want := RoadKm(from, to); matrix.RoadKm[i][j] != want {
+				t.Errorf("RoadKm[%d][%d] = %v, want %v", i, j, matrix.RoadKm[i][j], want)
+			}
+		}
+	}
+}
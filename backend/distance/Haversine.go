@@ -0,0 +1,27 @@
+package distance
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth
+const EarthRadiusKm = 6371.0088
+
+// Haversine : Great-circle distance in km between two points given in degrees
+func Haversine(fromLatitude, fromLongitude, toLatitude, toLongitude float64) float64 {
+	fromLat := radians(fromLatitude)
+	toLat := radians(toLatitude)
+	deltaLat := radians(toLatitude - fromLatitude)
+	deltaLon := radians(toLongitude - fromLongitude)
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(fromLat)*math.Cos(toLat)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// round : Keep the meters, more digits are noise for road distances
+func round(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
@@ -0,0 +1,26 @@
+package distance
+
+import (
+	"sort"
+	"tms-backend/models"
+)
+
+// Nearby is a checkpoint and its great-circle distance to a point
+type Nearby struct {
+	Checkpoint models.Checkpoint
+	DistanceKm float64
+}
+
+// Around : The checkpoints from the closest to the point to the furthest, radiusKm above 0 leaves out those further away
+func Around(checkpoints []models.Checkpoint, latitude, longitude, radiusKm float64) []Nearby {
+	nearby := make([]Nearby, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		km := round(Haversine(latitude, longitude, checkpoint.Latitude, checkpoint.Longitude))
+		if radiusKm > 0 && km > radiusKm {
+			continue
+		}
+		nearby = append(nearby, Nearby{Checkpoint: checkpoint, DistanceKm: km})
+	}
+	sort.SliceStable(nearby, func(i, j int) bool { return nearby[i].DistanceKm < nearby[j].DistanceKm })
+	return nearby
+}
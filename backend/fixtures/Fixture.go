@@ -0,0 +1,402 @@
+package fixtures
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"tms-backend/config"
+	"tms-backend/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes a whole world: who uses it, where the tractors and lots are and what is on the market.
+// Entities refer to each other by name, checkpoints by city, countries by name, lots by their key.
+// JSON being a subset of YAML, the same keys are used in both formats.
+type Fixture struct {
+	// Date the simulation is set to, YYYY-MM-DD, left as is when empty
+	SimulationDate string              `yaml:"simulation_date"`
+	Users          []UserFixture       `yaml:"users"`
+	Countries      []CountryFixture    `yaml:"countries"`
+	Checkpoints    []CheckpointFixture `yaml:"checkpoints"`
+	Legs           []LegFixture        `yaml:"legs"`
+	Routes         []RouteFixture      `yaml:"routes"`
+	Tractors       []TractorFixture    `yaml:"tractors"`
+	Lots           []LotFixture        `yaml:"lots"`
+	Offers         []OfferFixture      `yaml:"offers"`
+}
+
+type UserFixture struct {
+	Username string      `yaml:"username"`
+	Password string      `yaml:"password"`
+	Role     models.Role `yaml:"role"`
+	Email    string      `yaml:"email"`
+	// Organization joined by the user, created when missing, the default one when empty
+	Organization string `yaml:"organization"`
+}
+
+// CountryFixture declares a country missing from the checkpoint catalogue
+type CountryFixture struct {
+	Name string `yaml:"name"`
+	// ISO 3166-1 alpha-2 code, optional
+	Code string `yaml:"code"`
+}
+
+// CheckpointFixture declares a checkpoint missing from the checkpoint catalogue, its country must exist or be declared
+type CheckpointFixture struct {
+	Name      string  `yaml:"name"`
+	Country   string  `yaml:"country"`
+	Code      string  `yaml:"code"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+}
+
+// LegFixture connects two checkpoints by city, the distance and the duration are estimated when left at 0
+type LegFixture struct {
+	From            string  `yaml:"from"`
+	To              string  `yaml:"to"`
+	DistanceKm      float64 `yaml:"distance_km"`
+	DurationMinutes int64   `yaml:"duration_minutes"`
+	TollCost        float64 `yaml:"toll_cost"`
+	Closed          bool    `yaml:"closed"`
+}
+
+// Known lists what the database already has, fixtures may refer to it
+type Known struct {
+	Countries   map[string]bool
+	Checkpoints map[string]bool
+	// Legs by the cities they connect, see LegKey, true when the leg is open
+	Legs map[[2]string]bool
+}
+
+// LegKey : Key of the leg between two cities, legs are two-way so the order does not matter
+func LegKey(city string, otherCity string) [2]string {
+	if city > otherCity {
+		return [2]string{otherCity, city}
+	}
+	return [2]string{city, otherCity}
+}
+
+// RouteFixture lists the stops of a route by city, an open leg must connect every two consecutive stops
+type RouteFixture struct {
+	Name           string   `yaml:"name"`
+	TrafficManager string   `yaml:"traffic_manager"`
+	Stops          []string `yaml:"stops"`
+}
+
+type TractorFixture struct {
+	Name           string              `yaml:"name"`
+	ResourceType   models.ResourceType `yaml:"resource_type"`
+	MaxUnits       float64             `yaml:"max_units"`
+	CurrentUnits   float64             `yaml:"current_units"`
+	State          models.State        `yaml:"state"`
+	Start          string              `yaml:"start"`
+	End            string              `yaml:"end"`
+	Current        string              `yaml:"current"`
+	MinPriceByKm   float64             `yaml:"min_price_by_km"`
+	Owner          string              `yaml:"owner"`
+	TrafficManager string              `yaml:"traffic_manager"`
+	Trader         string              `yaml:"trader"`
+	Route          string              `yaml:"route"`
+}
+
+type LotFixture struct {
+	// Lots have no name, the key is only used by the fixture to refer to them
+	Key            string              `yaml:"key"`
+	ResourceType   models.ResourceType `yaml:"resource_type"`
+	Volume         float64             `yaml:"volume"`
+	State          models.State        `yaml:"state"`
+	Start          string              `yaml:"start"`
+	End            string              `yaml:"end"`
+	Current        string              `yaml:"current"`
+	MaxPriceByKm   float64             `yaml:"max_price_by_km"`
+	Owner          string              `yaml:"owner"`
+	TrafficManager string              `yaml:"traffic_manager"`
+	Trader         string              `yaml:"trader"`
+	Tractor        string              `yaml:"tractor"`
+	InTractor      bool                `yaml:"in_tractor"`
+}
+
+// OfferFixture puts a lot or a tractor on the market until a fixed date or for a number of simulated days
+type OfferFixture struct {
+	Lot         string `yaml:"lot"`
+	Tractor     string `yaml:"tractor"`
+	LimitDate   string `yaml:"limit_date"`
+	LimitInDays int    `yaml:"limit_in_days"`
+}
+
+//go:embed demo.yaml
+var demo []byte
+
+//go:embed catalogue.yaml
+var catalogue []byte
+
+// Demo : The demo world shipped with the backend
+func Demo() (Fixture, error) {
+	return Parse(demo)
+}
+
+// Catalogue : The countries and checkpoints a new database starts with, admins manage them through the API afterwards
+func Catalogue() (Fixture, error) {
+	return Parse(catalogue)
+}
+
+// ReadFile : Read a fixture from a YAML or JSON file
+func ReadFile(path string) (Fixture, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("unable to read fixture %s: %w", path, err)
+	}
+	fixture, err := Parse(content)
+	if err != nil {
+		return fixture, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// Parse : Decode a YAML or JSON fixture, unknown keys are rejected so typos do not go unnoticed
+func Parse(content []byte) (Fixture, error) {
+	var fixture Fixture
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fixture); err != nil && !errors.Is(err, io.EOF) {
+		return fixture, err
+	}
+	return fixture, nil
+}
+
+// Validate : Check the fixture on its own and against what the database already has
+func (fixture Fixture) Validate(existing Known) []error {
+	var problems []error
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if fixture.SimulationDate != "" {
+		if _, err := time.Parse(config.SimulationDateLayout, fixture.SimulationDate); err != nil {
+			invalid("simulation_date must be a YYYY-MM-DD date, got %q", fixture.SimulationDate)
+		}
+	}
+
+	roles := map[string]models.Role{}
+	for i, user := range fixture.Users {
+		where := fmt.Sprintf("users[%d]", i)
+		switch {
+		case user.Username == "":
+			invalid("%s: username is required", where)
+		case roles[user.Username] != "":
+			invalid("%s: username %q is used twice", where, user.Username)
+		}
+		if user.Password == "" {
+			invalid("%s: password is required", where)
+		}
+		switch user.Role {
+		case models.RoleAdmin:
+			if user.Organization != "" {
+				invalid("%s: admins do not belong to an organization", where)
+			}
+		case models.RoleClient, models.RoleTrafficManager, models.RoleTrader:
+		default:
+			invalid("%s: role must be one of admin, client, traffic_manager, trader, got %q", where, user.Role)
+		}
+		roles[user.Username] = user.Role
+	}
+	user := func(where string, field string, username string, role models.Role, required bool) {
+		if username == "" {
+			if required {
+				invalid("%s: %s is required", where, field)
+			}
+			return
+		}
+		if actual, ok := roles[username]; !ok {
+			invalid("%s: %s %q is not a user of the fixture", where, field, username)
+		} else if actual != role {
+			invalid("%s: %s %q must be a %s, not a %s", where, field, username, role, actual)
+		}
+	}
+
+	knownCountries := map[string]bool{}
+	for country := range existing.Countries {
+		knownCountries[country] = true
+	}
+	for i, country := range fixture.Countries {
+		where := fmt.Sprintf("countries[%d]", i)
+		if country.Name == "" {
+			invalid("%s: name is required", where)
+		}
+		if country.Code != "" && len(country.Code) != 2 {
+			invalid("%s: code must be an ISO 3166-1 alpha-2 code, got %q", where, country.Code)
+		}
+		knownCountries[country.Name] = true
+	}
+
+	known := map[string]bool{}
+	for city := range existing.Checkpoints {
+		known[city] = true
+	}
+	for i, checkpoint := range fixture.Checkpoints {
+		where := fmt.Sprintf("checkpoints[%d]", i)
+		if checkpoint.Name == "" || checkpoint.Country == "" {
+			invalid("%s: name and country are required", where)
+		} else if !knownCountries[checkpoint.Country] {
+			invalid("%s: country %q is not a known country", where, checkpoint.Country)
+		}
+		if checkpoint.Latitude < -90 || checkpoint.Latitude > 90 || checkpoint.Longitude < -180 || checkpoint.Longitude > 180 {
+			invalid("%s: latitude must be within -90..90 and longitude within -180..180", where)
+		}
+		known[checkpoint.Name] = true
+	}
+	city := func(where string, field string, name string, required bool) {
+		if name == "" {
+			if required {
+				invalid("%s: %s is required", where, field)
+			}
+			return
+		}
+		if !known[name] {
+			invalid("%s: %s %q is not a known checkpoint", where, field, name)
+		}
+	}
+
+	legs := map[[2]string]bool{}
+	for key, open := range existing.Legs {
+		legs[key] = open
+	}
+	declaredLegs := map[[2]string]bool{}
+	for i, leg := range fixture.Legs {
+		where := fmt.Sprintf("legs[%d]", i)
+		city(where, "from", leg.From, true)
+		city(where, "to", leg.To, true)
+		key := LegKey(leg.From, leg.To)
+		if leg.From != "" && leg.From == leg.To {
+			invalid("%s: a leg connects two different checkpoints", where)
+		} else if declaredLegs[key] {
+			invalid("%s: the leg between %q and %q is declared twice", where, leg.From, leg.To)
+		}
+		declaredLegs[key] = true
+		if leg.DistanceKm < 0 || leg.DurationMinutes < 0 || leg.TollCost < 0 {
+			invalid("%s: distance_km, duration_minutes and toll_cost must not be negative", where)
+		}
+		// Legs already in the database are kept as they are
+		if _, ok := legs[key]; !ok {
+			legs[key] = !leg.Closed
+		}
+	}
+
+	routes := map[string]bool{}
+	for i, route := range fixture.Routes {
+		where := fmt.Sprintf("routes[%d]", i)
+		if route.Name == "" {
+			invalid("%s: name is required", where)
+		} else if routes[route.Name] {
+			invalid("%s: route %q is declared twice", where, route.Name)
+		}
+		routes[route.Name] = true
+		user(where, "traffic_manager", route.TrafficManager, models.RoleTrafficManager, true)
+		if len(route.Stops) < 2 {
+			invalid("%s: a route needs at least two stops", where)
+		}
+		for j, stop := range route.Stops {
+			city(where, fmt.Sprintf("stops[%d]", j), stop, true)
+			if j == 0 {
+				continue
+			}
+			if open, ok := legs[LegKey(route.Stops[j-1], stop)]; !ok {
+				invalid("%s: no leg connects %q and %q", where, route.Stops[j-1], stop)
+			} else if !open {
+				invalid("%s: the leg between %q and %q is closed", where, route.Stops[j-1], stop)
+			}
+		}
+	}
+
+	tractors := map[string]bool{}
+	for i, tractor := range fixture.Tractors {
+		where := fmt.Sprintf("tractors[%d]", i)
+		if tractor.Name == "" {
+			invalid("%s: name is required", where)
+		} else if tractors[tractor.Name] {
+			invalid("%s: tractor %q is declared twice", where, tractor.Name)
+		}
+		tractors[tractor.Name] = true
+		problems = append(problems, checkResource(where, tractor.ResourceType, tractor.State)...)
+		if tractor.MaxUnits <= 0 || tractor.CurrentUnits < 0 || tractor.CurrentUnits > tractor.MaxUnits {
+			invalid("%s: current_units must be within 0..max_units and max_units positive", where)
+		}
+		city(where, "start", tractor.Start, true)
+		city(where, "end", tractor.End, true)
+		city(where, "current", tractor.Current, false)
+		user(where, "owner", tractor.Owner, models.RoleClient, true)
+		user(where, "traffic_manager", tractor.TrafficManager, models.RoleTrafficManager, false)
+		user(where, "trader", tractor.Trader, models.RoleTrader, false)
+		if tractor.Route != "" && !routes[tractor.Route] {
+			invalid("%s: route %q is not a route of the fixture", where, tractor.Route)
+		}
+		if tractor.State == models.StateInTransit && tractor.Route == "" {
+			invalid("%s: a tractor in transit needs a route", where)
+		}
+	}
+
+	lots := map[string]bool{}
+	for i, lot := range fixture.Lots {
+		where := fmt.Sprintf("lots[%d]", i)
+		if lot.Key == "" {
+			invalid("%s: key is required", where)
+		} else if lots[lot.Key] {
+			invalid("%s: lot %q is declared twice", where, lot.Key)
+		}
+		lots[lot.Key] = true
+		problems = append(problems, checkResource(where, lot.ResourceType, lot.State)...)
+		if lot.Volume <= 0 {
+			invalid("%s: volume must be positive", where)
+		}
+		city(where, "start", lot.Start, true)
+		city(where, "end", lot.End, true)
+		city(where, "current", lot.Current, false)
+		user(where, "owner", lot.Owner, models.RoleClient, true)
+		user(where, "traffic_manager", lot.TrafficManager, models.RoleTrafficManager, false)
+		user(where, "trader", lot.Trader, models.RoleTrader, false)
+		if lot.Tractor != "" && !tractors[lot.Tractor] {
+			invalid("%s: tractor %q is not a tractor of the fixture", where, lot.Tractor)
+		}
+		if lot.InTractor && lot.Tractor == "" {
+			invalid("%s: in_tractor needs a tractor", where)
+		}
+	}
+
+	for i, offer := range fixture.Offers {
+		where := fmt.Sprintf("offers[%d]", i)
+		if (offer.Lot == "") == (offer.Tractor == "") {
+			invalid("%s: an offer is either for a lot or for a tractor", where)
+		}
+		if offer.Lot != "" && !lots[offer.Lot] {
+			invalid("%s: lot %q is not a lot of the fixture", where, offer.Lot)
+		}
+		if offer.Tractor != "" && !tractors[offer.Tractor] {
+			invalid("%s: tractor %q is not a tractor of the fixture", where, offer.Tractor)
+		}
+		if offer.LimitDate == "" && offer.LimitInDays <= 0 {
+			invalid("%s: limit_date or a positive limit_in_days is required", where)
+		}
+		if offer.LimitDate != "" {
+			if _, err := time.Parse(config.SimulationDateLayout, offer.LimitDate); err != nil {
+				invalid("%s: limit_date must be a YYYY-MM-DD date, got %q", where, offer.LimitDate)
+			}
+		}
+	}
+	return problems
+}
+
+func checkResource(where string, resourceType models.ResourceType, state models.State) []error {
+	var problems []error
+	if !resourceType.Valid() {
+		problems = append(problems, fmt.Errorf("%s: resource_type must be one of bulk, solid, liquid, got %q", where, resourceType))
+	}
+	if !state.Creatable() {
+		problems = append(problems, fmt.Errorf("%s: state must be one of available, pending, in_transit, archive, on_market, at_trader, got %q", where, state))
+	}
+	return problems
+}
@@ -0,0 +1,364 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tms-backend/auth"
+	"tms-backend/config"
+	"tms-backend/distance"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// loader keeps the ids of the entities created so far, by the name the fixture knows them by
+type loader struct {
+	tx             *gorm.DB
+	organizations  map[string]uuid.UUID
+	users          map[string]models.User
+	countries      map[string]bool
+	checkpoints    map[string]models.Checkpoint
+	legs           map[[2]string]bool
+	routes         map[string]uuid.UUID
+	tractors       map[string]uuid.UUID
+	lots           map[string]uuid.UUID
+	simulationDate time.Time
+}
+
+// Load : Validate the fixture against the database and create all of it in one transaction, nothing is written on error
+func Load(db *gorm.DB, fixture Fixture) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		load := loader{
+			tx:            tx,
+			organizations: map[string]uuid.UUID{},
+			users:         map[string]models.User{},
+			countries:     map[string]bool{},
+			checkpoints:   map[string]models.Checkpoint{},
+			legs:          map[[2]string]bool{},
+			routes:        map[string]uuid.UUID{},
+			tractors:      map[string]uuid.UUID{},
+			lots:          map[string]uuid.UUID{},
+		}
+		if err := load.validate(fixture); err != nil {
+			return err
+		}
+		steps := []func(Fixture) error{
+			load.simulation,
+			load.createCountries,
+			load.createCheckpoints,
+			load.createLegs,
+			load.createUsers,
+			load.createRoutes,
+			load.createTractors,
+			load.createLots,
+			load.createOffers,
+		}
+		for _, step := range steps {
+			if err := step(fixture); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (load *loader) validate(fixture Fixture) error {
+	var countries []string
+	if err := load.tx.Model(&models.Country{}).Pluck("name", &countries).Error; err != nil {
+		return err
+	}
+	for _, country := range countries {
+		load.countries[country] = true
+	}
+	var existing []models.Checkpoint
+	if err := load.tx.Order("country").Find(&existing).Error; err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, checkpoint := range existing {
+		// Fixtures only know cities, the first country wins when several have a checkpoint with that name
+		if _, ok := load.checkpoints[checkpoint.Name]; !ok {
+			load.checkpoints[checkpoint.Name] = checkpoint
+		}
+		known[checkpoint.Name] = true
+	}
+	var legs []struct {
+		FromCity string
+		ToCity   string
+		Closed   bool
+	}
+	if err := load.tx.Raw(`SELECT f.name AS from_city, t.name AS to_city, l.closed FROM legs l
+		JOIN checkpoints f ON f.id = l.from_checkpoint_id JOIN checkpoints t ON t.id = l.to_checkpoint_id`).Scan(&legs).Error; err != nil {
+		return err
+	}
+	for _, leg := range legs {
+		load.legs[LegKey(leg.FromCity, leg.ToCity)] = !leg.Closed
+	}
+
+	problems := fixture.Validate(Known{Countries: load.countries, Checkpoints: known, Legs: load.legs})
+	for i, user := range fixture.Users {
+		var count int64
+		if err := load.tx.Model(&models.User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			problems = append(problems, fmt.Errorf("users[%d]: user %q already exists, reset the database first", i, user.Username))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+func (load *loader) simulation(fixture Fixture) error {
+	var simulation models.Simulation
+	err := load.tx.First(&simulation).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if fixture.SimulationDate != "" {
+		simulation.SimulationDate, _ = time.Parse(config.SimulationDateLayout, fixture.SimulationDate)
+		if err := load.tx.Save(&simulation).Error; err != nil {
+			return fmt.Errorf("unable to set the simulation date: %w", err)
+		}
+	}
+	if simulation.SimulationDate.IsZero() {
+		return errors.New("the simulation has no date, set simulation_date in the fixture")
+	}
+	load.simulationDate = simulation.SimulationDate
+	return nil
+}
+
+func (load *loader) createCountries(fixture Fixture) error {
+	for _, declared := range fixture.Countries {
+		if load.countries[declared.Name] {
+			continue
+		}
+		country := models.Country{Name: declared.Name}
+		if declared.Code != "" {
+			code := strings.ToUpper(declared.Code)
+			country.Code = &code
+		}
+		if err := load.tx.Create(&country).Error; err != nil {
+			return fmt.Errorf("unable to create country %s: %w", declared.Name, err)
+		}
+		load.countries[country.Name] = true
+	}
+	return nil
+}
+
+func (load *loader) createCheckpoints(fixture Fixture) error {
+	for _, declared := range fixture.Checkpoints {
+		if _, ok := load.checkpoints[declared.Name]; ok {
+			continue
+		}
+		checkpoint := models.Checkpoint{
+			Name:      declared.Name,
+			Country:   declared.Country,
+			Code:      declared.Code,
+			Latitude:  declared.Latitude,
+			Longitude: declared.Longitude,
+		}
+		if err := load.tx.Create(&checkpoint).Error; err != nil {
+			return fmt.Errorf("unable to create checkpoint %s: %w", declared.Name, err)
+		}
+		load.checkpoints[checkpoint.Name] = checkpoint
+	}
+	return nil
+}
+
+func (load *loader) createLegs(fixture Fixture) error {
+	for _, declared := range fixture.Legs {
+		if _, ok := load.legs[LegKey(declared.From, declared.To)]; ok {
+			continue
+		}
+		from := load.checkpoints[declared.From]
+		to := load.checkpoints[declared.To]
+		leg := models.Leg{
+			FromCheckpointId: from.Id,
+			ToCheckpointId:   to.Id,
+			DistanceKm:       declared.DistanceKm,
+			DurationMinutes:  declared.DurationMinutes,
+			TollCost:         declared.TollCost,
+			Closed:           declared.Closed,
+		}
+		if leg.DistanceKm == 0 {
+			leg.DistanceKm = distance.RoadKm(from, to)
+		}
+		if leg.DurationMinutes == 0 {
+			leg.DurationMinutes = distance.EstimatedMinutes(leg.DistanceKm)
+		}
+		if err := load.tx.Create(&leg).Error; err != nil {
+			return fmt.Errorf("unable to create the leg between %s and %s: %w", declared.From, declared.To, err)
+		}
+		load.legs[LegKey(declared.From, declared.To)] = !leg.Closed
+	}
+	return nil
+}
+
+func (load *loader) createUsers(fixture Fixture) error {
+	for _, declared := range fixture.Users {
+		password, err := auth.HashPassword(declared.Password)
+		if err != nil {
+			return fmt.Errorf("unable to hash the password of %s: %w", declared.Username, err)
+		}
+		user := models.User{
+			Username: declared.Username,
+			Password: password,
+			Role:     declared.Role,
+			Email:    declared.Email,
+		}
+		if declared.Role != models.RoleAdmin {
+			organizationName := declared.Organization
+			if organizationName == "" {
+				organizationName = models.DefaultOrganizationName
+			}
+			organizationId, err := load.organization(organizationName)
+			if err != nil {
+				return err
+			}
+			user.OrganizationId = &organizationId
+		}
+		if err := load.tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("unable to create user %s: %w", declared.Username, err)
+		}
+		load.users[user.Username] = user
+	}
+	return nil
+}
+
+// organization : Id of the organization with that name, created on first use
+func (load *loader) organization(name string) (uuid.UUID, error) {
+	if id, ok := load.organizations[name]; ok {
+		return id, nil
+	}
+	var organization models.Organization
+	if err := load.tx.Where(models.Organization{Name: name}).FirstOrCreate(&organization).Error; err != nil {
+		return uuid.Nil, fmt.Errorf("unable to create organization %s: %w", name, err)
+	}
+	load.organizations[name] = organization.Id
+	return organization.Id, nil
+}
+
+func (load *loader) createRoutes(fixture Fixture) error {
+	for _, declared := range fixture.Routes {
+		trafficManager := load.users[declared.TrafficManager]
+		route := models.Route{
+			Name:             declared.Name,
+			TrafficManagerId: trafficManager.Id,
+			OrganizationId:   trafficManager.OrganizationId,
+		}
+		if err := load.tx.Create(&route).Error; err != nil {
+			return fmt.Errorf("unable to create route %s: %w", declared.Name, err)
+		}
+		for position, stop := range declared.Stops {
+			routeCheckpoint := models.RouteCheckpoint{
+				RouteId:      route.Id,
+				CheckpointId: load.checkpoints[stop].Id,
+				Position:     uint(position),
+			}
+			if err := load.tx.Create(&routeCheckpoint).Error; err != nil {
+				return fmt.Errorf("unable to add stop %s to route %s: %w", stop, declared.Name, err)
+			}
+		}
+		load.routes[route.Name] = route.Id
+	}
+	return nil
+}
+
+func (load *loader) createTractors(fixture Fixture) error {
+	for _, declared := range fixture.Tractors {
+		owner := load.users[declared.Owner]
+		tractor := models.Tractor{
+			Name:                declared.Name,
+			ResourceType:        declared.ResourceType,
+			MaxVolume:           declared.MaxUnits,
+			CurrentVolume:       declared.CurrentUnits,
+			State:               declared.State,
+			StartCheckpointId:   load.checkpoint(declared.Start),
+			EndCheckpointId:     load.checkpoint(declared.End),
+			CurrentCheckpointId: load.checkpoint(declared.Current),
+			MinPriceByKm:        declared.MinPriceByKm,
+			OwnerId:             owner.Id,
+			TrafficManagerId:    load.user(declared.TrafficManager),
+			TraderId:            load.user(declared.Trader),
+			OrganizationId:      owner.OrganizationId,
+		}
+		if tractor.CurrentCheckpointId == nil {
+			tractor.CurrentCheckpointId = tractor.StartCheckpointId
+		}
+		if routeId, ok := load.routes[declared.Route]; ok {
+			tractor.RouteId = &routeId
+		}
+		if err := load.tx.Create(&tractor).Error; err != nil {
+			return fmt.Errorf("unable to create tractor %s: %w", declared.Name, err)
+		}
+		load.tractors[tractor.Name] = tractor.Id
+	}
+	return nil
+}
+
+func (load *loader) createLots(fixture Fixture) error {
+	for _, declared := range fixture.Lots {
+		owner := load.users[declared.Owner]
+		lot := models.Lot{
+			ResourceType:        declared.ResourceType,
+			Volume:              declared.Volume,
+			State:               declared.State,
+			StartCheckpointId:   load.checkpoint(declared.Start),
+			EndCheckpointId:     load.checkpoint(declared.End),
+			CurrentCheckpointId: load.checkpoint(declared.Current),
+			MaxPriceByKm:        declared.MaxPriceByKm,
+			OwnerId:             owner.Id,
+			TrafficManagerId:    load.user(declared.TrafficManager),
+			TraderId:            load.user(declared.Trader),
+			InTractor:           declared.InTractor,
+			OrganizationId:      owner.OrganizationId,
+		}
+		if lot.CurrentCheckpointId == nil {
+			lot.CurrentCheckpointId = lot.StartCheckpointId
+		}
+		if tractorId, ok := load.tractors[declared.Tractor]; ok {
+			lot.TractorId = &tractorId
+		}
+		if err := load.tx.Create(&lot).Error; err != nil {
+			return fmt.Errorf("unable to create lot %s: %w", declared.Key, err)
+		}
+		load.lots[declared.Key] = lot.Id
+	}
+	return nil
+}
+
+func (load *loader) createOffers(fixture Fixture) error {
+	for i, declared := range fixture.Offers {
+		offer := models.Offer{LimitDate: load.simulationDate.AddDate(0, 0, declared.LimitInDays)}
+		if declared.LimitDate != "" {
+			offer.LimitDate, _ = time.Parse(config.SimulationDateLayout, declared.LimitDate)
+		}
+		if lotId, ok := load.lots[declared.Lot]; ok {
+			offer.LotId = &lotId
+		}
+		if tractorId, ok := load.tractors[declared.Tractor]; ok {
+			offer.TractorId = &tractorId
+		}
+		if err := load.tx.Create(&offer).Error; err != nil {
+			return fmt.Errorf("unable to create offers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (load *loader) checkpoint(name string) *uuid.UUID {
+	if checkpoint, ok := load.checkpoints[name]; ok {
+		return &checkpoint.Id
+	}
+	return nil
+}
+
+func (load *loader) user(username string) *uuid.UUID {
+	if user, ok := load.users[username]; ok {
+		return &user.Id
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package geojson
+
+import "tms-backend/models"
+
+// FeatureCollection is the GeoJSON document (RFC 7946) every map endpoint answers with
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a geometry with the properties describing it
+type Feature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id,omitempty"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a Point or a LineString, positions are [longitude, latitude] as the RFC requires
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Collection : A collection of the features, empty rather than null without any
+func Collection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// NewFeature : A feature identified by id
+func NewFeature(id string, geometry Geometry, properties map[string]interface{}) Feature {
+	return Feature{Type: "Feature", Id: id, Geometry: geometry, Properties: properties}
+}
+
+// Point : The position of the checkpoint
+func Point(checkpoint models.Checkpoint) Geometry {
+	return Geometry{Type: "Point", Coordinates: position(checkpoint)}
+}
+
+// LineString : A line through the checkpoints in order
+func LineString(checkpoints []models.Checkpoint) Geometry {
+	positions := make([][2]float64, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		positions[i] = position(checkpoint)
+	}
+	return Geometry{Type: "LineString", Coordinates: positions}
+}
+
+func position(checkpoint models.Checkpoint) [2]float64 {
+	return [2]float64{checkpoint.Longitude, checkpoint.Latitude}
+}
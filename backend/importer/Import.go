@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"tms-backend/models"
+	"tms-backend/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Kind is what the rows of a file create
+type Kind string
+
+const (
+	KindCheckpoints Kind = "checkpoints"
+	KindTractors    Kind = "tractors"
+	KindLots        Kind = "lots"
+)
+
+// Kinds lists the kinds a file may hold
+var Kinds = []Kind{KindCheckpoints, KindTractors, KindLots}
+
+// MaxRows is the largest file imported at once
+const MaxRows = 5000
+
+// ErrInvalidFile : The file is not a CSV file with a known header, no row was read
+var ErrInvalidFile = errors.New("invalid CSV file")
+
+// ErrInvalidOwner : Tractors and lots are only imported for a client of an organization, as clients create them
+var ErrInvalidOwner = errors.New("tractors and lots belong to a client of an organization")
+
+// RowError tells why a row can not be imported, Line counts the lines of the file from the header, 1
+type RowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report sums up an import, nothing is created when Errors is not empty or on a dry run
+type Report struct {
+	Kind       Kind        `json:"kind"`
+	DryRun     bool        `json:"dry_run"`
+	Rows       int         `json:"rows"`
+	Created    int         `json:"created"`
+	CreatedIds []uuid.UUID `json:"created_ids"`
+	Errors     []RowError  `json:"errors"`
+}
+
+// Valid : Check that every row can be imported
+func (report Report) Valid() bool {
+	return len(report.Errors) == 0
+}
+
+// Import : Read the CSV file and validate every row, then create them all in one transaction unless dryRun is set or
+// a row is invalid. Tractors and lots belong to owner, checkpoints are shared. The error is ErrInvalidOwner when owner
+// is not a client of an organization and ErrInvalidFile when the file can not be read at all, per row problems are in
+// the report
+func Import(db *gorm.DB, kind Kind, owner models.User, file io.Reader, dryRun bool) (Report, error) {
+	report := Report{Kind: kind, DryRun: dryRun, CreatedIds: []uuid.UUID{}, Errors: []RowError{}}
+	if kind != KindCheckpoints && (owner.Role != models.RoleClient || owner.OrganizationId == nil) {
+		return report, fmt.Errorf("%w, %s is not one", ErrInvalidOwner, owner.Username)
+	}
+	rows, err := read(file, columns[kind])
+	if err != nil {
+		return report, err
+	}
+	report.Rows = len(rows)
+
+	var simulation models.Simulation
+	if kind != KindCheckpoints {
+		if err := db.First(&simulation).Error; err != nil {
+			return report, err
+		}
+	}
+	build, err := newBuilder(db, owner, simulation)
+	if err != nil {
+		return report, err
+	}
+	var records []interface{}
+	for _, row := range rows {
+		record, problems := build.row(kind, row)
+		report.Errors = append(report.Errors, problems...)
+		records = append(records, record)
+	}
+	if !report.Valid() || dryRun {
+		return report, nil
+	}
+
+	err = services.Atomically(db, func(tx *gorm.DB) error {
+		for _, record := range records {
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	for _, record := range records {
+		report.CreatedIds = append(report.CreatedIds, idOf(record))
+	}
+	report.Created = len(records)
+	return report, nil
+}
+
+func idOf(record interface{}) uuid.UUID {
+	switch created := record.(type) {
+	case *models.Checkpoint:
+		return created.Id
+	case *models.Tractor:
+		return created.Id
+	case *models.Lot:
+		return created.Id
+	}
+	return uuid.Nil
+}
+
+// row is a line of the file by column name
+type row struct {
+	line   int
+	values map[string]string
+}
+
+func (row row) get(column string) string {
+	return row.values[column]
+}
+
+// read : Parse the file, its first line names the columns, in any order, among the known ones
+func read(file io.Reader, known []string) ([]row, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	for i, column := range header {
+		// Spreadsheets often start the file with a byte order mark
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !slices.Contains(known, header[i]) {
+			return nil, fmt.Errorf("%w: unknown column %q, expected some of %s", ErrInvalidFile, header[i], strings.Join(known, ", "))
+		}
+		if slices.Contains(header[:i], header[i]) {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidFile, header[i])
+		}
+	}
+
+	var rows []row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("%w: more than %d rows, split the file", ErrInvalidFile, MaxRows)
+		}
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(header))
+		for i, column := range header {
+			values[column] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, row{line: line, values: values})
+	}
+	return rows, nil
+}
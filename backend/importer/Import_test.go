@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	known := []string{"name", "country", "latitude"}
+	tests := []struct {
+		name    string
+		file    string
+		want    []row
+		wantErr bool
+	}{
+		{
+			name: "header and rows",
+			file: "name,country\nLyon,France\nGenoa,Italy\n",
+			want: []row{
+				{line: 2, values: map[string]string{"name": "Lyon", "country": "France"}},
+				{line: 3, values: map[string]string{"name": "Genoa", "country": "Italy"}},
+			},
+		},
+		{
+			name: "columns in any order and case",
+			file: " Country , NAME\nFrance,Lyon\n",
+			want: []row{{line: 2, values: map[string]string{"name": "Lyon", "country": "France"}}},
+		},
+		{
+			name: "byte order mark",
+			file: "\ufeffname,country\nLyon,France\n",
+			want: []row{{line: 2, values: map[string]string{"name": "Lyon", "country": "France"}}},
+		},
+		{
+			name: "values trimmed",
+			file: "name,country\n  Lyon  ,\"France \"\n",
+			want: []row{{line: 2, values: map[string]string{"name": "Lyon", "country": "France"}}},
+		},
+		{
+			name: "lines counted with quoted line breaks",
+			file: "name,country\n\"Lyon\nPart-Dieu\",France\nGenoa,Italy\n",
+			want: []row{
+				{line: 2, values: map[string]string{"name": "Lyon\nPart-Dieu", "country": "France"}},
+				{line: 4, values: map[string]string{"name": "Genoa", "country": "Italy"}},
+			},
+		},
+		{name: "header only", file: "name,country\n", want: nil},
+		{name: "empty file", file: "", wantErr: true},
+		{name: "unknown column", file: "name,city\nLyon,Lyon\n", wantErr: true},
+		{name: "duplicate column", file: "name,country,Name\nLyon,France,Lyon\n", wantErr: true},
+		{name: "duplicate column after the byte order mark", file: "\ufeffname,NAME\nLyon,Lyon\n", wantErr: true},
+		{name: "missing field", file: "name,country\nLyon\n", wantErr: true},
+		{name: "too many rows", file: "name\n" + strings.Repeat("Lyon\n", MaxRows+1), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := read(strings.NewReader(test.file), known)
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidFile) {
+					t.Fatalf("err = %v, want ErrInvalidFile", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(rows, test.want) {
+				t.Errorf("rows = %v, want %v", rows, test.want)
+			}
+		})
+	}
+}
+
+func TestReadMaxRows(t *testing.T) {
+	rows, err := read(strings.NewReader("name\n"+strings.Repeat("Lyon\n", MaxRows)), []string{"name"})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(rows) != MaxRows {
+		t.Errorf("%d rows, want %d", len(rows), MaxRows)
+	}
+}
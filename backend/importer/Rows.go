@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tms-backend/distance"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SnapRadiusKm is how far from coordinates a checkpoint may be to stand for them
+const SnapRadiusKm = 10
+
+// columns lists the columns of each kind. A checkpoint of a tractor or a lot is given by its id, its name, "name, country"
+// when the name is not unique, or by the coordinates of a point within SnapRadiusKm of it
+var columns = map[Kind][]string{
+	KindCheckpoints: {"name", "country", "code", "latitude", "longitude"},
+	KindTractors: append([]string{"name", "resource_type", "max_units", "state", "min_price_by_km"},
+		checkpointColumns("start", "end", "current")...),
+	KindLots: append([]string{"resource_type", "volume", "state", "max_price_by_km"},
+		checkpointColumns("start", "end", "current")...),
+}
+
+func checkpointColumns(prefixes ...string) []string {
+	var names []string
+	for _, prefix := range prefixes {
+		names = append(names, prefix+"_checkpoint", prefix+"_latitude", prefix+"_longitude")
+	}
+	return names
+}
+
+// Columns : The columns a file of the kind may have
+func Columns(kind Kind) []string {
+	return columns[kind]
+}
+
+// builder turns rows into records, checking them as CreateCheckpoint, CreateTractor and CreateLot do
+type builder struct {
+	owner       models.User
+	simulation  models.Simulation
+	countries   map[string]string // country name, by name and by code
+	checkpoints []models.Checkpoint
+	byName      map[string][]models.Checkpoint
+	seen        map[[2]string]int // line of each checkpoint of the file, by name and country
+}
+
+func newBuilder(db *gorm.DB, owner models.User, simulation models.Simulation) (*builder, error) {
+	var countries []models.Country
+	if err := db.Find(&countries).Error; err != nil {
+		return nil, err
+	}
+	var checkpoints []models.Checkpoint
+	if err := db.Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	return builderOf(owner, simulation, countries, checkpoints), nil
+}
+
+// builderOf : A builder looking checkpoints and countries up among the ones given
+func builderOf(owner models.User, simulation models.Simulation, countries []models.Country, checkpoints []models.Checkpoint) *builder {
+	build := &builder{
+		owner:       owner,
+		simulation:  simulation,
+		countries:   map[string]string{},
+		checkpoints: checkpoints,
+		byName:      map[string][]models.Checkpoint{},
+		seen:        map[[2]string]int{},
+	}
+	for _, country := range countries {
+		build.countries[strings.ToLower(country.Name)] = country.Name
+		if country.Code != nil {
+			build.countries[strings.ToLower(*country.Code)] = country.Name
+		}
+	}
+	for _, checkpoint := range build.checkpoints {
+		key := strings.ToLower(checkpoint.Name)
+		build.byName[key] = append(build.byName[key], checkpoint)
+	}
+	return build
+}
+
+// check collects the problems of a row
+type check struct {
+	row      row
+	problems []RowError
+}
+
+func (check *check) fail(column string, format string, args ...interface{}) {
+	check.problems = append(check.problems, RowError{Line: check.row.line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (check *check) required(column string) string {
+	value := check.row.get(column)
+	if value == "" {
+		check.fail(column, "%s is required", column)
+	}
+	return value
+}
+
+func (check *check) number(column string, min float64, max float64) float64 {
+	raw := check.required(column)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		check.fail(column, "%s must be a number, got %q", column, raw)
+		return 0
+	}
+	if value < min || value > max {
+		check.fail(column, "%s must be between %g and %g", column, min, max)
+	}
+	return value
+}
+
+// positive : A number above 0, the API requires prices and volumes
+func (check *check) positive(column string) float64 {
+	raw := check.required(column)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value <= 0 {
+		check.fail(column, "%s must be a number above 0, got %q", column, raw)
+		return 0
+	}
+	return value
+}
+
+func (check *check) resourceType() models.ResourceType {
+	resourceType := models.ResourceType(check.required("resource_type"))
+	if resourceType != "" && !resourceType.Valid() {
+		check.fail("resource_type", "resource_type must be one of bulk, solid, liquid, got %q", resourceType)
+	}
+	return resourceType
+}
+
+func (check *check) state() models.State {
+	state := models.State(check.required("state"))
+	if state != "" && !state.Creatable() {
+		check.fail("state", "state must be one of available, pending, in_transit, archive, on_market, at_trader, got %q", state)
+	}
+	return state
+}
+
+func (build *builder) row(kind Kind, row row) (interface{}, []RowError) {
+	check := &check{row: row}
+	var record interface{}
+	switch kind {
+	case KindCheckpoints:
+		record = build.checkpoint(check)
+	case KindTractors:
+		record = build.tractor(check)
+	case KindLots:
+		record = build.lot(check)
+	}
+	return record, check.problems
+}
+
+func (build *builder) checkpoint(check *check) *models.Checkpoint {
+	checkpoint := &models.Checkpoint{
+		Name:      check.required("name"),
+		Code:      check.row.get("code"),
+		Latitude:  check.number("latitude", -90, 90),
+		Longitude: check.number("longitude", -180, 180),
+	}
+	if raw := check.required("country"); raw != "" {
+		country, ok := build.countries[strings.ToLower(raw)]
+		if !ok {
+			check.fail("country", "unknown country %q, create it first", raw)
+		}
+		checkpoint.Country = country
+	}
+	if checkpoint.Name == "" || checkpoint.Country == "" {
+		return checkpoint
+	}
+	for _, existing := range build.byName[strings.ToLower(checkpoint.Name)] {
+		if existing.Name == checkpoint.Name && existing.Country == checkpoint.Country {
+			check.fail("name", "%s already has a checkpoint named %s", checkpoint.Country, checkpoint.Name)
+		}
+	}
+	key := [2]string{checkpoint.Name, checkpoint.Country}
+	if line, ok := build.seen[key]; ok {
+		check.fail("name", "line %d already creates %s in %s", line, checkpoint.Name, checkpoint.Country)
+	} else {
+		build.seen[key] = check.row.line
+	}
+	return checkpoint
+}
+
+func (build *builder) tractor(check *check) *models.Tractor {
+	tractor := &models.Tractor{
+		Name:         check.required("name"),
+		ResourceType: check.resourceType(),
+		MaxVolume:    check.positive("max_units"),
+		State:        check.state(),
+		MinPriceByKm: check.positive("min_price_by_km"),
+		CreatedAt:    build.simulation.SimulationDate,
+		OwnerId:      build.owner.Id,
+	}
+	tractor.StartCheckpointId, tractor.EndCheckpointId, tractor.CurrentCheckpointId = build.journey(check)
+	return tractor
+}
+
+func (build *builder) lot(check *check) *models.Lot {
+	lot := &models.Lot{
+		ResourceType: check.resourceType(),
+		Volume:       check.positive("volume"),
+		State:        check.state(),
+		MaxPriceByKm: check.positive("max_price_by_km"),
+		CreatedAt:    build.simulation.SimulationDate,
+		OwnerId:      build.owner.Id,
+	}
+	lot.StartCheckpointId, lot.EndCheckpointId, lot.CurrentCheckpointId = build.journey(check)
+	return lot
+}
+
+// journey : The start, end and current checkpoints of a row, the current one is the start when left out
+func (build *builder) journey(check *check) (*uuid.UUID, *uuid.UUID, *uuid.UUID) {
+	start := build.locate(check, "start", true)
+	end := build.locate(check, "end", true)
+	current := build.locate(check, "current", false)
+	if start != nil && end != nil && *start == *end {
+		check.fail("end_checkpoint", "end_checkpoint must differ from start_checkpoint")
+	}
+	if current == nil {
+		current = start
+	}
+	return start, end, current
+}
+
+// locate : Find the checkpoint given by the columns of the prefix, nil when missing or unknown
+func (build *builder) locate(check *check, prefix string, required bool) *uuid.UUID {
+	column := prefix + "_checkpoint"
+	reference := check.row.get(column)
+	latitude, longitude := check.row.get(prefix+"_latitude"), check.row.get(prefix+"_longitude")
+	switch {
+	case reference != "":
+		return build.byReference(check, column, reference)
+	case latitude != "" || longitude != "":
+		return build.byPosition(check, prefix)
+	case required:
+		check.fail(column, "%s or %s_latitude and %s_longitude are required", column, prefix, prefix)
+	}
+	return nil
+}
+
+func (build *builder) byReference(check *check, column string, reference string) *uuid.UUID {
+	if id, err := uuid.Parse(reference); err == nil {
+		for _, checkpoint := range build.checkpoints {
+			if checkpoint.Id == id {
+				return &checkpoint.Id
+			}
+		}
+		check.fail(column, "checkpoint %s not found", id)
+		return nil
+	}
+
+	name, country := reference, ""
+	if comma := strings.LastIndex(reference, ","); comma >= 0 {
+		name, country = strings.TrimSpace(reference[:comma]), strings.TrimSpace(reference[comma+1:])
+		if known, ok := build.countries[strings.ToLower(country)]; ok {
+			country = known
+		}
+	}
+	var matches []models.Checkpoint
+	for _, checkpoint := range build.byName[strings.ToLower(name)] {
+		if country == "" || checkpoint.Country == country {
+			matches = append(matches, checkpoint)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		check.fail(column, "no checkpoint named %q", reference)
+		return nil
+	case 1:
+		return &matches[0].Id
+	}
+	check.fail(column, "%d checkpoints are named %q, write \"%s, <country>\"", len(matches), name, name)
+	return nil
+}
+
+func (build *builder) byPosition(check *check, prefix string) *uuid.UUID {
+	problems := len(check.problems)
+	latitude := check.number(prefix+"_latitude", -90, 90)
+	longitude := check.number(prefix+"_longitude", -180, 180)
+	if len(check.problems) > problems {
+		return nil
+	}
+	nearby := distance.Around(build.checkpoints, latitude, longitude, SnapRadiusKm)
+	if len(nearby) == 0 {
+		check.fail(prefix+"_checkpoint", "no checkpoint within %d km of %g, %g", SnapRadiusKm, latitude, longitude)
+		return nil
+	}
+	return &nearby[0].Checkpoint.Id
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// testCheckpoints are the checkpoints of the test builders, by a name unique among them
+var testCheckpoints = map[string]models.Checkpoint{
+	"lyon":          {Id: uuid.New(), Name: "Lyon", Country: "France", Latitude: 45.764, Longitude: 4.8357},
+	"marseille":     {Id: uuid.New(), Name: "Marseille", Country: "France", Latitude: 43.2965, Longitude: 5.3698},
+	"valence (fr)":  {Id: uuid.New(), Name: "Valence", Country: "France", Latitude: 44.9334, Longitude: 4.8924},
+	"valence (es)":  {Id: uuid.New(), Name: "Valence", Country: "Spain", Latitude: 39.4699, Longitude: -0.3763},
+	"genoa":         {Id: uuid.New(), Name: "Genoa", Country: "Italy", Latitude: 44.4056, Longitude: 8.9463},
+	"genoa harbour": {Id: uuid.New(), Name: "Genoa harbour", Country: "Italy", Latitude: 44.4072, Longitude: 8.9339},
+}
+
+func testBuilder() *builder {
+	fr, it := "FR", "IT"
+	countries := []models.Country{{Id: uuid.New(), Name: "France", Code: &fr}, {Id: uuid.New(), Name: "Italy", Code: &it}, {Id: uuid.New(), Name: "Spain"}}
+	var checkpoints []models.Checkpoint
+	for _, checkpoint := range testCheckpoints {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	organizationId := uuid.New()
+	owner := models.User{Id: uuid.New(), Role: models.RoleClient, OrganizationId: &organizationId}
+	return builderOf(owner, models.Simulation{}, countries, checkpoints)
+}
+
+// columnsOf : The columns of the problems, in order
+func columnsOf(problems []RowError) []string {
+	columns := []string{}
+	for _, problem := range problems {
+		columns = append(columns, problem.Column)
+	}
+	return columns
+}
+
+func TestBuilderCheckpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       map[string]string
+		wantCountry  string
+		wantProblems []string
+	}{
+		{"country by name", map[string]string{"name": "Turin", "country": "italy", "latitude": "45.07", "longitude": "7.68"}, "Italy", []string{}},
+		{"country by code", map[string]string{"name": "Turin", "country": "IT", "latitude": "45.07", "longitude": "7.68"}, "Italy", []string{}},
+		{"unknown country", map[string]string{"name": "Turin", "country": "Piedmont", "latitude": "45.07", "longitude": "7.68"}, "", []string{"country"}},
+		{"name taken in the country", map[string]string{"name": "Lyon", "country": "FR", "latitude": "45.76", "longitude": "4.83"}, "France", []string{"name"}},
+		{"name free in another country", map[string]string{"name": "Lyon", "country": "Spain", "latitude": "40", "longitude": "-3"}, "Spain", []string{}},
+		{"coordinates out of range", map[string]string{"name": "Nowhere", "country": "France", "latitude": "91", "longitude": "abc"}, "France", []string{"latitude", "longitude"}},
+		{"required columns", map[string]string{}, "", []string{"name", "latitude", "longitude", "country"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			build := testBuilder()
+			record, problems := build.row(KindCheckpoints, row{line: 2, values: test.values})
+			if got := columnsOf(problems); !reflect.DeepEqual(got, test.wantProblems) {
+				t.Errorf("problems on %v, want %v: %v", got, test.wantProblems, problems)
+			}
+			if got := record.(*models.Checkpoint).Country; got != test.wantCountry {
+				t.Errorf("country = %q, want %q", got, test.wantCountry)
+			}
+		})
+	}
+}
+
+func TestBuilderCheckpointTwiceInFile(t *testing.T) {
+	build := testBuilder()
+	values := map[string]string{"name": "Turin", "country": "Italy", "latitude": "45.07", "longitude": "7.68"}
+	if _, problems := build.row(KindCheckpoints, row{line: 2, values: values}); len(problems) > 0 {
+		t.Fatalf("first row: %v", problems)
+	}
+	_, problems := build.row(KindCheckpoints, row{line: 5, values: values})
+	if len(problems) != 1 || problems[0].Line != 5 || problems[0].Column != "name" {
+		t.Errorf("second row problems = %v, want one on the name of line 5", problems)
+	}
+}
+
+func TestBuilderLot(t *testing.T) {
+	lot := func(extra map[string]string) map[string]string {
+		values := map[string]string{"resource_type": "bulk", "volume": "10", "state": "available", "max_price_by_km": "2"}
+		for column, value := range extra {
+			values[column] = value
+		}
+		return values
+	}
+	tests := []struct {
+		name         string
+		values       map[string]string
+		wantStart    string
+		wantEnd      string
+		wantCurrent  string
+		wantProblems []string
+	}{
+		{"by id", lot(map[string]string{"start_checkpoint": testCheckpoints["lyon"].Id.String(), "end_checkpoint": testCheckpoints["genoa"].Id.String()}),
+			"lyon", "genoa", "lyon", []string{}},
+		{"unknown id", lot(map[string]string{"start_checkpoint": uuid.NewString(), "end_checkpoint": "Genoa"}),
+			"", "genoa", "", []string{"start_checkpoint"}},
+		{"by name, any case", lot(map[string]string{"start_checkpoint": "LYON", "end_checkpoint": "genoa", "current_checkpoint": "Marseille"}),
+			"lyon", "genoa", "marseille", []string{}},
+		{"unknown name", lot(map[string]string{"start_checkpoint": "Lyon", "end_checkpoint": "Turin"}),
+			"lyon", "", "lyon", []string{"end_checkpoint"}},
+		{"name in several countries", lot(map[string]string{"start_checkpoint": "Valence", "end_checkpoint": "Genoa"}),
+			"", "genoa", "", []string{"start_checkpoint"}},
+		{"name and country", lot(map[string]string{"start_checkpoint": "Valence, Spain", "end_checkpoint": "Genoa"}),
+			"valence (es)", "genoa", "valence (es)", []string{}},
+		{"name and country code", lot(map[string]string{"start_checkpoint": "Valence, fr", "end_checkpoint": "Genoa"}),
+			"valence (fr)", "genoa", "valence (fr)", []string{}},
+		{"name in another country", lot(map[string]string{"start_checkpoint": "Lyon, Italy", "end_checkpoint": "Genoa"}),
+			"", "genoa", "", []string{"start_checkpoint"}},
+		{"closest checkpoint to the coordinates", lot(map[string]string{"start_latitude": "44.406", "start_longitude": "8.945", "end_checkpoint": "Lyon"}),
+			"genoa", "lyon", "genoa", []string{}},
+		{"no checkpoint around the coordinates", lot(map[string]string{"start_latitude": "48.85", "start_longitude": "2.35", "end_checkpoint": "Lyon"}),
+			"", "lyon", "", []string{"start_checkpoint"}},
+		{"half the coordinates", lot(map[string]string{"start_latitude": "48.85", "end_checkpoint": "Lyon"}),
+			"", "lyon", "", []string{"start_longitude"}},
+		{"same start and end", lot(map[string]string{"start_checkpoint": "Lyon", "end_checkpoint": "lyon"}),
+			"lyon", "lyon", "lyon", []string{"end_checkpoint"}},
+		{"missing checkpoints", lot(nil), "", "", "", []string{"start_checkpoint", "end_checkpoint"}},
+		{"invalid values", map[string]string{"resource_type": "gas", "volume": "-1", "state": "sold", "start_checkpoint": "Lyon", "end_checkpoint": "Genoa"},
+			"lyon", "genoa", "lyon", []string{"resource_type", "volume", "state", "max_price_by_km"}},
+	}
+	name := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		for name, checkpoint := range testCheckpoints {
+			if checkpoint.Id == *id {
+				return name
+			}
+		}
+		return id.String()
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			build := testBuilder()
+			record, problems := build.row(KindLots, row{line: 3, values: test.values})
+			if got := columnsOf(problems); !reflect.DeepEqual(got, test.wantProblems) {
+				t.Errorf("problems on %v, want %v: %v", got, test.wantProblems, problems)
+			}
+			lot := record.(*models.Lot)
+			if got := name(lot.StartCheckpointId); got != test.wantStart {
+				t.Errorf("start = %q, want %q", got, test.wantStart)
+			}
+			if got := name(lot.EndCheckpointId); got != test.wantEnd {
+				t.Errorf("end = %q, want %q", got, test.wantEnd)
+			}
+			if got := name(lot.CurrentCheckpointId); got != test.wantCurrent {
+				t.Errorf("current = %q, want %q", got, test.wantCurrent)
+			}
+			if lot.OwnerId != build.owner.Id {
+				t.Errorf("owner = %v, want %v", lot.OwnerId, build.owner.Id)
+			}
+		})
+	}
+}
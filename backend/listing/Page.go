@@ -0,0 +1,80 @@
+package listing
+
+import (
+	"net/url"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Filter : Restrict the db to the rows matching the filters of the query
+func (query Query) Filter(db *gorm.DB) *gorm.DB {
+	if len(query.conditions) == 0 {
+		return db
+	}
+	return db.Clauses(clause.Where{Exprs: query.conditions})
+}
+
+// Sort : Order the db as requested
+func (query Query) Sort(db *gorm.DB) *gorm.DB {
+	if len(query.orders) == 0 {
+		return db
+	}
+	return db.Clauses(clause.OrderBy{Columns: query.orders})
+}
+
+// Page : Order the db and keep the rows of the requested page
+func (query Query) Page(db *gorm.DB) *gorm.DB {
+	return query.Sort(db).Limit(query.Limit).Offset(query.Offset)
+}
+
+// Find : Load the requested page in dest and count the rows matching the filters
+func Find(db *gorm.DB, query Query, dest interface{}) (int64, error) {
+	filtered := query.Filter(db)
+
+	// The count runs on a copy without the preloads, they only make sense for the page
+	var total int64
+	counter := filtered.Session(&gorm.Session{Context: filtered.Statement.Context})
+	counter.Statement.Preloads = nil
+	if err := counter.Model(dest).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, query.Page(filtered).Find(dest).Error
+}
+
+// Slice : The requested page of items already filtered and ordered in memory
+func Slice[T any](items []T, query Query) []T {
+	if query.Offset >= len(items) {
+		return []T{}
+	}
+	end := query.Offset + query.Limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[query.Offset:end]
+}
+
+// Links : URLs of the next and previous pages of the list, keyed by their rel, missing when there is no such page
+func (query Query) Links(requestUrl *url.URL, total int64) map[string]string {
+	links := map[string]string{}
+	if int64(query.Offset+query.Limit) < total {
+		links["next"] = query.pageUrl(requestUrl, query.Offset+query.Limit)
+	}
+	if query.Offset > 0 {
+		previous := query.Offset - query.Limit
+		if previous < 0 {
+			previous = 0
+		}
+		links["prev"] = query.pageUrl(requestUrl, previous)
+	}
+	return links
+}
+
+func (query Query) pageUrl(requestUrl *url.URL, offset int) string {
+	values := requestUrl.Query()
+	values.Set(ParamLimit, strconv.Itoa(query.Limit))
+	values.Set(ParamOffset, strconv.Itoa(offset))
+	page := url.URL{Path: requestUrl.Path, RawQuery: values.Encode()}
+	return page.String()
+}
@@ -0,0 +1,70 @@
+package listing
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	requestUrl, err := url.Parse("/lots?state=available&limit=10&offset=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		total  int64
+		want   map[string]string
+	}{
+		{"single page", 10, 0, 5, map[string]string{}},
+		{"first page", 10, 0, 25, map[string]string{
+			"next": "/lots?limit=10&offset=10&state=available",
+		}},
+		{"middle page", 10, 10, 25, map[string]string{
+			"next": "/lots?limit=10&offset=20&state=available",
+			"prev": "/lots?limit=10&offset=0&state=available",
+		}},
+		{"last page", 10, 20, 25, map[string]string{
+			"prev": "/lots?limit=10&offset=10&state=available",
+		}},
+		{"offset not on a page boundary", 10, 5, 25, map[string]string{
+			"next": "/lots?limit=10&offset=15&state=available",
+			"prev": "/lots?limit=10&offset=0&state=available",
+		}},
+		{"past the end", 10, 40, 25, map[string]string{
+			"prev": "/lots?limit=10&offset=30&state=available",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Query{Limit: test.limit, Offset: test.offset}.Links(requestUrl, test.total)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Links = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   []int
+	}{
+		{"first page", 2, 0, []int{1, 2}},
+		{"last page", 2, 4, []int{5}},
+		{"everything", 10, 0, []int{1, 2, 3, 4, 5}},
+		{"past the end", 2, 5, []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Slice(items, Query{Limit: test.limit, Offset: test.offset}); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Slice = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package listing
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tms-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Parameters shared by every list endpoint, the others are filters
+const (
+	ParamLimit  = "limit"
+	ParamOffset = "offset"
+	ParamSort   = "sort"
+
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Range filters are the field name with one of these suffixes, both bounds are inclusive
+const (
+	suffixFrom = "_from"
+	suffixTo   = "_to"
+)
+
+// Kind decides how the values of a filter are parsed and whether it accepts ranges
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindUUID
+	KindBool
+	KindNumber
+	KindTime
+)
+
+// Field is a column clients can filter on
+type Field struct {
+	Column string
+	Kind   Kind
+}
+
+// Spec lists what clients can filter and sort a list on, anything else is refused
+type Spec struct {
+	Filters map[string]Field
+	// Sort keys and the column they order on
+	Sorts map[string]string
+	// Sort used when the client does not send one, like "-created_at"
+	DefaultSort string
+	// Unique column ordering ties, so pages do not overlap
+	Key string
+}
+
+// Query is a parsed list request: the page to return, its filters and its order
+type Query struct {
+	Limit      int
+	Offset     int
+	conditions []clause.Expression
+	orders     []clause.OrderByColumn
+}
+
+// Parse : Read the pagination, filters and sort of a list request.
+// Filters are `field=value`, comma separated values match any of them, numbers and dates also accept `field_from` and `field_to`.
+// The sort is a comma separated list of keys, prefixed by `-` for a descending order.
+func Parse(values url.Values, spec Spec) (Query, error) {
+	query := Query{Limit: DefaultLimit}
+
+	if limit := values.Get(ParamLimit); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit <= 0 || parsedLimit > MaxLimit {
+			return query, apierror.InvalidParameter(ParamLimit).WithMessage("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+		}
+		query.Limit = parsedLimit
+	}
+	if offset := values.Get(ParamOffset); offset != "" {
+		parsedOffset, err := strconv.Atoi(offset)
+		if err != nil || parsedOffset < 0 {
+			return query, apierror.InvalidParameter(ParamOffset).WithMessage("offset must be a positive number")
+		}
+		query.Offset = parsedOffset
+	}
+
+	for _, name := range sortedKeys(spec.Filters) {
+		field := spec.Filters[name]
+		if raw := values.Get(name); raw != "" {
+			condition, err := match(name, field, raw)
+			if err != nil {
+				return query, err
+			}
+			query.conditions = append(query.conditions, condition)
+		}
+		if field.Kind != KindNumber && field.Kind != KindTime {
+			continue
+		}
+		for _, suffix := range []string{suffixFrom, suffixTo} {
+			raw := values.Get(name + suffix)
+			if raw == "" {
+				continue
+			}
+			bound, err := parseValue(name+suffix, field.Kind, raw)
+			if err != nil {
+				return query, err
+			}
+			column := clause.Column{Name: field.Column}
+			if suffix == suffixFrom {
+				query.conditions = append(query.conditions, clause.Gte{Column: column, Value: bound})
+			} else {
+				query.conditions = append(query.conditions, clause.Lte{Column: column, Value: bound})
+			}
+		}
+	}
+
+	orders, err := parseSort(values.Get(ParamSort), spec)
+	if err != nil {
+		return query, err
+	}
+	query.orders = orders
+	return query, nil
+}
+
+// match : Condition of an equality filter, several values match any of them
+func match(name string, field Field, raw string) (clause.Expression, error) {
+	var matched []interface{}
+	for _, value := range strings.Split(raw, ",") {
+		parsed, err := parseValue(name, field.Kind, strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, parsed)
+	}
+	return clause.IN{Column: clause.Column{Name: field.Column}, Values: matched}, nil
+}
+
+func parseValue(name string, kind Kind, raw string) (interface{}, error) {
+	switch kind {
+	case KindUUID:
+		if parsed, err := uuid.Parse(raw); err == nil {
+			return parsed, nil
+		}
+		return nil, apierror.InvalidParameter(name).WithMessage("Invalid " + name + ", expected a UUID")
+	case KindBool:
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			return parsed, nil
+		}
+		return nil, apierror.InvalidParameter(name).WithMessage("Invalid " + name + ", expected true or false")
+	case KindNumber:
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+			return parsed, nil
+		}
+		return nil, apierror.InvalidParameter(name).WithMessage("Invalid " + name + ", expected a number")
+	case KindTime:
+		if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+			return parsed, nil
+		}
+		return nil, apierror.InvalidParameter(name).WithMessage("Invalid " + name + " date, expected RFC3339")
+	default:
+		return raw, nil
+	}
+}
+
+func parseSort(raw string, spec Spec) ([]clause.OrderByColumn, error) {
+	if raw == "" {
+		raw = spec.DefaultSort
+	}
+	var orders []clause.OrderByColumn
+	ordered := map[string]bool{}
+	for _, key := range strings.Split(raw, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		desc := strings.HasPrefix(key, "-")
+		column, ok := spec.Sorts[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return nil, apierror.InvalidParameter(ParamSort).WithMessage("Invalid sort " + key).
+				WithDetails(gin.H{"parameter": ParamSort, "allowed": sortedKeys(spec.Sorts)})
+		}
+		orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc})
+		ordered[column] = true
+	}
+	if spec.Key != "" && !ordered[spec.Key] {
+		orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: spec.Key}})
+	}
+	return orders, nil
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
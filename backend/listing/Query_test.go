@@ -0,0 +1,170 @@
+package listing
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+	"tms-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+var testSpec = Spec{
+	Filters: map[string]Field{
+		"name":       {Column: "name", Kind: KindString},
+		"owner_id":   {Column: "owner_id", Kind: KindUUID},
+		"closed":     {Column: "closed", Kind: KindBool},
+		"volume":     {Column: "volume", Kind: KindNumber},
+		"created_at": {Column: "created_at", Kind: KindTime},
+	},
+	Sorts: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+func in(column string, values ...interface{}) clause.Expression {
+	return clause.IN{Column: clause.Column{Name: column}, Values: values}
+}
+
+func order(column string, desc bool) clause.OrderByColumn {
+	return clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}
+}
+
+func TestParse(t *testing.T) {
+	ownerId := uuid.New()
+	otherId := uuid.New()
+	date := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	byDefault := []clause.OrderByColumn{order("created_at", true), order("id", false)}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantLimit      int
+		wantOffset     int
+		wantConditions []clause.Expression
+		wantOrders     []clause.OrderByColumn
+		wantParameter  string
+	}{
+		{name: "defaults", query: "", wantLimit: DefaultLimit, wantOrders: byDefault},
+		{name: "page", query: "limit=20&offset=40", wantLimit: 20, wantOffset: 40, wantOrders: byDefault},
+		{name: "largest page", query: "limit=1000", wantLimit: MaxLimit, wantOrders: byDefault},
+		{name: "limit too large", query: "limit=1001", wantParameter: ParamLimit},
+		{name: "limit zero", query: "limit=0", wantParameter: ParamLimit},
+		{name: "limit not a number", query: "limit=ten", wantParameter: ParamLimit},
+		{name: "negative offset", query: "offset=-1", wantParameter: ParamOffset},
+		{
+			name:           "string filter",
+			query:          "name=Lyon",
+			wantLimit:      DefaultLimit,
+			wantConditions: []clause.Expression{in("name", "Lyon")},
+			wantOrders:     byDefault,
+		},
+		{
+			name:           "several values",
+			query:          "owner_id=" + ownerId.String() + ", " + otherId.String(),
+			wantLimit:      DefaultLimit,
+			wantConditions: []clause.Expression{in("owner_id", ownerId, otherId)},
+			wantOrders:     byDefault,
+		},
+		{name: "invalid uuid", query: "owner_id=42", wantParameter: "owner_id"},
+		{
+			name:           "bool filter",
+			query:          "closed=true",
+			wantLimit:      DefaultLimit,
+			wantConditions: []clause.Expression{in("closed", true)},
+			wantOrders:     byDefault,
+		},
+		{name: "invalid bool", query: "closed=maybe", wantParameter: "closed"},
+		{
+			name:      "number range",
+			query:     "volume_from=10&volume_to=20.5",
+			wantLimit: DefaultLimit,
+			wantConditions: []clause.Expression{
+				clause.Gte{Column: clause.Column{Name: "volume"}, Value: 10.0},
+				clause.Lte{Column: clause.Column{Name: "volume"}, Value: 20.5},
+			},
+			wantOrders: byDefault,
+		},
+		{name: "invalid number", query: "volume_to=lots", wantParameter: "volume_to"},
+		{
+			name:           "date from",
+			query:          "created_at_from=2024-03-01T08:30:00Z",
+			wantLimit:      DefaultLimit,
+			wantConditions: []clause.Expression{clause.Gte{Column: clause.Column{Name: "created_at"}, Value: date}},
+			wantOrders:     byDefault,
+		},
+		{name: "invalid date", query: "created_at=2024-03-01", wantParameter: "created_at"},
+		{
+			name:       "range on a string filter is ignored",
+			query:      "name_from=A",
+			wantLimit:  DefaultLimit,
+			wantOrders: byDefault,
+		},
+		{
+			name:       "unknown filter is ignored",
+			query:      "color=red",
+			wantLimit:  DefaultLimit,
+			wantOrders: byDefault,
+		},
+		{
+			name:       "sort keys",
+			query:      "sort=name,-created_at",
+			wantLimit:  DefaultLimit,
+			wantOrders: []clause.OrderByColumn{order("name", false), order("created_at", true), order("id", false)},
+		},
+		{name: "unknown sort", query: "sort=volume", wantParameter: ParamSort},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, err := Parse(values, testSpec)
+			if test.wantParameter != "" {
+				var apiErr *apierror.Error
+				if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeInvalidParameter {
+					t.Fatalf("err = %v, want an invalid %s", err, test.wantParameter)
+				}
+				if details, ok := apiErr.Details.(gin.H); !ok || details["parameter"] != test.wantParameter {
+					t.Errorf("details = %v, want the parameter %s", apiErr.Details, test.wantParameter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if query.Limit != test.wantLimit || query.Offset != test.wantOffset {
+				t.Errorf("limit %d and offset %d, want %d and %d", query.Limit, query.Offset, test.wantLimit, test.wantOffset)
+			}
+			if !reflect.DeepEqual(query.conditions, test.wantConditions) {
+				t.Errorf("conditions = %v, want %v", query.conditions, test.wantConditions)
+			}
+			if !reflect.DeepEqual(query.orders, test.wantOrders) {
+				t.Errorf("orders = %v, want %v", query.orders, test.wantOrders)
+			}
+		})
+	}
+}
+
+func TestParseSortWithKey(t *testing.T) {
+	values := url.Values{ParamSort: {"-id,name"}}
+	spec := testSpec
+	spec.Sorts = map[string]string{"id": "id", "name": "name"}
+	query, err := Parse(values, spec)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	// The key already orders the rows, it is not added again
+	want := []clause.OrderByColumn{order("id", true), order("name", false)}
+	if !reflect.DeepEqual(query.orders, want) {
+		t.Errorf("orders = %v, want %v", query.orders, want)
+	}
+}
@@ -0,0 +1,44 @@
+package listing
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"tms-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TotalCountHeader carries the number of items of a list across all its pages
+const TotalCountHeader = "X-Total-Count"
+
+// FromRequest : Parse the pagination, filters and sort of a list request, the request is aborted when they are invalid
+func FromRequest(c *gin.Context, spec Spec) (Query, bool) {
+	query, err := Parse(c.Request.URL.Query(), spec)
+	if err != nil {
+		apierror.Abort(c, err)
+		return query, false
+	}
+	return query, true
+}
+
+// Respond : Send a page of a list, the total count and the links to the next and previous pages go in the headers
+// so the body stays the plain array it always was
+func Respond(c *gin.Context, query Query, total int64, items interface{}) {
+	c.Header(TotalCountHeader, strconv.FormatInt(total, 10))
+	links := query.Links(c.Request.URL, total)
+	rels := make([]string, 0, len(links))
+	for rel := range links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	header := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		header = append(header, "<"+links[rel]+`>; rel="`+rel+`"`)
+	}
+	if len(header) > 0 {
+		c.Header("Link", strings.Join(header, ", "))
+	}
+	c.JSON(http.StatusOK, items)
+}
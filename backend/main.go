@@ -1,9 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
 	"time"
+	"tms-backend/auth"
+	"tms-backend/config"
 	"tms-backend/database"
+	"tms-backend/distance"
 	docs "tms-backend/docs"
+	"tms-backend/listing"
+	"tms-backend/middlewares"
 	"tms-backend/models"
 	"tms-backend/routes"
 
@@ -15,27 +27,103 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+const usage = `usage: tms-backend [command] [flags]
+
+commands:
+  serve                       start the HTTP server, the default command
+  migrate up|down|status      apply, roll back or list the schema migrations
+  seed [--reset]              load the demo world or --fixture, --reset empties the database first
+  simulate advance [--days N] move the simulation N days forward without the HTTP server
+  check                       validate the data invariants, exits with 1 when some are broken
+  import checkpoints|tractors|lots --file path [--owner username] [--dry-run]
+                              create rows from a CSV file, all of them or none
+
+every command accepts the configuration flags, run tms-backend serve -h to list them`
+
 // swagger embed files
 
 func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "seed":
+		err = runSeed(args)
+	case "simulate":
+		err = runSimulate(args)
+	case "check":
+		err = runCheck(args)
+	case "import":
+		err = runImport(args)
+	case "help":
+		fmt.Println(usage)
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runServe : Start the HTTP server
+func runServe(args []string) error {
+	cfg, err := loadConfig(newFlagSet("serve"), args)
+	if err != nil {
+		return err
+	}
+	if cfg.Auth.JwtSecret == "" {
+		log.Println("JWT_SECRET is not set, tokens are signed with a random key and are lost on restart")
+	}
+	if cfg.Log.Level != config.LogLevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	if err := auth.Configure(cfg.Auth, cfg.Mail); err != nil {
+		return err
+	}
+	distance.Configure(cfg.Distance)
+
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
+		AllowOrigins:     cfg.Server.CorsOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", middlewares.RequestIdHeader, "Retry-After", "ETag", listing.TotalCountHeader, "Link"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
-		AllowHeaders:     []string{"Strict-Transport-Security", "strict-origin-when-cross-origin", "Content-Type"},
+		AllowHeaders:     []string{"Strict-Transport-Security", "strict-origin-when-cross-origin", "Content-Type", "Authorization", "X-API-Key", middlewares.RequestIdHeader, "If-Match"},
 	}))
 
-	db := database.InitDb()
+	// Handlers abort with an apierror, the Errors middleware renders it with the id of the request
+	router.Use(middlewares.RequestId(), middlewares.Errors())
+	router.NoRoute(middlewares.NotFound)
+	router.Use(middlewares.Audit())
+
+	db := database.InitDb(cfg.Database, cfg.Log.Level)
 
 	// Initialize simulation datetime
-	initializeSimulationDate(db)
+	initializeSimulationDate(db, cfg.Simulation)
+	if err := seedCatalogue(db); err != nil {
+		return err
+	}
+	if cfg.Simulation.Seed {
+		if err := seedEmptyDatabase(db, cfg.Simulation.Fixture); err != nil {
+			return err
+		}
+	}
 
-	models.CreateCheckpoints(db)
 	router = routes.CheckpointsRoute(router, db)
+	router = routes.CountryRoutes(router, db)
+	router = routes.LegRoutes(router, db)
 	router = routes.LotRoutes(router, db)
 
 	router = routes.TractorRoutes(router, db)
@@ -44,18 +132,45 @@ func main() {
 	router = routes.AuthRoutes(router, db)
 	router = routes.RoutesRoute(router, db)
 	router = routes.StockExchangeRoute(router, db)
+	router = routes.ApiKeyRoutes(router, db)
+	router = routes.OrganizationRoutes(router, db)
+	router = routes.AuditLogRoutes(router, db)
 
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	router.Run(":8080")
+	if err := router.Run(cfg.Server.Address); err != nil {
+		return fmt.Errorf("failed to start the server: %w", err)
+	}
+	return nil
+}
+
+func newFlagSet(command string) *flag.FlagSet {
+	return flag.NewFlagSet("tms-backend "+command, flag.ContinueOnError)
+}
+
+// loadConfig : Read the configuration, fs holds the flags of the command next to the configuration ones
+func loadConfig(fs *flag.FlagSet, args []string) (config.Config, error) {
+	cfg, err := config.LoadCommand(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return cfg, err
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// commandDb : Changes made by a command are audited with the command as endpoint
+func commandDb(db *gorm.DB, command string) *gorm.DB {
+	return db.WithContext(models.WithAuditContext(context.Background(), &models.AuditContext{Endpoint: "cli " + command}))
 }
 
-func initializeSimulationDate(db *gorm.DB) {
+func initializeSimulationDate(db *gorm.DB, simulationConfig config.SimulationConfig) {
 	var simulation models.Simulation
 	if err := db.First(&simulation).Error; err != nil {
 		simulation = models.Simulation{
-			SimulationDate: time.Now().Truncate(24 * time.Hour),
+			SimulationDate: simulationConfig.StartTime(),
 		}
 		db.Create(&simulation)
 	}
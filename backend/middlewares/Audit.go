@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Audit : Record the endpoint of the request, the writes it makes are logged with it
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auditContext := &models.AuditContext{Endpoint: c.Request.Method + " " + c.FullPath()}
+		c.Request = c.Request.WithContext(models.WithAuditContext(c.Request.Context(), auditContext))
+		c.Next()
+	}
+}
+
+// setAuditActor : Attach the authenticated caller to the audit context of the request
+func setAuditActor(c *gin.Context, user models.User, apiKeyId *uuid.UUID) {
+	if auditContext := models.AuditContextOf(c.Request.Context()); auditContext != nil {
+		auditContext.ActorId = &user.Id
+		auditContext.ApiKeyId = apiKeyId
+	}
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authenticate : Resolve the user behind the bearer token or API key and reject anonymous requests
+func Authenticate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			tokenString, found = apiKey, true
+		}
+		if !found || tokenString == "" {
+			apierror.Abort(c, apierror.ErrMissingCredentials)
+			return
+		}
+
+		// Integrations authenticate with an API key instead of an interactive session
+		if auth.IsApiKey(tokenString) {
+			apiKey, user, err := auth.AuthenticateApiKey(db, tokenString)
+			if err != nil {
+				apierror.Abort(c, apierror.ErrInvalidApiKey)
+				return
+			}
+			auth.SetCurrentUser(c, user)
+			auth.SetCurrentApiKey(c, apiKey)
+			setAuditActor(c, user, &apiKey.Id)
+			c.Next()
+			return
+		}
+
+		userId, sessionId, err := auth.ParseAccessToken(tokenString)
+		if err != nil {
+			apierror.Abort(c, apierror.ErrInvalidToken)
+			return
+		}
+
+		// Revoked sessions are rejected right away, even if the token has not expired yet
+		var session models.Session
+		if err := session.FindById(db, sessionId); err != nil || !session.IsActive() || session.UserId != userId {
+			apierror.Abort(c, apierror.ErrSessionRevoked)
+			return
+		}
+
+		var user models.User
+		user, err = user.FindById(db, userId)
+		if err != nil {
+			apierror.Abort(c, apierror.ErrUnauthorized.WithMessage("User not found"))
+			return
+		}
+
+		auth.SetCurrentUser(c, user)
+		auth.SetCurrentSession(c, session)
+		setAuditActor(c, user, nil)
+		c.Next()
+	}
+}
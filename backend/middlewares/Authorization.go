@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize : Only let users with one of the given roles through, admins are always allowed
+func Authorize(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := auth.CurrentUser(c)
+		if !ok {
+			apierror.Abort(c, apierror.ErrUnauthorized)
+			return
+		}
+		if !auth.HasRole(user, roles...) {
+			apierror.Abort(c, apierror.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
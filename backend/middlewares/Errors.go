@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"log"
+	"tms-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors : Render the error the handlers aborted with as {"error": {code, message, details, request_id}}
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiErr := *apierror.From(c.Errors.Last().Err)
+		apiErr.RequestId = RequestIdOf(c)
+		if cause := apiErr.Cause(); cause != nil {
+			log.Printf("[%s] %s %s: %s: %v", apiErr.RequestId, c.Request.Method, c.Request.URL.Path, apiErr.Code, cause)
+		}
+		c.JSON(apiErr.Status, apierror.Envelope{Error: &apiErr})
+	}
+}
+
+// NotFound : Answer unknown endpoints with the error envelope
+func NotFound(c *gin.Context) {
+	apierror.Abort(c, apierror.ErrNotFound)
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIdHeader     = "X-Request-Id"
+	requestIdContextKey = "request_id"
+	maxRequestIdLength  = 128
+)
+
+// RequestId : Reuse the X-Request-Id of the caller or generate one, and echo it in the response
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > maxRequestIdLength {
+			requestId = uuid.NewString()
+		}
+		c.Set(requestIdContextKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+		c.Next()
+	}
+}
+
+// RequestIdOf : Id of the request, empty when the RequestId middleware is not installed
+func RequestIdOf(c *gin.Context) string {
+	return c.GetString(requestIdContextKey)
+}
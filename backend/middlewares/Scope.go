@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"tms-backend/apierror"
+	"tms-backend/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope : Make API keys hold the read or write scope of the resource, sessions are not scoped
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, ok := auth.CurrentApiKey(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		scope := auth.ScopeFor(resource, c.Request.Method)
+		if !apiKey.HasScope(scope) {
+			apierror.Abort(c, apierror.ErrMissingScope.WithMessage("API key is missing the "+scope+" scope").WithDetails(gin.H{"scope": scope}))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Interactive : Reject API keys on endpoints that manage credentials
+func Interactive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := auth.CurrentApiKey(c); ok {
+			apierror.Abort(c, apierror.ErrInteractiveOnly)
+			return
+		}
+		c.Next()
+	}
+}
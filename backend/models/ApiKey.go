@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ApiKey struct {
+	Id         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserId     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserId"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;not null"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:""`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:""`
+}
+
+func (apiKey *ApiKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if apiKey.Id == uuid.Nil {
+		apiKey.Id = uuid.New()
+	}
+	return
+}
+
+// IsActive : A key is active until it expires or gets revoked
+func (apiKey *ApiKey) IsActive() bool {
+	return apiKey.RevokedAt == nil && time.Now().Before(apiKey.ExpiresAt)
+}
+
+func (apiKey *ApiKey) HasScope(scope string) bool {
+	for _, granted := range apiKey.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (apiKey *ApiKey) Create(db *gorm.DB) error {
+	return db.Create(apiKey).Error
+}
+
+func (apiKey *ApiKey) FindById(db *gorm.DB, apiKeyId uuid.UUID) error {
+	return db.First(apiKey, "id = ?", apiKeyId).Error
+}
+
+func (apiKey *ApiKey) FindByKeyHash(db *gorm.DB, hash string) error {
+	return db.First(apiKey, "key_hash = ?", hash).Error
+}
+
+// Touch : Record that the key was just used
+func (apiKey *ApiKey) Touch(db *gorm.DB) error {
+	now := time.Now()
+	apiKey.LastUsedAt = &now
+	return db.Model(apiKey).Update("last_used_at", now).Error
+}
+
+func (apiKey *ApiKey) Revoke(db *gorm.DB) error {
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	return db.Model(apiKey).Update("revoked_at", now).Error
+}
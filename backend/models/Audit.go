@@ -0,0 +1,303 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditContext describes who is behind the writes of a request
+type AuditContext struct {
+	Endpoint string
+	ActorId  *uuid.UUID
+	ApiKeyId *uuid.UUID
+}
+
+type auditContextKey struct{}
+
+const auditBeforeKey = "audit:before"
+
+// Every audited table is keyed by an id column
+const auditIdColumn = "id"
+
+// Tables whose changes end up in the audit log
+var auditedTables = map[string]bool{
+	"lots":              true,
+	"tractors":          true,
+	"routes":            true,
+	"route_checkpoints": true,
+	"offers":            true,
+	"bids":              true,
+	"transactions":      true,
+	"simulations":       true,
+	"users":             true,
+	"organizations":     true,
+	"api_keys":          true,
+	"checkpoints":       true,
+	"countries":         true,
+	"legs":              true,
+}
+
+// Bookkeeping columns that change on every use and would drown the real changes
+var ignoredColumns = map[string]bool{
+	"last_used_at": true,
+}
+
+// Secrets never reach the audit log, only the fact that they changed
+var redactedColumns = map[string]bool{
+	"password": true,
+	"key_hash": true,
+}
+
+// WithAuditContext : Attach the audit context to a request context
+func WithAuditContext(ctx context.Context, auditContext *AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, auditContext)
+}
+
+// AuditContextOf : Get the audit context of a request context, if any
+func AuditContextOf(ctx context.Context) *AuditContext {
+	if ctx == nil {
+		return nil
+	}
+	auditContext, _ := ctx.Value(auditContextKey{}).(*AuditContext)
+	return auditContext
+}
+
+// RegisterAuditTrail : Install the callbacks recording every create, update and delete of the audited tables
+func RegisterAuditTrail(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("audit:create", auditCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("audit:before_update", captureBefore); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("audit:update", auditUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("audit:before_delete", captureBefore); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("audit:delete", auditDelete)
+}
+
+// RecordAudit : Append entries for changes made outside of gorm, like raw SQL updates
+func RecordAudit(db *gorm.DB, action AuditAction, entityType string, entityIds []uuid.UUID, before map[string]interface{}, after map[string]interface{}) error {
+	if len(entityIds) == 0 {
+		return nil
+	}
+	entries := make([]AuditLog, 0, len(entityIds))
+	for _, entityId := range entityIds {
+		entries = append(entries, newAuditLog(db, action, entityType, entityId.String(), before, after))
+	}
+	return writeAuditLogs(db, entries)
+}
+
+func isAudited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && auditedTables[db.Statement.Table]
+}
+
+func captureBefore(db *gorm.DB) {
+	if !isAudited(db) {
+		return
+	}
+	conditions := auditConditions(db)
+	if len(conditions) == 0 {
+		return
+	}
+	rows, err := loadRows(db, conditions)
+	if err != nil {
+		log.Printf("audit: unable to load %s before change: %v", db.Statement.Table, err)
+		return
+	}
+	db.Statement.Settings.Store(auditBeforeKey, rows)
+}
+
+func auditCreate(db *gorm.DB) {
+	if !isAudited(db) || db.Statement.RowsAffected == 0 {
+		return
+	}
+	ids := primaryKeysOf(db)
+	if len(ids) == 0 {
+		return
+	}
+	rows, err := loadRows(db, []clause.Expression{clause.IN{Column: clause.Column{Name: auditIdColumn}, Values: ids}})
+	if err != nil {
+		log.Printf("audit: unable to load created %s: %v", db.Statement.Table, err)
+		return
+	}
+	entries := make([]AuditLog, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, newAuditLog(db, AuditActionCreate, db.Statement.Table, fmt.Sprint(row[auditIdColumn]), nil, redact(row)))
+	}
+	logAuditError(db, writeAuditLogs(db, entries))
+}
+
+func auditUpdate(db *gorm.DB) {
+	before, ok := takeBefore(db)
+	if !ok || db.Statement.RowsAffected == 0 {
+		return
+	}
+	ids := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		ids = append(ids, row[auditIdColumn])
+	}
+	after, err := loadRows(db, []clause.Expression{clause.IN{Column: clause.Column{Name: auditIdColumn}, Values: ids}})
+	if err != nil {
+		log.Printf("audit: unable to load %s after change: %v", db.Statement.Table, err)
+		return
+	}
+	afterById := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		afterById[fmt.Sprint(row[auditIdColumn])] = row
+	}
+
+	var entries []AuditLog
+	for _, beforeRow := range before {
+		entityId := fmt.Sprint(beforeRow[auditIdColumn])
+		changedBefore, changedAfter := diffRows(beforeRow, afterById[entityId])
+		if len(changedAfter) == 0 {
+			continue
+		}
+		entries = append(entries, newAuditLog(db, AuditActionUpdate, db.Statement.Table, entityId, changedBefore, changedAfter))
+	}
+	logAuditError(db, writeAuditLogs(db, entries))
+}
+
+func auditDelete(db *gorm.DB) {
+	before, ok := takeBefore(db)
+	if !ok || db.Statement.RowsAffected == 0 {
+		return
+	}
+	entries := make([]AuditLog, 0, len(before))
+	for _, row := range before {
+		entries = append(entries, newAuditLog(db, AuditActionDelete, db.Statement.Table, fmt.Sprint(row[auditIdColumn]), redact(row), nil))
+	}
+	logAuditError(db, writeAuditLogs(db, entries))
+}
+
+func takeBefore(db *gorm.DB) ([]map[string]interface{}, bool) {
+	if db.Error != nil {
+		return nil, false
+	}
+	value, ok := db.Statement.Settings.LoadAndDelete(auditBeforeKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := value.([]map[string]interface{})
+	return rows, ok && len(rows) > 0
+}
+
+// auditConditions : Conditions selecting the rows a statement is about to change
+func auditConditions(db *gorm.DB) []clause.Expression {
+	var conditions []clause.Expression
+	if where, ok := db.Statement.Clauses["WHERE"]; ok {
+		if whereClause, ok := where.Expression.(clause.Where); ok {
+			conditions = append(conditions, whereClause.Exprs...)
+		}
+	}
+	if ids := primaryKeysOf(db); len(ids) > 0 {
+		conditions = append(conditions, clause.IN{Column: clause.Column{Name: auditIdColumn}, Values: ids})
+	}
+	return conditions
+}
+
+// primaryKeysOf : Primary keys of the models the statement works on, zero keys are skipped
+func primaryKeysOf(db *gorm.DB) []interface{} {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+	var ids []interface{}
+	collect := func(value reflect.Value) {
+		if id, isZero := field.ValueOf(db.Statement.Context, value); !isZero {
+			ids = append(ids, id)
+		}
+	}
+	switch value := reflect.Indirect(db.Statement.ReflectValue); value.Kind() {
+	case reflect.Struct:
+		collect(value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collect(reflect.Indirect(value.Index(i)))
+		}
+	}
+	return ids
+}
+
+func loadRows(db *gorm.DB, conditions []clause.Expression) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).Clauses(clause.Where{Exprs: conditions}).Find(&rows).Error
+	return rows, err
+}
+
+func diffRows(before map[string]interface{}, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+	for column, afterValue := range after {
+		beforeValue := before[column]
+		if ignoredColumns[column] || reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		if redactedColumns[column] {
+			beforeValue, afterValue = "[redacted]", "[redacted]"
+		}
+		changedBefore[column] = beforeValue
+		changedAfter[column] = afterValue
+	}
+	return changedBefore, changedAfter
+}
+
+func redact(row map[string]interface{}) map[string]interface{} {
+	for column := range row {
+		if redactedColumns[column] {
+			row[column] = "[redacted]"
+		}
+	}
+	return row
+}
+
+func newAuditLog(db *gorm.DB, action AuditAction, entityType string, entityId string, before map[string]interface{}, after map[string]interface{}) AuditLog {
+	entry := AuditLog{
+		Action:         action,
+		EntityType:     entityType,
+		EntityId:       entityId,
+		Before:         before,
+		After:          after,
+		SimulationDate: currentSimulationDate(db),
+	}
+	if auditContext := AuditContextOf(db.Statement.Context); auditContext != nil {
+		entry.Endpoint = auditContext.Endpoint
+		entry.ActorId = auditContext.ActorId
+		entry.ApiKeyId = auditContext.ApiKeyId
+	}
+	return entry
+}
+
+func currentSimulationDate(db *gorm.DB) *time.Time {
+	var simulation Simulation
+	if err := db.Session(&gorm.Session{NewDB: true}).Limit(1).Find(&simulation).Error; err != nil || simulation.SimulationDate.IsZero() {
+		return nil
+	}
+	return &simulation.SimulationDate
+}
+
+func writeAuditLogs(db *gorm.DB, entries []AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.Session(&gorm.Session{NewDB: true}).Create(&entries).Error
+}
+
+// The change is already written at this point, so a failing audit write is only logged
+func logAuditError(db *gorm.DB, err error) {
+	if err != nil {
+		log.Printf("audit: unable to record change of %s: %v", db.Statement.Table, err)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries can not be changed")
+
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "create"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+)
+
+// AuditLog is an append-only record of a change made to an entity.
+// Before and After only hold the columns that changed.
+type AuditLog struct {
+	Id             uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey"`
+	ActorId        *uuid.UUID             `json:"actor_id" gorm:"type:uuid;index"`
+	ApiKeyId       *uuid.UUID             `json:"api_key_id" gorm:"type:uuid"`
+	Endpoint       string                 `json:"endpoint" gorm:""`
+	Action         AuditAction            `json:"action" gorm:"not null"`
+	EntityType     string                 `json:"entity_type" gorm:"not null;index:idx_audit_logs_entity"`
+	EntityId       string                 `json:"entity_id" gorm:"not null;index:idx_audit_logs_entity"`
+	Before         map[string]interface{} `json:"before" gorm:"type:jsonb;serializer:json"`
+	After          map[string]interface{} `json:"after" gorm:"type:jsonb;serializer:json"`
+	SimulationDate *time.Time             `json:"simulation_date" gorm:""`
+	CreatedAt      time.Time              `json:"created_at" gorm:"not null;index"`
+}
+
+func (auditLog *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	if auditLog.Id == uuid.Nil {
+		auditLog.Id = uuid.New()
+	}
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now()
+	}
+	return
+}
+
+func (auditLog *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (auditLog *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Country groups checkpoints, checkpoints refer to it by name
+type Country struct {
+	Id       uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey"`
+	Code     *string                `json:"code" gorm:"type:varchar(2);uniqueIndex"` // ISO 3166-1 alpha-2, missing for countries created before codes
+	Name     string                 `json:"name" gorm:"not null;uniqueIndex"`
+	Metadata map[string]interface{} `json:"metadata" gorm:"type:jsonb;serializer:json;not null"`
+}
+
+func (country *Country) BeforeCreate(tx *gorm.DB) (err error) {
+	if country.Id == uuid.Nil {
+		country.Id = uuid.New()
+	}
+	return nil
+}
+
+func (country *Country) BeforeSave(tx *gorm.DB) error {
+	if country.Metadata == nil {
+		country.Metadata = map[string]interface{}{}
+	}
+	return nil
+}
+
+func (country *Country) FindById(db *gorm.DB, id uuid.UUID) error {
+	return db.First(country, "id = ?", id).Error
+}
+
+func (country *Country) FindByName(db *gorm.DB, name string) error {
+	return db.First(country, "name = ?", name).Error
+}
+
+func (country *Country) FindByCode(db *gorm.DB, code string) error {
+	return db.First(country, "code = ?", code).Error
+}
+
+// CountCheckpoints : Number of checkpoints in the country, a country with checkpoints cannot be deleted
+func (country *Country) CountCheckpoints(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&Checkpoint{}).Where("country = ?", country.Name).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Leg is a two-way road between two checkpoints, routes only go from one checkpoint to the next along an open leg
+type Leg struct {
+	Id               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	FromCheckpointId uuid.UUID  `json:"from_checkpoint_id" gorm:"type:uuid;not null"`
+	FromCheckpoint   Checkpoint `json:"from_checkpoint" gorm:"foreignKey:FromCheckpointId"`
+	ToCheckpointId   uuid.UUID  `json:"to_checkpoint_id" gorm:"type:uuid;not null"`
+	ToCheckpoint     Checkpoint `json:"to_checkpoint" gorm:"foreignKey:ToCheckpointId"`
+	DistanceKm       float64    `json:"distance_km" gorm:"not null"`
+	DurationMinutes  int64      `json:"duration_minutes" gorm:"not null"` // Typical travel time
+	TollCost         float64    `json:"toll_cost" gorm:"not null"`
+	Closed           bool       `json:"closed" gorm:"not null"` // Closed legs are kept but no new route may use them
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (leg *Leg) BeforeCreate(tx *gorm.DB) (err error) {
+	if leg.Id == uuid.Nil {
+		leg.Id = uuid.New()
+	}
+	return nil
+}
+
+func (leg *Leg) FindById(db *gorm.DB, id uuid.UUID) error {
+	return db.First(leg, "id = ?", id).Error
+}
+
+// FindBetween : The leg between two checkpoints, in either direction
+func (leg *Leg) FindBetween(db *gorm.DB, checkpointId uuid.UUID, otherCheckpointId uuid.UUID) error {
+	return db.First(leg, "(from_checkpoint_id = ? AND to_checkpoint_id = ?) OR (from_checkpoint_id = ? AND to_checkpoint_id = ?)",
+		checkpointId, otherCheckpointId, otherCheckpointId, checkpointId).Error
+}
+
+// Other : The checkpoint at the other end of the leg
+func (leg *Leg) Other(checkpointId uuid.UUID) uuid.UUID {
+	if leg.FromCheckpointId == checkpointId {
+		return leg.ToCheckpointId
+	}
+	return leg.FromCheckpointId
+}
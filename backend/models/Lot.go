@@ -20,6 +20,15 @@ const (
 	StateReturnFromMarket State = "return_from_market"
 )
 
+// Creatable : Check lots and tractors may be created in the state, return_from_market is only reached from the market
+func (state State) Creatable() bool {
+	switch state {
+	case StateAvailable, StatePending, StateInTransit, StateArchive, StateOnMarket, StateAtTrader:
+		return true
+	}
+	return false
+}
+
 type Lot struct {
 	Id                  uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
 	ResourceType        ResourceType `json:"resource_type" gorm:"not null" binding:"required"`
@@ -44,28 +53,15 @@ type Lot struct {
 	CurrentPrice        float64      `json:"current_price" gorm:"-"`
 	InTractor           bool         `json:"in_tractor" gorm:"not null;default:false"`
 	LimitDate           time.Time    `json:"limit_date" gorm:"-"`
+	OrganizationId      *uuid.UUID   `json:"organization_id" gorm:"type:uuid;index"` // Tenant owning the lot
+	Version             int64        `json:"version" gorm:"not null;default:1"`      // Bumped on every update, sent as the ETag
 }
 
 func (lot *Lot) BeforeCreate(tx *gorm.DB) (err error) {
-	validTypes := map[ResourceType]bool{
-		ResourceTypeBulk:   true,
-		ResourceTypeSolid:  true,
-		ResourceTypeLiquid: true,
-	}
-	if !validTypes[lot.ResourceType] {
+	if !lot.ResourceType.Valid() {
 		return errors.New("invalid resource type")
 	}
-
-	validState := map[State]bool{
-		StateAvailable: true,
-		StateArchive:   true,
-		StateAtTrader:  true,
-		StateInTransit: true,
-		StateOnMarket:  true,
-		StatePending:   true,
-	}
-
-	if !validState[lot.State] {
+	if !lot.State.Creatable() {
 		return errors.New("invalid valid state")
 	}
 	if lot.Id == uuid.Nil {
@@ -106,14 +102,6 @@ func (lot *Lot) GetLotsByState(db *gorm.DB, state State) ([]Lot, error) {
 	return lots, nil
 }
 
-func (lot *Lot) GetLotsByTrafficManager(db *gorm.DB, trafficManagerId uuid.UUID) ([]Lot, error) {
-	var lots []Lot
-	if err := db.Preload("EndCheckpoint").Preload("StartCheckpoint").Preload("CurrentCheckpoint").Preload("Tractor").Where("traffic_manager_id = ?", trafficManagerId).Find(&lots).Error; err != nil {
-		return nil, err
-	}
-	return lots, nil
-}
-
 func (lot *Lot) GetLotsByTrader(db *gorm.DB, traderId uuid.UUID) ([]Lot, error) {
 	var lots []Lot
 	if err := db.Preload("EndCheckpoint").Preload("StartCheckpoint").Preload("Tractor").Where("trader_id = ?", traderId).Find(&lots).Error; err != nil {
@@ -122,14 +110,6 @@ func (lot *Lot) GetLotsByTrader(db *gorm.DB, traderId uuid.UUID) ([]Lot, error)
 	return lots, nil
 }
 
-func (lot *Lot) GetLotsByOwner(db *gorm.DB, ownerId uuid.UUID) ([]Lot, error) {
-	var lots []Lot
-	if err := db.Preload("EndCheckpoint").Preload("StartCheckpoint").Preload("Tractor").Preload("CurrentCheckpoint").Preload("TrafficManager").Where("owner_id = ?", ownerId).Find(&lots).Error; err != nil {
-		return nil, err
-	}
-	return lots, nil
-}
-
 func (lot *Lot) AssociateTraficManager(db *gorm.DB, trafficManagerId uuid.UUID) error {
 	return db.Model(&lot).Preload("StartCheckpoint").Preload("EndCheckpoint").Preload("CurrentCheckpoint").Update("traffic_manager_id", trafficManagerId).Error
 }
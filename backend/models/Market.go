@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The stock exchange is shared by every organization, so offers and bids only expose
+// what a buyer needs: no owner, traffic manager, trader or organization.
+
+type MarketLotOffer struct {
+	OfferId      uuid.UUID    `json:"offer_id"`
+	LimitDate    time.Time    `json:"limit_date"`
+	LotId        uuid.UUID    `json:"lot_id"`
+	ResourceType ResourceType `json:"resource_type"`
+	Volume       float64      `json:"volume"`
+	MaxPriceByKm float64      `json:"max_price_by_km"`
+	CurrentPrice float64      `json:"current_price"`
+}
+
+type MarketTractorOffer struct {
+	OfferId      uuid.UUID    `json:"offer_id"`
+	LimitDate    time.Time    `json:"limit_date"`
+	TractorId    uuid.UUID    `json:"tractor_id"`
+	ResourceType ResourceType `json:"resource_type"`
+	CurrentUnits float64      `json:"current_units"`
+	MaxUnits     float64      `json:"max_units"`
+	MinPriceByKm float64      `json:"min_price_by_km"`
+	CurrentPrice float64      `json:"current_price"`
+}
+
+type MarketBid struct {
+	Id        uuid.UUID `json:"id"`
+	OfferId   uuid.UUID `json:"offer_id"`
+	Bid       float64   `json:"bid"`
+	Volume    float64   `json:"volume"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToMarket : Strip the bid of the identity of its owner
+func (bid *Bid) ToMarket() MarketBid {
+	return MarketBid{
+		Id:        bid.Id,
+		OfferId:   bid.OfferId,
+		Bid:       bid.Bid,
+		Volume:    bid.Volume,
+		State:     bid.State,
+		CreatedAt: bid.CreatedAt,
+	}
+}
@@ -15,6 +15,7 @@ type Offer struct {
 	Tractor   *Tractor   `json:"tractor" gorm:"foreignKey:TractorId"`
 	LotId     *uuid.UUID `json:"lot_id" gorm:""` // Changed to pointer to allow null values
 	Lot       *Lot       `json:"lot" gorm:"foreignKey:LotId"`
+	Version   int64      `json:"version" gorm:"not null;default:1"` // Bumped on every update, sent as the ETag
 }
 
 func (offer *Offer) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Organization is the tenant users, lots, tractors and routes belong to
+type Organization struct {
+	Id        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex" binding:"required"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (organization *Organization) BeforeCreate(tx *gorm.DB) (err error) {
+	if organization.Id == uuid.Nil {
+		organization.Id = uuid.New()
+	}
+	return
+}
+
+func (organization *Organization) Create(db *gorm.DB) error {
+	return db.Create(organization).Error
+}
+
+func (organization *Organization) FindById(db *gorm.DB, organizationId uuid.UUID) error {
+	return db.First(organization, "id = ?", organizationId).Error
+}
+
+func (organization *Organization) FindByName(db *gorm.DB, name string) error {
+	return db.First(organization, "name = ?", name).Error
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetToken struct {
+	Id        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserId    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserId"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at" gorm:""`
+}
+
+func (resetToken *PasswordResetToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if resetToken.Id == uuid.Nil {
+		resetToken.Id = uuid.New()
+	}
+	return
+}
+
+func (resetToken *PasswordResetToken) IsUsable() bool {
+	return resetToken.UsedAt == nil && time.Now().Before(resetToken.ExpiresAt)
+}
+
+func (resetToken *PasswordResetToken) Create(db *gorm.DB) error {
+	return db.Create(resetToken).Error
+}
+
+func (resetToken *PasswordResetToken) FindByTokenHash(db *gorm.DB, hash string) error {
+	return db.First(resetToken, "token_hash = ?", hash).Error
+}
+
+// Use : Mark the token used unless it already is or has expired, false when another request used it first
+func (resetToken *PasswordResetToken) Use(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	result := db.Model(&PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", resetToken.Id, now).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	resetToken.UsedAt = &now
+	return true, nil
+}
+
+// InvalidateAllByUserId : Burn every pending reset token of the user
+func (resetToken *PasswordResetToken) InvalidateAllByUserId(db *gorm.DB, userId uuid.UUID) error {
+	return db.Model(&PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", userId).Update("used_at", time.Now()).Error
+}
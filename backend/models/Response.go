@@ -0,0 +1,430 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The API answers with these representations rather than with the models themselves, so secrets such as the
+// password hash never leave the server and the associations only appear when the client asks for them.
+
+// Expansion holds the associations a client asked to embed in a response, from their JSON name to the field preloading them
+type Expansion map[string]string
+
+// Associations clients can expand, from their JSON name to the field preloading them
+var (
+	LotExpansions = map[string]string{
+		"start_checkpoint":   "StartCheckpoint",
+		"end_checkpoint":     "EndCheckpoint",
+		"current_checkpoint": "CurrentCheckpoint",
+		"tractor":            "Tractor",
+		"owner":              "Owner",
+		"traffic_manager":    "TrafficManager",
+		"trader":             "Trader",
+	}
+	TractorExpansions = map[string]string{
+		"start_checkpoint":   "StartCheckpoint",
+		"end_checkpoint":     "EndCheckpoint",
+		"current_checkpoint": "CurrentCheckpoint",
+		"route":              "Route",
+		"owner":              "Owner",
+		"traffic_manager":    "TrafficManager",
+		"trader":             "Trader",
+	}
+	RouteExpansions = map[string]string{
+		"traffic_manager": "TrafficManager",
+	}
+	LegExpansions = map[string]string{
+		"from_checkpoint": "FromCheckpoint",
+		"to_checkpoint":   "ToCheckpoint",
+	}
+)
+
+// Has : Whether the client asked for the association
+func (expansion Expansion) Has(name string) bool {
+	_, ok := expansion[name]
+	return ok
+}
+
+// Preload : Load the expanded associations along with the models
+func (expansion Expansion) Preload(db *gorm.DB) *gorm.DB {
+	fields := make([]string, 0, len(expansion))
+	for _, field := range expansion {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		db = db.Preload(field)
+	}
+	return db
+}
+
+// Load : Reload a model with the expanded associations, nothing is queried when none was asked for
+func (expansion Expansion) Load(db *gorm.DB, model interface{}) error {
+	if len(expansion) == 0 {
+		return nil
+	}
+	return expansion.Preload(db).First(model).Error
+}
+
+type UserResponse struct {
+	Id             uuid.UUID  `json:"id"`
+	Username       string     `json:"username"`
+	Role           Role       `json:"role"`
+	Email          string     `json:"email"`
+	OrganizationId *uuid.UUID `json:"organization_id"`
+}
+
+type CheckpointResponse struct {
+	Id        uuid.UUID              `json:"id"`
+	Name      string                 `json:"name"`
+	Country   string                 `json:"country"`
+	Code      string                 `json:"code"`
+	Longitude float64                `json:"longitude"`
+	Latitude  float64                `json:"latitude"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+type CountryResponse struct {
+	Id       uuid.UUID              `json:"id"`
+	Code     *string                `json:"code"`
+	Name     string                 `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type OrganizationResponse struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LegResponse struct {
+	Id               uuid.UUID           `json:"id"`
+	FromCheckpointId uuid.UUID           `json:"from_checkpoint_id"`
+	FromCheckpoint   *CheckpointResponse `json:"from_checkpoint,omitempty"`
+	ToCheckpointId   uuid.UUID           `json:"to_checkpoint_id"`
+	ToCheckpoint     *CheckpointResponse `json:"to_checkpoint,omitempty"`
+	DistanceKm       float64             `json:"distance_km"`
+	DurationMinutes  int64               `json:"duration_minutes"`
+	TollCost         float64             `json:"toll_cost"`
+	Closed           bool                `json:"closed"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+type RouteResponse struct {
+	Id               uuid.UUID     `json:"id"`
+	Name             string        `json:"name"`
+	TrafficManagerId uuid.UUID     `json:"traffic_manager_id"`
+	TrafficManager   *UserResponse `json:"traffic_manager,omitempty"`
+	OrganizationId   *uuid.UUID    `json:"organization_id"`
+}
+
+type RouteCheckpointResponse struct {
+	Id           uuid.UUID          `json:"id"`
+	RouteId      uuid.UUID          `json:"route_id"`
+	CheckpointId uuid.UUID          `json:"checkpoint_id"`
+	Checkpoint   CheckpointResponse `json:"checkpoint"`
+	Position     uint               `json:"position"`
+}
+
+type OfferResponse struct {
+	Id        uuid.UUID  `json:"id"`
+	LimitDate time.Time  `json:"limit_date"`
+	CreatedAt time.Time  `json:"created_at"`
+	LotId     *uuid.UUID `json:"lot_id"`
+	TractorId *uuid.UUID `json:"tractor_id"`
+	Version   int64      `json:"version"`
+}
+
+type LotResponse struct {
+	Id                  uuid.UUID           `json:"id"`
+	ResourceType        ResourceType        `json:"resource_type"`
+	Volume              float64             `json:"volume"`
+	StartCheckpointId   *uuid.UUID          `json:"start_checkpoint_id"`
+	StartCheckpoint     *CheckpointResponse `json:"start_checkpoint,omitempty"`
+	EndCheckpointId     *uuid.UUID          `json:"end_checkpoint_id"`
+	EndCheckpoint       *CheckpointResponse `json:"end_checkpoint,omitempty"`
+	CurrentCheckpointId *uuid.UUID          `json:"current_checkpoint_id"`
+	CurrentCheckpoint   *CheckpointResponse `json:"current_checkpoint,omitempty"`
+	TractorId           *uuid.UUID          `json:"tractor_id"`
+	Tractor             *TractorResponse    `json:"tractor,omitempty"`
+	OwnerId             uuid.UUID           `json:"owner_id"`
+	Owner               *UserResponse       `json:"owner,omitempty"`
+	TrafficManagerId    *uuid.UUID          `json:"traffic_manager_id"`
+	TrafficManager      *UserResponse       `json:"traffic_manager,omitempty"`
+	TraderId            *uuid.UUID          `json:"trader_id"`
+	Trader              *UserResponse       `json:"trader,omitempty"`
+	State               State               `json:"state"`
+	MaxPriceByKm        float64             `json:"max_price_by_km"`
+	CurrentPrice        float64             `json:"current_price"`
+	InTractor           bool                `json:"in_tractor"`
+	LimitDate           time.Time           `json:"limit_date"`
+	CreatedAt           time.Time           `json:"created_at"`
+	OrganizationId      *uuid.UUID          `json:"organization_id"`
+	Version             int64               `json:"version"`
+}
+
+type TractorResponse struct {
+	Id                  uuid.UUID           `json:"id"`
+	Name                string              `json:"name"`
+	ResourceType        ResourceType        `json:"resource_type"`
+	MaxVolume           float64             `json:"max_units"`
+	CurrentVolume       float64             `json:"current_units"`
+	StartCheckpointId   *uuid.UUID          `json:"start_checkpoint_id"`
+	StartCheckpoint     *CheckpointResponse `json:"start_checkpoint,omitempty"`
+	EndCheckpointId     *uuid.UUID          `json:"end_checkpoint_id"`
+	EndCheckpoint       *CheckpointResponse `json:"end_checkpoint,omitempty"`
+	CurrentCheckpointId *uuid.UUID          `json:"current_checkpoint_id"`
+	CurrentCheckpoint   *CheckpointResponse `json:"current_checkpoint,omitempty"`
+	RouteId             *uuid.UUID          `json:"route_id"`
+	Route               *RouteResponse      `json:"route,omitempty"`
+	OwnerId             uuid.UUID           `json:"owner_id"`
+	Owner               *UserResponse       `json:"owner,omitempty"`
+	TrafficManagerId    *uuid.UUID          `json:"traffic_manager_id"`
+	TrafficManager      *UserResponse       `json:"traffic_manager,omitempty"`
+	TraderId            *uuid.UUID          `json:"trader_id"`
+	Trader              *UserResponse       `json:"trader,omitempty"`
+	State               State               `json:"state"`
+	MinPriceByKm        float64             `json:"min_price_by_km"`
+	CurrentPrice        float64             `json:"current_price"`
+	LimitDate           time.Time           `json:"limit_date"`
+	CreatedAt           time.Time           `json:"created_at"`
+	OrganizationId      *uuid.UUID          `json:"organization_id"`
+	Version             int64               `json:"version"`
+}
+
+// ToResponse : The user without its password hash and login counters
+func (user *User) ToResponse() UserResponse {
+	return UserResponse{
+		Id:             user.Id,
+		Username:       user.Username,
+		Role:           user.Role,
+		Email:          user.Email,
+		OrganizationId: user.OrganizationId,
+	}
+}
+
+func (checkpoint *Checkpoint) ToResponse() CheckpointResponse {
+	return CheckpointResponse{
+		Id:        checkpoint.Id,
+		Name:      checkpoint.Name,
+		Country:   checkpoint.Country,
+		Code:      checkpoint.Code,
+		Longitude: checkpoint.Longitude,
+		Latitude:  checkpoint.Latitude,
+		Metadata:  checkpoint.Metadata,
+	}
+}
+
+func (country *Country) ToResponse() CountryResponse {
+	return CountryResponse{
+		Id:       country.Id,
+		Code:     country.Code,
+		Name:     country.Name,
+		Metadata: country.Metadata,
+	}
+}
+
+func (organization *Organization) ToResponse() OrganizationResponse {
+	return OrganizationResponse{
+		Id:        organization.Id,
+		Name:      organization.Name,
+		CreatedAt: organization.CreatedAt,
+	}
+}
+
+func (offer *Offer) ToResponse() OfferResponse {
+	return OfferResponse{
+		Id:        offer.Id,
+		LimitDate: offer.LimitDate,
+		CreatedAt: offer.CreatedAt,
+		LotId:     offer.LotId,
+		TractorId: offer.TractorId,
+		Version:   offer.Version,
+	}
+}
+
+// ToResponse : The leg, with its checkpoints when expanded
+func (leg *Leg) ToResponse(expansion Expansion) LegResponse {
+	response := LegResponse{
+		Id:               leg.Id,
+		FromCheckpointId: leg.FromCheckpointId,
+		ToCheckpointId:   leg.ToCheckpointId,
+		DistanceKm:       leg.DistanceKm,
+		DurationMinutes:  leg.DurationMinutes,
+		TollCost:         leg.TollCost,
+		Closed:           leg.Closed,
+		UpdatedAt:        leg.UpdatedAt,
+	}
+	if expansion.Has("from_checkpoint") {
+		response.FromCheckpoint = checkpointResponse(&leg.FromCheckpoint)
+	}
+	if expansion.Has("to_checkpoint") {
+		response.ToCheckpoint = checkpointResponse(&leg.ToCheckpoint)
+	}
+	return response
+}
+
+// ToResponse : The route, with its traffic manager when expanded
+func (route *Route) ToResponse(expansion Expansion) RouteResponse {
+	response := RouteResponse{
+		Id:               route.Id,
+		Name:             route.Name,
+		TrafficManagerId: route.TrafficManagerId,
+		OrganizationId:   route.OrganizationId,
+	}
+	if expansion.Has("traffic_manager") {
+		response.TrafficManager = userResponse(&route.TrafficManager)
+	}
+	return response
+}
+
+func (routeCheckpoint *RouteCheckpoint) ToResponse() RouteCheckpointResponse {
+	return RouteCheckpointResponse{
+		Id:           routeCheckpoint.Id,
+		RouteId:      routeCheckpoint.RouteId,
+		CheckpointId: routeCheckpoint.CheckpointId,
+		Checkpoint:   routeCheckpoint.Checkpoint.ToResponse(),
+		Position:     routeCheckpoint.Position,
+	}
+}
+
+// ToResponse : The lot, with the associations the client expanded
+func (lot *Lot) ToResponse(expansion Expansion) LotResponse {
+	response := LotResponse{
+		Id:                  lot.Id,
+		ResourceType:        lot.ResourceType,
+		Volume:              lot.Volume,
+		StartCheckpointId:   lot.StartCheckpointId,
+		EndCheckpointId:     lot.EndCheckpointId,
+		CurrentCheckpointId: lot.CurrentCheckpointId,
+		TractorId:           lot.TractorId,
+		OwnerId:             lot.OwnerId,
+		TrafficManagerId:    lot.TrafficManagerId,
+		TraderId:            lot.TraderId,
+		State:               lot.State,
+		MaxPriceByKm:        lot.MaxPriceByKm,
+		CurrentPrice:        lot.CurrentPrice,
+		InTractor:           lot.InTractor,
+		LimitDate:           lot.LimitDate,
+		CreatedAt:           lot.CreatedAt,
+		OrganizationId:      lot.OrganizationId,
+		Version:             lot.Version,
+	}
+	if expansion.Has("start_checkpoint") {
+		response.StartCheckpoint = checkpointResponse(lot.StartCheckpoint)
+	}
+	if expansion.Has("end_checkpoint") {
+		response.EndCheckpoint = checkpointResponse(lot.EndCheckpoint)
+	}
+	if expansion.Has("current_checkpoint") {
+		response.CurrentCheckpoint = checkpointResponse(lot.CurrentCheckpoint)
+	}
+	if expansion.Has("tractor") && lot.Tractor != nil {
+		tractor := lot.Tractor.ToResponse(nil)
+		response.Tractor = &tractor
+	}
+	if expansion.Has("owner") {
+		response.Owner = userResponse(&lot.Owner)
+	}
+	if expansion.Has("traffic_manager") {
+		response.TrafficManager = userResponse(lot.TrafficManager)
+	}
+	if expansion.Has("trader") {
+		response.Trader = userResponse(lot.Trader)
+	}
+	return response
+}
+
+// ToResponse : The tractor, with the associations the client expanded
+func (tractor *Tractor) ToResponse(expansion Expansion) TractorResponse {
+	response := TractorResponse{
+		Id:                  tractor.Id,
+		Name:                tractor.Name,
+		ResourceType:        tractor.ResourceType,
+		MaxVolume:           tractor.MaxVolume,
+		CurrentVolume:       tractor.CurrentVolume,
+		StartCheckpointId:   tractor.StartCheckpointId,
+		EndCheckpointId:     tractor.EndCheckpointId,
+		CurrentCheckpointId: tractor.CurrentCheckpointId,
+		RouteId:             tractor.RouteId,
+		OwnerId:             tractor.OwnerId,
+		TrafficManagerId:    tractor.TrafficManagerId,
+		TraderId:            tractor.TraderId,
+		State:               tractor.State,
+		MinPriceByKm:        tractor.MinPriceByKm,
+		CurrentPrice:        tractor.CurrentPrice,
+		LimitDate:           tractor.LimitDate,
+		CreatedAt:           tractor.CreatedAt,
+		OrganizationId:      tractor.OrganizationId,
+		Version:             tractor.Version,
+	}
+	if expansion.Has("start_checkpoint") {
+		response.StartCheckpoint = checkpointResponse(tractor.StartCheckpoint)
+	}
+	if expansion.Has("end_checkpoint") {
+		response.EndCheckpoint = checkpointResponse(tractor.EndCheckpoint)
+	}
+	if expansion.Has("current_checkpoint") {
+		response.CurrentCheckpoint = checkpointResponse(tractor.CurrentCheckpoint)
+	}
+	if expansion.Has("route") && tractor.Route != nil {
+		route := tractor.Route.ToResponse(nil)
+		response.Route = &route
+	}
+	if expansion.Has("owner") {
+		response.Owner = userResponse(&tractor.Owner)
+	}
+	if expansion.Has("traffic_manager") {
+		response.TrafficManager = userResponse(tractor.TrafficManager)
+	}
+	if expansion.Has("trader") {
+		response.Trader = userResponse(tractor.Trader)
+	}
+	return response
+}
+
+// userResponse : nil when the association is empty, so it is left out of the response
+func userResponse(user *User) *UserResponse {
+	if user == nil || user.Id == uuid.Nil {
+		return nil
+	}
+	response := user.ToResponse()
+	return &response
+}
+
+func checkpointResponse(checkpoint *Checkpoint) *CheckpointResponse {
+	if checkpoint == nil || checkpoint.Id == uuid.Nil {
+		return nil
+	}
+	response := checkpoint.ToResponse()
+	return &response
+}
+
+// ToResponses : Representations of a list of models, like ToResponses(users, (*User).ToResponse)
+func ToResponses[M any, R any](items []M, toResponse func(*M) R) []R {
+	responses := make([]R, 0, len(items))
+	for i := range items {
+		responses = append(responses, toResponse(&items[i]))
+	}
+	return responses
+}
+
+func LotResponses(lots []Lot, expansion Expansion) []LotResponse {
+	return ToResponses(lots, func(lot *Lot) LotResponse { return lot.ToResponse(expansion) })
+}
+
+func TractorResponses(tractors []Tractor, expansion Expansion) []TractorResponse {
+	return ToResponses(tractors, func(tractor *Tractor) TractorResponse { return tractor.ToResponse(expansion) })
+}
+
+func RouteResponses(routes []Route, expansion Expansion) []RouteResponse {
+	return ToResponses(routes, func(route *Route) RouteResponse { return route.ToResponse(expansion) })
+}
+
+func LegResponses(legs []Leg, expansion Expansion) []LegResponse {
+	return ToResponses(legs, func(leg *Leg) LegResponse { return leg.ToResponse(expansion) })
+}
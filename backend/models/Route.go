@@ -6,10 +6,17 @@ import (
 )
 
 type Route struct {
-	Id               uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Name             string    `json:"name" gorm:"not null"`
-	TrafficManagerId uuid.UUID `json:"traffic_manager_id" gorm:"type:uuid;not null"` // Foreign key for Traffic Manager (User)
-	TrafficManager   User      `json:"traffic_manager" gorm:"foreignKey:TrafficManagerId"`
+	Id               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Name             string     `json:"name" gorm:"not null"`
+	TrafficManagerId uuid.UUID  `json:"traffic_manager_id" gorm:"type:uuid;not null"` // Foreign key for Traffic Manager (User)
+	TrafficManager   User       `json:"traffic_manager" gorm:"foreignKey:TrafficManagerId"`
+	OrganizationId   *uuid.UUID `json:"organization_id" gorm:"type:uuid;index"` // Tenant owning the route
+}
+
+// RouteUsage counts what depends on the stops of a route, they can not change while it is in use
+type RouteUsage struct {
+	Tractors     int64 `json:"tractors"`
+	Transactions int64 `json:"transactions"`
 }
 
 func (route *Route) BeforeCreate(tx *gorm.DB) (err error) {
@@ -36,6 +43,21 @@ func (route *Route) GetRoutesByTrafficManagerId(db *gorm.DB, trafficManagerId uu
 	return routes, nil
 }
 
+// Usage : Count the tractors bound to the route and the transactions planned along it
+func (route *Route) Usage(db *gorm.DB) (RouteUsage, error) {
+	var usage RouteUsage
+	err := db.Raw(`SELECT
+		(SELECT count(*) FROM tractors WHERE route_id = @id) AS tractors,
+		(SELECT count(*) FROM transactions WHERE route_id = @id) AS transactions`,
+		map[string]interface{}{"id": route.Id}).Scan(&usage).Error
+	return usage, err
+}
+
+// InUse : Whether a tractor follows the route or a transaction refers to its stops
+func (usage RouteUsage) InUse() bool {
+	return usage.Tractors+usage.Transactions > 0
+}
+
 func (route *Route) GetRouteString(db *gorm.DB) string {
 	var routeName string
 	db.Raw("select STRING_AGG(c.name, ' - ' order by rc.position) from route_checkpoints rc join checkpoints c on c.id = rc.checkpoint_id where rc.route_id = ?", route.Id).Scan(&routeName)
@@ -50,14 +72,6 @@ func (routeCheckpoint *RouteCheckpoint) GetRouteCheckpointsByRouteId(db *gorm.DB
 	return routeCheckpoints, nil
 }
 
-func (route *Route) GetAllRoutes(db *gorm.DB) ([]Route, error) {
-	var routes []Route
-	if err := db.Find(&routes).Error; err != nil {
-		return nil, err
-	}
-	return routes, nil
-}
-
 func (routeCheckpoint *RouteCheckpoint) SaveRouteCheckpoint(db *gorm.DB) error {
 	return db.Save(routeCheckpoint).Error
 }
@@ -91,7 +105,6 @@ func (routeCheckpoint *RouteCheckpoint) GetRouteCheckpoint(db *gorm.DB, routeId
 	return db.First(routeCheckpoint, "route_id = ? AND checkpoint_id = ?", routeId, checkpointId).Error
 }
 
-
 func (routeCheckpoint *RouteCheckpoint) IsNextCheckpoint(db *gorm.DB, route Route) bool {
 	var nextCheckpoint RouteCheckpoint
 	if err := db.First(&nextCheckpoint, "route_id = ? AND position = ?", route.Id, routeCheckpoint.Position).Error; err != nil {
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Session struct {
+	Id                       uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserId                   uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User                     User       `json:"-" gorm:"foreignKey:UserId"`
+	RefreshTokenHash         string     `json:"-" gorm:"not null;uniqueIndex"`
+	PreviousRefreshTokenHash string     `json:"-" gorm:"index"`
+	UserAgent                string     `json:"user_agent" gorm:""`
+	IpAddress                string     `json:"ip_address" gorm:""`
+	CreatedAt                time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	LastUsedAt               time.Time  `json:"last_used_at" gorm:""`
+	ExpiresAt                time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt                *time.Time `json:"revoked_at" gorm:""`
+}
+
+func (session *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if session.Id == uuid.Nil {
+		session.Id = uuid.New()
+	}
+	return
+}
+
+// IsActive : A session is active until it expires or gets revoked
+func (session *Session) IsActive() bool {
+	return session.RevokedAt == nil && time.Now().Before(session.ExpiresAt)
+}
+
+func (session *Session) Create(db *gorm.DB) error {
+	return db.Create(session).Error
+}
+
+func (session *Session) FindById(db *gorm.DB, sessionId uuid.UUID) error {
+	return db.First(session, "id = ?", sessionId).Error
+}
+
+func (session *Session) FindByRefreshTokenHash(db *gorm.DB, hash string) error {
+	return db.First(session, "refresh_token_hash = ?", hash).Error
+}
+
+func (session *Session) FindByPreviousRefreshTokenHash(db *gorm.DB, hash string) error {
+	return db.First(session, "previous_refresh_token_hash = ?", hash).Error
+}
+
+// Rotate : Replace the refresh token of the session, the old one is kept to detect reuse. False when the token was
+// rotated meanwhile, by a concurrent refresh with the same token
+func (session *Session) Rotate(db *gorm.DB, newHash string, expiresAt time.Time) (bool, error) {
+	oldHash := session.RefreshTokenHash
+	rotated := *session
+	rotated.PreviousRefreshTokenHash = oldHash
+	rotated.RefreshTokenHash = newHash
+	rotated.ExpiresAt = expiresAt
+	rotated.LastUsedAt = time.Now()
+	result := db.Model(&rotated).Where("refresh_token_hash = ?", oldHash).
+		Select("previous_refresh_token_hash", "refresh_token_hash", "expires_at", "last_used_at").Updates(&rotated)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	*session = rotated
+	return true, nil
+}
+
+func (session *Session) Revoke(db *gorm.DB) error {
+	now := time.Now()
+	session.RevokedAt = &now
+	return db.Model(session).Update("revoked_at", now).Error
+}
+
+// RevokeOthersByUserId : Revoke every session of the user except the given one
+func (session *Session) RevokeOthersByUserId(db *gorm.DB, userId uuid.UUID, keptSessionId uuid.UUID) error {
+	return db.Model(&Session{}).Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, keptSessionId).Update("revoked_at", time.Now()).Error
+}
+
+func (session *Session) RevokeAllByUserId(db *gorm.DB, userId uuid.UUID) (int64, error) {
+	result := db.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userId).Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rows created before organizations existed are moved to this organization at startup
+const DefaultOrganizationName = "Ligne8"
+
+type tenantContextKey struct{}
+
+// ForOrganization : Scope every query made through the returned db to the organization.
+// Models with an OrganizationId are filtered on it, and get it set when created.
+func ForOrganization(db *gorm.DB, organizationId uuid.UUID) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, tenantContextKey{}, organizationId))
+}
+
+// OrganizationOf : Get the organization the db is scoped to, if any
+func OrganizationOf(db *gorm.DB) (uuid.UUID, bool) {
+	if db.Statement.Context == nil {
+		return uuid.Nil, false
+	}
+	organizationId, ok := db.Statement.Context.Value(tenantContextKey{}).(uuid.UUID)
+	return organizationId, ok
+}
+
+// RegisterTenantScope : Install the callbacks enforcing the scope set by ForOrganization
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", filterByTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", filterByTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", filterByTenant); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenant:create", assignTenant)
+}
+
+func filterByTenant(db *gorm.DB) {
+	organizationId, ok := OrganizationOf(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("OrganizationId")
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: organizationId},
+	}})
+}
+
+// The organization always comes from the scope, never from the request payload
+func assignTenant(db *gorm.DB) {
+	organizationId, ok := OrganizationOf(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField("OrganizationId"); field != nil {
+		db.Statement.SetColumn(field.Name, &organizationId, true)
+	}
+}
+
+// AssignDefaultOrganization : Attach the rows without organization to the default one, admins stay global
+func AssignDefaultOrganization(db *gorm.DB) error {
+	var organization Organization
+	if err := db.Where(Organization{Name: DefaultOrganizationName}).FirstOrCreate(&organization).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&User{}).Where("organization_id IS NULL AND role <> ?", RoleAdmin).Update("organization_id", organization.Id).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{&Lot{}, &Tractor{}, &Route{}} {
+		if err := db.Model(model).Where("organization_id IS NULL").Update("organization_id", organization.Id).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,6 +16,15 @@ const (
 	ResourceTypeLiquid ResourceType = "liquid"
 )
 
+// Valid : Check the resource type is one lots and tractors carry
+func (resourceType ResourceType) Valid() bool {
+	switch resourceType {
+	case ResourceTypeBulk, ResourceTypeSolid, ResourceTypeLiquid:
+		return true
+	}
+	return false
+}
+
 type Tractor struct {
 	Id                  uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
 	Name                string       `json:"name" gorm:"not null"`
@@ -41,28 +50,15 @@ type Tractor struct {
 	Route               *Route       `json:"route" gorm:"foreignKey:RouteId"`
 	CurrentPrice        float64      `json:"current_price" gorm:"-"`
 	LimitDate           time.Time    `json:"limit_date" gorm:""`
+	OrganizationId      *uuid.UUID   `json:"organization_id" gorm:"type:uuid;index"` // Tenant owning the tractor
+	Version             int64        `json:"version" gorm:"not null;default:1"`      // Bumped on every update, sent as the ETag
 }
 
 func (tractor *Tractor) BeforeCreate(tx *gorm.DB) (err error) {
-	validTypes := map[ResourceType]bool{
-		ResourceTypeBulk:   true,
-		ResourceTypeSolid:  true,
-		ResourceTypeLiquid: true,
-	}
-	if !validTypes[tractor.ResourceType] {
+	if !tractor.ResourceType.Valid() {
 		return errors.New("invalid resource type")
 	}
-
-	validState := map[State]bool{
-		StateAvailable: true,
-		StateArchive:   true,
-		StateAtTrader:  true,
-		StateInTransit: true,
-		StateOnMarket:  true,
-		StatePending:   true,
-	}
-
-	if !validState[tractor.State] {
+	if !tractor.State.Creatable() {
 		return errors.New("invalid valid state")
 	}
 
@@ -96,14 +92,6 @@ func (tractor *Tractor) FindById(db *gorm.DB, tractorId uuid.UUID) (Tractor, err
 	return foundTractor, nil
 }
 
-func (tractor *Tractor) GetByOwnerId(db *gorm.DB, ownerId uuid.UUID) ([]Tractor, error) {
-	var tractors []Tractor
-	if err := db.Preload("EndCheckpoint").Preload("StartCheckpoint").Preload("CurrentCheckpoint").Preload("TrafficManager").Where("owner_id = ?", ownerId).Find(&tractors).Error; err != nil {
-		return nil, err
-	}
-	return tractors, nil
-}
-
 func (tractor *Tractor) GetTractorsByTrader(db *gorm.DB, traderId uuid.UUID) ([]Tractor, error) {
 	var tractors []Tractor
 	if err := db.Preload("EndCheckpoint").Preload("StartCheckpoint").Where("trader_id = ?", traderId).Find(&tractors).Error; err != nil {
@@ -112,29 +100,6 @@ func (tractor *Tractor) GetTractorsByTrader(db *gorm.DB, traderId uuid.UUID) ([]
 	return tractors, nil
 }
 
-func (tractor *Tractor) GetByTrafficManagerId(db *gorm.DB, trafficManagerId uuid.UUID) ([]Tractor, error) {
-	var tractors []Tractor
-	if err := db.Preload("Route").Preload("StartCheckpoint").Preload("EndCheckpoint").Preload("CurrentCheckpoint").Where("traffic_manager_id = ?", trafficManagerId).Find(&tractors).Error; err != nil {
-		return nil, err
-	}
-	return tractors, nil
-}
-
-func (tractor *Tractor) GetByState(db *gorm.DB, state string) ([]Tractor, error) {
-	var tractors []Tractor
-	if err := db.Where("state = ?", state).Find(&tractors).Error; err != nil {
-		return nil, err
-	}
-	return tractors, nil
-}
-
-func (tractor *Tractor) GetByRouteId(db *gorm.DB, routeId uuid.UUID) ([]Tractor, error) {
-	var tractors []Tractor
-	if err := db.Where("route_id = ?", routeId).Find(&tractors).Error; err != nil {
-		return nil, err
-	}
-	return tractors, nil
-}
 func (tractor *Tractor) AssociateTraficManager(db *gorm.DB, trafficManagerId uuid.UUID) error {
 	return db.Model(&tractor).Update("traffic_manager_id", trafficManagerId).Error
 }
@@ -2,9 +2,11 @@ package models
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Role string
@@ -17,10 +19,14 @@ const (
 )
 
 type User struct {
-	Id       uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Username string    `json:"username" gorm:"not null" binding:"required"`
-	Password string    `json:"password" gorm:"not null"`
-	Role     Role      `json:"role" gorm:"not null"`
+	Id                  uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Username            string     `json:"username" gorm:"not null" binding:"required"`
+	Password            string     `json:"password" gorm:"not null"`
+	Role                Role       `json:"role" gorm:"not null"`
+	Email               string     `json:"email" gorm:""`
+	FailedLoginAttempts int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil         *time.Time `json:"-" gorm:""`
+	OrganizationId      *uuid.UUID `json:"organization_id" gorm:"type:uuid;index"` // Nil for admins, they are not bound to a tenant
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -38,14 +44,6 @@ func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
-func (user *User) GetAllUsers(db *gorm.DB) ([]User, error) {
-	var users []User
-	if err := db.Find(&users).Error; err != nil {
-		return nil, err
-	}
-	return users, nil
-}
-
 func (user *User) FindById(db *gorm.DB, userId uuid.UUID) (User, error) {
 	var foundUser User
 	if err := db.First(&foundUser, "id = ?", userId).Error; err != nil {
@@ -65,4 +63,45 @@ func (user *User) FindByRole(db *gorm.DB, role Role) ([]User, error) {
 		return nil, err
 	}
 	return users, nil
-}
\ No newline at end of file
+}
+
+// IsLocked : Check if the account is locked after too many failed logins
+func (user *User) IsLocked() bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// RegisterFailedLogin : Count a failed login and lock the account for as long as lockDuration tells for the new count.
+// The counter is incremented by the database, so parallel failures are all counted
+func (user *User) RegisterFailedLogin(db *gorm.DB, lockDuration func(failedAttempts int) time.Duration) error {
+	err := db.Model(user).Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error
+	if err != nil {
+		return err
+	}
+	lockFor := lockDuration(user.FailedLoginAttempts)
+	if lockFor <= 0 {
+		return nil
+	}
+	lockedUntil := time.Now().Add(lockFor)
+	user.LockedUntil = &lockedUntil
+	return db.Model(user).Update("locked_until", lockedUntil).Error
+}
+
+// ResetFailedLogins : Clear the failed login counter and the lock of the account
+func (user *User) ResetFailedLogins(db *gorm.DB) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	return db.Model(user).Updates(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil}).Error
+}
+
+func (user *User) UpdatePassword(db *gorm.DB, hashedPassword string) error {
+	user.Password = hashedPassword
+	return db.Model(user).Update("password", hashedPassword).Error
+}
+
+func (user *User) FindByUsername(db *gorm.DB, username string) error {
+	return db.First(user, "username = ?", username).Error
+}
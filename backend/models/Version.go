@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Models with a Version field are optimistically locked, versions start at 1
+const versionField = "Version"
+
+const versionExpectedKey = "version:expected"
+
+// StaleVersionError is returned when a write is based on another version than the one stored
+type StaleVersionError struct {
+	EntityType string
+	EntityId   string
+	Version    int64
+}
+
+func (err *StaleVersionError) Error() string {
+	return fmt.Sprintf("%s %s was modified since version %d", err.EntityType, err.EntityId, err.Version)
+}
+
+// ExpectVersion : StaleVersionError when the client read another version than the current one, expected 0 skips the check
+func ExpectVersion(entityType string, entityId uuid.UUID, current int64, expected int64) error {
+	if expected == 0 || expected == current {
+		return nil
+	}
+	return &StaleVersionError{EntityType: entityType, EntityId: entityId.String(), Version: expected}
+}
+
+// RegisterOptimisticLocking : Install the callbacks bumping the version of versioned models on every update.
+// Updates of a loaded model only apply to the version it was loaded at, and fail with StaleVersionError otherwise.
+func RegisterOptimisticLocking(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Update().Before("gorm:update").Register("version:before_update", bumpVersion); err != nil {
+		return err
+	}
+	return callbacks.Update().After("gorm:update").Register("version:update", checkVersion)
+}
+
+func versionFieldOf(db *gorm.DB) *schema.Field {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(versionField)
+}
+
+// loadedVersion : Version of the model the statement works on, 0 for bulk updates
+func loadedVersion(db *gorm.DB, field *schema.Field) int64 {
+	value := reflect.Indirect(db.Statement.ReflectValue)
+	if value.Kind() != reflect.Struct {
+		return 0
+	}
+	version, _ := field.ValueOf(db.Statement.Context, value)
+	current, _ := version.(int64)
+	return current
+}
+
+func bumpVersion(db *gorm.DB) {
+	field := versionFieldOf(db)
+	if field == nil {
+		return
+	}
+	current := loadedVersion(db, field)
+	if current == 0 {
+		// Bulk updates do not know the versions of the rows, they only bump them
+		if dest, ok := db.Statement.Dest.(map[string]interface{}); ok {
+			dest[field.DBName] = gorm.Expr(field.DBName + " + 1")
+		}
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: current},
+	}})
+	db.Statement.SetColumn(field.DBName, current+1, true)
+	db.Statement.Settings.Store(versionExpectedKey, current)
+}
+
+func checkVersion(db *gorm.DB) {
+	value, ok := db.Statement.Settings.LoadAndDelete(versionExpectedKey)
+	if !ok {
+		return
+	}
+	expected := value.(int64)
+	if db.Error == nil && db.Statement.RowsAffected > 0 {
+		return
+	}
+
+	// Nothing was written, the model keeps the version it was loaded at
+	field := db.Statement.Schema.LookUpField(versionField)
+	if model := reflect.Indirect(db.Statement.ReflectValue); model.Kind() == reflect.Struct && model.CanAddr() {
+		_ = field.Set(db.Statement.Context, model, expected)
+	}
+	if db.Error != nil {
+		return
+	}
+	var entityId string
+	if ids := primaryKeysOf(db); len(ids) > 0 {
+		entityId = fmt.Sprint(ids[0])
+	}
+	db.AddError(&StaleVersionError{EntityType: db.Statement.Table, EntityId: entityId, Version: expected})
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ApiKeyRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
+	ApiKeyController := controllers.ApiKeyController{
+		Db: db,
+	}
+
+	// Keys are managed from an interactive session only, a key can not mint other keys
+	v1 := r.Group("/api/v1/api_keys", middlewares.Authenticate(db), middlewares.Interactive(), middlewares.Authorize(models.RoleClient))
+	{
+		v1.POST("", ApiKeyController.CreateApiKey)
+		v1.GET("", ApiKeyController.ListApiKeys)
+		v1.GET("/scopes", ApiKeyController.ListScopes)
+		v1.DELETE("/:id", ApiKeyController.RevokeApiKey)
+	}
+	return r
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func AuditLogRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
+	AuditLogController := controllers.AuditLogController{
+		Db: db,
+	}
+
+	v1 := r.Group("/api/v1/audit_logs", middlewares.Authenticate(db), middlewares.Interactive(), middlewares.Authorize(models.RoleAdmin))
+	{
+		v1.GET("", AuditLogController.GetAuditLogs)
+	}
+	return r
+}
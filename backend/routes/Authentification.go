@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"tms-backend/controllers/Authentification"
+	"tms-backend/middlewares"
 )
 
 func AuthRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
@@ -14,6 +15,15 @@ func AuthRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
 	{
 		v1.POST("/register", AuthController.CreateUser)
 		v1.POST("/login", AuthController.LoginUser)
+		v1.POST("/refresh", AuthController.RefreshToken)
+		v1.POST("/password/forgot", AuthController.ForgotPassword)
+		v1.POST("/password/reset", AuthController.ResetPassword)
+
+		authenticated := v1.Group("", middlewares.Authenticate(db), middlewares.Interactive())
+		authenticated.POST("/logout", AuthController.Logout)
+		authenticated.POST("/logout/all", AuthController.LogoutAll)
+		authenticated.GET("/sessions", AuthController.ListSessions)
+		authenticated.POST("/password", AuthController.ChangePassword)
 	}
 	return r
 }
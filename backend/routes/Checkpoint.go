@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -16,6 +18,22 @@ func CheckpointsRoute(r *gin.Engine, db *gorm.DB) *gin.Engine {
 		v1.GET("", CheckpointController.GetAllCheckpoints)
 		v1.GET("/countries/:country/cities", CheckpointController.GetCitiesByCountry)
 		v1.GET("/cities/:city/country", CheckpointController.GetCountryByCity)
+		v1.GET("/distances", CheckpointController.GetDistances)
+		v1.GET("/distances/matrix", CheckpointController.GetDistanceMatrix)
+		v1.GET("/nearest", CheckpointController.GetNearestCheckpoints)
+		v1.GET("/within", CheckpointController.GetCheckpointsWithin)
+		v1.GET("/:checkpoint_id", CheckpointController.GetCheckpoint)
+	}
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+
+	manage := r.Group("/api/v1/checkpoints", middlewares.Authenticate(db), middlewares.RequireScope("routes"), admin)
+	{
+		manage.POST("", CheckpointController.CreateCheckpoint)
+		manage.POST("/import", CheckpointController.ImportCheckpoints)
+		manage.GET("/:checkpoint_id/usage", CheckpointController.GetCheckpointUsage)
+		manage.PATCH("/:checkpoint_id", CheckpointController.UpdateCheckpoint)
+		manage.DELETE("/:checkpoint_id", CheckpointController.DeleteCheckpoint)
 	}
 	return r
 }
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CountryRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
+	CountryController := controllers.CountryController{
+		Db: db,
+	}
+	v1 := r.Group("/api/v1/countries")
+	{
+		v1.GET("", CountryController.GetCountries)
+		v1.GET("/:id", CountryController.GetCountry)
+	}
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+
+	manage := r.Group("/api/v1/countries", middlewares.Authenticate(db), middlewares.RequireScope("routes"), admin)
+	{
+		manage.POST("", CountryController.CreateCountry)
+		manage.PATCH("/:id", CountryController.UpdateCountry)
+		manage.DELETE("/:id", CountryController.DeleteCountry)
+	}
+	return r
+}
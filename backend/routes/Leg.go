@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func LegRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
+	LegController := controllers.LegController{
+		Db: db,
+	}
+	v1 := r.Group("/api/v1/legs")
+	{
+		v1.GET("", LegController.GetLegs)
+		v1.GET("/:leg_id", LegController.GetLeg)
+	}
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+
+	manage := r.Group("/api/v1/legs", middlewares.Authenticate(db), middlewares.RequireScope("routes"), admin)
+	{
+		manage.POST("", LegController.CreateLeg)
+		manage.PATCH("/:leg_id", LegController.UpdateLeg)
+		manage.DELETE("/:leg_id", LegController.DeleteLeg)
+	}
+	return r
+}
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,21 +15,28 @@ func LotRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
 		Db: db,
 	}
 
-	v1 := r.Group("/api/v1/lots")
+	client := middlewares.Authorize(models.RoleClient)
+	trafficManager := middlewares.Authorize(models.RoleTrafficManager)
+	trader := middlewares.Authorize(models.RoleTrader)
+
+	v1 := r.Group("/api/v1/lots", middlewares.Authenticate(db), middlewares.RequireScope("lots"))
 	{
-		v1.POST("", LotController.CreateLot)
-		v1.POST("traffic_manager", LotController.AssociateToTrafficManager)
-		v1.PATCH("/state", LotController.UpdateLotState)
+		v1.POST("", client, LotController.CreateLot)
+		v1.POST("/import", client, LotController.ImportLots)
+		v1.POST("traffic_manager", client, LotController.AssociateToTrafficManager)
+		v1.PATCH("/state", middlewares.Authorize(models.RoleClient, models.RoleTrafficManager, models.RoleTrader), LotController.UpdateLotState)
 		//v1.PATCH(":id", LotController.PatchLot)
-		v1.GET("owner/:owner_id", LotController.ListLotsByOwner)
-		v1.DELETE("/:lot_id", LotController.DeleteLot)
+		v1.GET("owner/:owner_id", client, LotController.ListLotsByOwner)
+		v1.DELETE("/:lot_id", client, LotController.DeleteLot)
 
-		v1.GET("traffic_manager/:traffic_manager_id", LotController.ListLotsByTrafficManager)
-		v1.GET("/tractors/compatible/:traffic_manager_id/:lot_id", LotController.ListCompatibleTractorsForLot)
-		v1.POST("/tractors/assign", LotController.AssignTractorToLot)
-		v1.POST("/assign/:lot_id/trader", LotController.AssignTraderToLot)
-		v1.GET("/trader/:trader_id", LotController.GetAllLotTraderId)
-		v1.GET("/bids/:owner_id", LotController.GetLotBidByOwnerId)
+		v1.GET("traffic_manager/:traffic_manager_id", trafficManager, LotController.ListLotsByTrafficManager)
+		v1.GET("/within", middlewares.Authorize(models.RoleClient, models.RoleTrafficManager, models.RoleTrader), LotController.ListLotsWithin)
+		v1.GET("/geojson", middlewares.Authorize(models.RoleClient, models.RoleTrafficManager, models.RoleTrader), LotController.GetLotFlowsGeoJSON)
+		v1.GET("/tractors/compatible/:traffic_manager_id/:lot_id", trafficManager, LotController.ListCompatibleTractorsForLot)
+		v1.POST("/tractors/assign", trafficManager, LotController.AssignTractorToLot)
+		v1.POST("/assign/:lot_id/trader", trafficManager, LotController.AssignTraderToLot)
+		v1.GET("/trader/:trader_id", trader, LotController.GetAllLotTraderId)
+		v1.GET("/bids/:owner_id", client, LotController.GetLotBidByOwnerId)
 	}
 	return r
 }
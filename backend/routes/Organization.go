@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func OrganizationRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
+	OrganizationController := controllers.OrganizationController{
+		Db: db,
+	}
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+
+	v1 := r.Group("/api/v1/organizations", middlewares.Authenticate(db), middlewares.RequireScope("users"))
+	{
+		v1.GET("", admin, OrganizationController.GetOrganizations)
+		v1.POST("", admin, OrganizationController.CreateOrganization)
+		v1.GET("/:id", OrganizationController.GetOrganization)
+		v1.GET("/:id/members", OrganizationController.GetOrganizationMembers)
+	}
+	return r
+}
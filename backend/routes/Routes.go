@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,12 +15,21 @@ func RoutesRoute(r *gin.Engine, db *gorm.DB) *gin.Engine {
 		Db: db,
 	}
 
-	v1 := r.Group("/api/v1/routes")
+	trafficManager := middlewares.Authorize(models.RoleTrafficManager)
+
+	v1 := r.Group("/api/v1/routes", middlewares.Authenticate(db), middlewares.RequireScope("routes"))
 	{
-		v1.POST("", RouteController.CreateRoute)
-		v1.GET("", RouteController.GetAllRoutes)
-		v1.GET("/traffic_manager/parsed/:traffic_manager_id", RouteController.GetRouteStringByTrafficManagerId)
-		v1.GET("/:route_id/checkpoints", RouteController.GetCheckpointsByRouteId)
+		v1.POST("", trafficManager, RouteController.CreateRoute)
+		v1.POST("/suggestions", trafficManager, RouteController.SuggestRoute)
+		v1.POST("/plans", trafficManager, RouteController.PlanRoutes)
+		v1.POST("/plans/accept", trafficManager, RouteController.AcceptPlan)
+		v1.GET("", trafficManager, RouteController.GetAllRoutes)
+		v1.GET("/traffic_manager/parsed/:traffic_manager_id", trafficManager, RouteController.GetRouteStringByTrafficManagerId)
+		v1.GET("/geojson", middlewares.Authorize(models.RoleTrafficManager, models.RoleClient), RouteController.GetRoutesGeoJSON)
+		v1.GET("/:route_id/checkpoints", middlewares.Authorize(models.RoleTrafficManager, models.RoleClient), RouteController.GetCheckpointsByRouteId)
+		v1.GET("/:route_id/geojson", middlewares.Authorize(models.RoleTrafficManager, models.RoleClient), RouteController.GetRouteGeoJSON)
+		v1.PATCH("/:route_id", trafficManager, RouteController.UpdateRoute)
+		v1.DELETE("/:route_id", trafficManager, RouteController.DeleteRoute)
 	}
 	return r
 }
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,14 +15,16 @@ func SimulationRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
 		Db: db,
 	}
 
+	admin := middlewares.Authorize(models.RoleAdmin)
+
 	// Grouper les routes sous /api/v1/simulations
-	v1 := r.Group("/api/v1/simulations")
+	v1 := r.Group("/api/v1/simulations", middlewares.Authenticate(db), middlewares.RequireScope("simulations"))
 	{
 		v1.GET("/date", SimulationController.GetSimulationDate)
 
-		v1.PATCH("/date", SimulationController.UpdateSimulationDate)
-		v1.GET("/move_tractors", SimulationController.MoveTractorForward)
-		v1.GET("", SimulationController.MoveTractorForward)
+		v1.PATCH("/date", admin, SimulationController.UpdateSimulationDate)
+		v1.GET("/move_tractors", admin, SimulationController.MoveTractorForward)
+		v1.GET("", admin, SimulationController.MoveTractorForward)
 
 	}
 
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,17 +14,21 @@ func StockExchangeRoute(r *gin.Engine, db *gorm.DB) *gin.Engine {
 		Db: db,
 	}
 
-	v1 := r.Group("/api/v1/stock_exchange")
+	admin := middlewares.Authorize(models.RoleAdmin)
+	client := middlewares.Authorize(models.RoleClient)
+	market := middlewares.Authorize(models.RoleClient, models.RoleTrader)
+
+	v1 := r.Group("/api/v1/stock_exchange", middlewares.Authenticate(db), middlewares.RequireScope("market"))
 	{
-		v1.POST("/lot_offers", StockExchangeController.CreateLotOffer)
-		v1.GET("/lot_offers", StockExchangeController.GetAllLotsOnMarket)
+		v1.POST("/lot_offers", client, StockExchangeController.CreateLotOffer)
+		v1.GET("/lot_offers", market, StockExchangeController.GetAllLotsOnMarket)
 
-		v1.POST("/lot/bid", StockExchangeController.CreateBidLot)
-		v1.POST("/tractor/bid", StockExchangeController.CreateBidTractor)
+		v1.POST("/lot/bid", client, StockExchangeController.CreateBidLot)
+		v1.POST("/tractor/bid", client, StockExchangeController.CreateBidTractor)
 
-		v1.POST("/tractor_offers", StockExchangeController.CreateTractorOffer)
-		v1.GET("/tractor_offers", StockExchangeController.GetAllTractorOnMarket)
-		v1.PUT("/return_from_market", StockExchangeController.ChangeStateToReturnFromMarket2)
+		v1.POST("/tractor_offers", client, StockExchangeController.CreateTractorOffer)
+		v1.GET("/tractor_offers", market, StockExchangeController.GetAllTractorOnMarket)
+		v1.PUT("/return_from_market", admin, StockExchangeController.ChangeStateToReturnFromMarket)
 	}
 	return r
 }
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -11,28 +13,37 @@ func TractorRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
 	TractorController := controllers.TractorController{
 		Db: db,
 	}
-	v1 := r.Group("/api/v1/tractors")
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+	client := middlewares.Authorize(models.RoleClient)
+	trafficManager := middlewares.Authorize(models.RoleTrafficManager)
+	trader := middlewares.Authorize(models.RoleTrader)
+
+	v1 := r.Group("/api/v1/tractors", middlewares.Authenticate(db), middlewares.RequireScope("tractors"))
 	{
-		v1.POST("traffic_manager", TractorController.AssociateToTrafficManager)
-		v1.POST("", TractorController.CreateTractor)
+		v1.POST("traffic_manager", client, TractorController.AssociateToTrafficManager)
+		v1.POST("", client, TractorController.CreateTractor)
+		v1.POST("/import", client, TractorController.ImportTractors)
 		// Get tractors by OwnerID
-		v1.GET("owner/:ownerId", TractorController.ListTractorsByOwner)
+		v1.GET("owner/:ownerId", client, TractorController.ListTractorsByOwner)
 		// Get tractors by TrafficManagerId
-		v1.GET("trafficManager/:trafficManagerId", TractorController.ListTractorsByTrafficManagerId)
+		v1.GET("trafficManager/:trafficManagerId", trafficManager, TractorController.ListTractorsByTrafficManagerId)
 		// Get tractors by State
-		v1.GET("state/:state", TractorController.ListTractorsByState)
+		v1.GET("state/:state", trafficManager, TractorController.ListTractorsByState)
 		// Get tractors by RouteId
-		v1.GET("/route/:routeId", TractorController.ListTractorsByRouteId)
-		v1.GET("/next-route", TractorController.GoToNextCheckpoint)
-		v1.PATCH("/updateState", TractorController.UpdateTractorState)
-		v1.POST("/route", TractorController.BindRoute)
-		v1.DELETE("/route", TractorController.UnbindRoute)
-		v1.DELETE("/:tractor_id", TractorController.DeleteTractor)
+		v1.GET("/route/:routeId", trafficManager, TractorController.ListTractorsByRouteId)
+		v1.GET("/within", middlewares.Authorize(models.RoleClient, models.RoleTrafficManager, models.RoleTrader), TractorController.ListTractorsWithin)
+		v1.GET("/geojson", middlewares.Authorize(models.RoleClient, models.RoleTrafficManager, models.RoleTrader), TractorController.GetFleetGeoJSON)
+		v1.GET("/next-route", admin, TractorController.GoToNextCheckpoint)
+		v1.PATCH("/updateState", middlewares.Authorize(models.RoleTrafficManager, models.RoleTrader), TractorController.UpdateTractorState)
+		v1.POST("/route", trafficManager, TractorController.BindRoute)
+		v1.DELETE("/route", trafficManager, TractorController.UnbindRoute)
+		v1.DELETE("/:tractor_id", client, TractorController.DeleteTractor)
 		//v1.PATCH(":id", LotController.PatchLot)
 		//v1.GET("", LotController.ListLots)
-		v1.POST("/assign/:tractor_id/trader", TractorController.AssignTraderToTractor)
-		v1.GET("/trader/:trader_id", TractorController.GetAllTractorTraderId)
-		v1.GET("/bids/:owner_id", TractorController.GetTractorBidByOwnerId)
+		v1.POST("/assign/:tractor_id/trader", trafficManager, TractorController.AssignTraderToTractor)
+		v1.GET("/trader/:trader_id", trader, TractorController.GetAllTractorTraderId)
+		v1.GET("/bids/:owner_id", client, TractorController.GetTractorBidByOwnerId)
 	}
 	return r
 }
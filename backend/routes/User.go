@@ -2,6 +2,8 @@ package routes
 
 import (
 	"tms-backend/controllers"
+	"tms-backend/middlewares"
+	"tms-backend/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -11,14 +13,18 @@ func UserRoutes(r *gin.Engine, db *gorm.DB) *gin.Engine {
 	UserController := controllers.UserController{
 		Db: db,
 	}
-	v1 := r.Group("/api/v1/users")
+
+	admin := middlewares.Authorize(models.RoleAdmin)
+
+	v1 := r.Group("/api/v1/users", middlewares.Authenticate(db), middlewares.RequireScope("users"))
 	{
-		v1.GET("/", UserController.GetUsers)
+		v1.GET("/", admin, UserController.GetUsers)
 		v1.GET("/:id", UserController.GetUser)
-		v1.POST("/", UserController.CreateUser)
+		v1.POST("/", admin, UserController.CreateUser)
 		v1.PATCH("/:id", UserController.UpdateUser)
-		v1.DELETE("/:id", UserController.DeleteUser)
-		v1.GET("/traffic_managers", UserController.GetTrafficManagers)
+		v1.DELETE("/:id", admin, UserController.DeleteUser)
+		v1.DELETE("/:id/sessions", admin, UserController.RevokeUserSessions)
+		v1.GET("/traffic_managers", middlewares.Authorize(models.RoleClient), UserController.GetTrafficManagers)
 	}
 	return r
 }
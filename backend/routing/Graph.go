@@ -0,0 +1,241 @@
+package routing
+
+import (
+	"container/heap"
+	"math"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Objective is what a path minimises
+type Objective string
+
+const (
+	ObjectiveDistance Objective = "distance"
+	ObjectiveDuration Objective = "duration"
+	ObjectiveCost     Objective = "cost"
+)
+
+// Objectives lists the valid objectives
+var Objectives = []Objective{ObjectiveDistance, ObjectiveDuration, ObjectiveCost}
+
+// weight orders paths on the objective first, then on the distance so free legs do not lead to detours
+type weight struct {
+	primary   float64
+	secondary float64
+}
+
+func (w weight) add(other weight) weight {
+	return weight{primary: w.primary + other.primary, secondary: w.secondary + other.secondary}
+}
+
+func (w weight) less(other weight) bool {
+	if w.primary != other.primary {
+		return w.primary < other.primary
+	}
+	return w.secondary < other.secondary
+}
+
+// Graph is the road network, checkpoints connected by the open legs in both directions
+type Graph struct {
+	edges map[uuid.UUID][]edge
+}
+
+type edge struct {
+	to  uuid.UUID
+	leg models.Leg
+}
+
+// NewGraph : Build the network from legs, closed ones are left out
+func NewGraph(legs []models.Leg) Graph {
+	graph := Graph{edges: map[uuid.UUID][]edge{}}
+	for _, leg := range legs {
+		if leg.Closed {
+			continue
+		}
+		graph.edges[leg.FromCheckpointId] = append(graph.edges[leg.FromCheckpointId], edge{to: leg.ToCheckpointId, leg: leg})
+		graph.edges[leg.ToCheckpointId] = append(graph.edges[leg.ToCheckpointId], edge{to: leg.FromCheckpointId, leg: leg})
+	}
+	return graph
+}
+
+// Path goes through Checkpoints along Legs, Legs[i] connects Checkpoints[i] and Checkpoints[i+1]
+type Path struct {
+	Checkpoints []uuid.UUID
+	Legs        []models.Leg
+	weight      weight
+}
+
+// DistanceKm : Road distance along the path
+func (path Path) DistanceKm() float64 {
+	var total float64
+	for _, leg := range path.Legs {
+		total += leg.DistanceKm
+	}
+	return math.Round(total*1000) / 1000
+}
+
+// DurationMinutes : Typical travel time along the path
+func (path Path) DurationMinutes() int64 {
+	var total int64
+	for _, leg := range path.Legs {
+		total += leg.DurationMinutes
+	}
+	return total
+}
+
+// TollCost : Tolls paid along the path
+func (path Path) TollCost() float64 {
+	var total float64
+	for _, leg := range path.Legs {
+		total += leg.TollCost
+	}
+	return math.Round(total*100) / 100
+}
+
+// join : The path followed by next, which starts where the path ends
+func (path Path) join(next Path) Path {
+	joined := Path{
+		Checkpoints: append(append([]uuid.UUID{}, path.Checkpoints...), next.Checkpoints[1:]...),
+		Legs:        append(append([]models.Leg{}, path.Legs...), next.Legs...),
+		weight:      path.weight.add(next.weight),
+	}
+	return joined
+}
+
+// through : The path along the checkpoints in order that goes only once through a checkpoint, false when there is none.
+// Each segment is the best one that avoids the checkpoints the path went through and the ones still ahead, shortest gives
+// the unrestricted best paths from a checkpoint, used as they are when they avoid them
+func (graph Graph) through(checkpoints []uuid.UUID, objective Objective, shortest func(uuid.UUID) map[uuid.UUID]Path) (Path, bool) {
+	path := Path{Checkpoints: []uuid.UUID{checkpoints[0]}}
+	visited := map[uuid.UUID]bool{checkpoints[0]: true}
+	for i := 1; i < len(checkpoints); i++ {
+		at, next := checkpoints[i-1], checkpoints[i]
+		if next == at {
+			continue
+		}
+		if visited[next] {
+			return Path{}, false
+		}
+		avoid := make(map[uuid.UUID]bool, len(visited)+len(checkpoints)-i)
+		for checkpointId := range visited {
+			avoid[checkpointId] = true
+		}
+		for _, ahead := range checkpoints[i+1:] {
+			if ahead != next {
+				avoid[ahead] = true
+			}
+		}
+		segment, ok := shortest(at)[next]
+		if !ok || crosses(segment, avoid) {
+			segment, ok = graph.shortestPaths(at, objective, avoid)[next]
+		}
+		if !ok {
+			return Path{}, false
+		}
+		path = path.join(segment)
+		for _, checkpointId := range segment.Checkpoints {
+			visited[checkpointId] = true
+		}
+	}
+	return path, true
+}
+
+// crosses : Whether the path goes through one of the checkpoints after leaving its first one
+func crosses(path Path, checkpoints map[uuid.UUID]bool) bool {
+	for _, checkpointId := range path.Checkpoints[1:] {
+		if checkpoints[checkpointId] {
+			return true
+		}
+	}
+	return false
+}
+
+// ShortestPaths : Best path from the checkpoint to every checkpoint it can reach (Dijkstra)
+func (graph Graph) ShortestPaths(from uuid.UUID, objective Objective) map[uuid.UUID]Path {
+	return graph.shortestPaths(from, objective, nil)
+}
+
+// shortestPaths : Best path from the checkpoint to every checkpoint it can reach without going through the avoided ones
+func (graph Graph) shortestPaths(from uuid.UUID, objective Objective, avoid map[uuid.UUID]bool) map[uuid.UUID]Path {
+	best := map[uuid.UUID]weight{from: {}}
+	previous := map[uuid.UUID]edge{}
+	done := map[uuid.UUID]bool{}
+	queue := &priorityQueue{{checkpointId: from}}
+
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(item)
+		if done[current.checkpointId] {
+			continue
+		}
+		done[current.checkpointId] = true
+		for _, next := range graph.edges[current.checkpointId] {
+			if avoid[next.to] {
+				continue
+			}
+			candidate := current.weight.add(legWeight(next.leg, objective))
+			if known, ok := best[next.to]; ok && !candidate.less(known) {
+				continue
+			}
+			best[next.to] = candidate
+			previous[next.to] = edge{to: current.checkpointId, leg: next.leg}
+			heap.Push(queue, item{checkpointId: next.to, weight: candidate})
+		}
+	}
+
+	paths := make(map[uuid.UUID]Path, len(best))
+	for checkpointId, total := range best {
+		path := Path{Checkpoints: []uuid.UUID{checkpointId}, weight: total}
+		for at := checkpointId; at != from; {
+			step := previous[at]
+			path.Checkpoints = append(path.Checkpoints, step.to)
+			path.Legs = append(path.Legs, step.leg)
+			at = step.to
+		}
+		reverse(path.Checkpoints)
+		reverse(path.Legs)
+		paths[checkpointId] = path
+	}
+	return paths
+}
+
+func legWeight(leg models.Leg, objective Objective) weight {
+	switch objective {
+	case ObjectiveDuration:
+		return weight{primary: float64(leg.DurationMinutes), secondary: leg.DistanceKm}
+	case ObjectiveCost:
+		return weight{primary: leg.TollCost, secondary: leg.DistanceKm}
+	default:
+		return weight{primary: leg.DistanceKm, secondary: float64(leg.DurationMinutes)}
+	}
+}
+
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
+type item struct {
+	checkpointId uuid.UUID
+	weight       weight
+}
+
+// priorityQueue pops the lightest item first
+type priorityQueue []item
+
+func (queue priorityQueue) Len() int           { return len(queue) }
+func (queue priorityQueue) Less(i, j int) bool { return queue[i].weight.less(queue[j].weight) }
+func (queue priorityQueue) Swap(i, j int)      { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *priorityQueue) Push(x interface{}) {
+	*queue = append(*queue, x.(item))
+}
+
+func (queue *priorityQueue) Pop() interface{} {
+	old := *queue
+	last := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,114 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// network names the checkpoints of a test graph
+type network map[string]uuid.UUID
+
+func (network network) id(name string) uuid.UUID {
+	if _, ok := network[name]; !ok {
+		network[name] = uuid.New()
+	}
+	return network[name]
+}
+
+func (network network) leg(from string, to string, distanceKm float64, durationMinutes int64, tollCost float64) models.Leg {
+	return models.Leg{
+		Id:               uuid.New(),
+		FromCheckpointId: network.id(from),
+		ToCheckpointId:   network.id(to),
+		DistanceKm:       distanceKm,
+		DurationMinutes:  durationMinutes,
+		TollCost:         tollCost,
+	}
+}
+
+// names : The checkpoints joined by dashes, A-B-C
+func (network network) names(checkpointIds []uuid.UUID) string {
+	names := make([]string, len(checkpointIds))
+	for i, checkpointId := range checkpointIds {
+		names[i] = "?"
+		for name, id := range network {
+			if id == checkpointId {
+				names[i] = name
+			}
+		}
+	}
+	return strings.Join(names, "-")
+}
+
+func TestShortestPaths(t *testing.T) {
+	n := network{}
+	// A-B-D is short but slow and paid, A-C-D is long but fast and free
+	legs := []models.Leg{
+		n.leg("A", "B", 10, 100, 25),
+		n.leg("B", "D", 10, 100, 25),
+		n.leg("C", "A", 20, 10, 0),
+		n.leg("C", "D", 20, 10, 0),
+		n.leg("E", "F", 1, 1, 0),
+	}
+	closed := n.leg("A", "D", 1, 1, 0)
+	closed.Closed = true
+	legs = append(legs, closed)
+
+	tests := []struct {
+		name      string
+		objective Objective
+		from      string
+		to        string
+		want      string
+		wantKm    float64
+	}{
+		{"shortest distance", ObjectiveDistance, "A", "D", "A-B-D", 20},
+		{"shortest duration", ObjectiveDuration, "A", "D", "A-C-D", 40},
+		{"cheapest tolls", ObjectiveCost, "A", "D", "A-C-D", 40},
+		{"legs go both ways", ObjectiveDistance, "D", "A", "D-B-A", 20},
+		{"to itself", ObjectiveDistance, "A", "A", "A", 0},
+		{"out of reach", ObjectiveDistance, "A", "E", "", 0},
+	}
+	graph := NewGraph(legs)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, ok := graph.ShortestPaths(n.id(test.from), test.objective)[n.id(test.to)]
+			if test.want == "" {
+				if ok {
+					t.Fatalf("found %s, want no path", n.names(path.Checkpoints))
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("found no path, want %s", test.want)
+			}
+			if got := n.names(path.Checkpoints); got != test.want {
+				t.Errorf("path = %s, want %s", got, test.want)
+			}
+			if len(path.Legs) != len(path.Checkpoints)-1 {
+				t.Errorf("%d legs for %d checkpoints", len(path.Legs), len(path.Checkpoints))
+			}
+			if path.DistanceKm() != test.wantKm {
+				t.Errorf("DistanceKm = %v, want %v", path.DistanceKm(), test.wantKm)
+			}
+		})
+	}
+}
+
+func TestShortestPathsTies(t *testing.T) {
+	n := network{}
+	// Both ways are free, the shorter one wins the tie on the cost
+	graph := NewGraph([]models.Leg{
+		n.leg("A", "B", 50, 30, 0),
+		n.leg("B", "D", 50, 30, 0),
+		n.leg("A", "C", 10, 30, 0),
+		n.leg("C", "D", 10, 30, 0),
+	})
+	path := graph.ShortestPaths(n.id("A"), ObjectiveCost)[n.id("D")]
+	if got := n.names(path.Checkpoints); got != "A-C-D" {
+		t.Errorf("path = %s, want A-C-D", got)
+	}
+}
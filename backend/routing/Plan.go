@@ -0,0 +1,310 @@
+package routing
+
+import (
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Vehicle is a tractor the planner may give shipments to, it leaves From and ends at To when set
+type Vehicle struct {
+	Id           uuid.UUID
+	ResourceType models.ResourceType
+	Capacity     float64
+	From         uuid.UUID
+	To           *uuid.UUID
+}
+
+// Shipment is a lot to pick up at Pickup and deliver at Delivery
+type Shipment struct {
+	Id           uuid.UUID
+	ResourceType models.ResourceType
+	Volume       float64
+	Pickup       uuid.UUID
+	Delivery     uuid.UUID
+}
+
+// Reason tells why a shipment is left out of a plan
+type Reason string
+
+const (
+	// ReasonResourceType : No vehicle carries the resource type of the shipment
+	ReasonResourceType Reason = "no_tractor_for_resource_type"
+	// ReasonCapacity : The shipment is larger than every vehicle carrying its resource type
+	ReasonCapacity Reason = "exceeds_capacity"
+	// ReasonNoRoute : No vehicle fits the shipment in its route, out of reach over the open legs, over capacity on the
+	// way or going twice through a checkpoint
+	ReasonNoRoute Reason = "no_route"
+)
+
+// Stop is a checkpoint where a vehicle picks up or delivers shipments, Load is carried when it leaves
+type Stop struct {
+	CheckpointId uuid.UUID
+	Pickups      []uuid.UUID
+	Deliveries   []uuid.UUID
+	Load         float64
+}
+
+// Assignment is the route of a vehicle, Path goes through every stop in order
+type Assignment struct {
+	VehicleId uuid.UUID
+	Stops     []Stop
+	Path      Path
+}
+
+// Unplanned is a shipment no vehicle takes
+type Unplanned struct {
+	ShipmentId uuid.UUID
+	Reason     Reason
+}
+
+// Plan gives shipments to vehicles, vehicles without shipments have no assignment
+type Plan struct {
+	Assignments []Assignment
+	Unplanned   []Unplanned
+}
+
+// DistanceKm : Road distance driven by every vehicle of the plan
+func (plan Plan) DistanceKm() float64 {
+	var legs []models.Leg
+	for _, assignment := range plan.Assignments {
+		legs = append(legs, assignment.Path.Legs...)
+	}
+	return Path{Legs: legs}.DistanceKm()
+}
+
+// Plan : Give the shipments to the vehicles, minimising the total distance. Shipments go one at a time, the one that
+// adds the fewest km first, into the vehicle and at the stops where they cost the least (cheapest insertion). A vehicle
+// picks up a shipment before delivering it, never carries more than its capacity and goes only once through a checkpoint
+func (graph Graph) Plan(vehicles []Vehicle, shipments []Shipment) Plan {
+	planner := planner{graph: graph, shipments: shipments, shortest: map[uuid.UUID]map[uuid.UUID]Path{}}
+	routes := make([]route, len(vehicles))
+	usable := make([]bool, len(vehicles))
+	for v, vehicle := range vehicles {
+		routes[v] = route{stops: []stop{{checkpointId: vehicle.From}}}
+		if vehicle.To != nil && *vehicle.To != vehicle.From {
+			routes[v].stops = append(routes[v].stops, stop{checkpointId: *vehicle.To})
+		}
+		routes[v].path, usable[v] = planner.follow(vehicle, routes[v].stops)
+	}
+
+	// best[s][v] is the cheapest insertion of shipment s into vehicle v, only the vehicle that changed is computed again
+	best := make([][]*insertion, len(shipments))
+	for s := range shipments {
+		best[s] = make([]*insertion, len(vehicles))
+		for v, vehicle := range vehicles {
+			if usable[v] {
+				best[s][v] = planner.insert(vehicle, routes[v], s)
+			}
+		}
+	}
+	planned := make([]bool, len(shipments))
+	for {
+		chosenShipment, chosenVehicle := -1, -1
+		for s := range shipments {
+			for v := range vehicles {
+				if planned[s] || best[s][v] == nil {
+					continue
+				}
+				if chosenShipment < 0 || best[s][v].addedKm < best[chosenShipment][chosenVehicle].addedKm {
+					chosenShipment, chosenVehicle = s, v
+				}
+			}
+		}
+		if chosenShipment < 0 {
+			break
+		}
+		planned[chosenShipment] = true
+		routes[chosenVehicle] = best[chosenShipment][chosenVehicle].route
+		for s := range shipments {
+			if !planned[s] {
+				best[s][chosenVehicle] = planner.insert(vehicles[chosenVehicle], routes[chosenVehicle], s)
+			}
+		}
+	}
+
+	var plan Plan
+	for v, vehicle := range vehicles {
+		if routes[v].shipments() > 0 {
+			plan.Assignments = append(plan.Assignments, planner.assignment(vehicle, routes[v]))
+		}
+	}
+	for s, shipment := range shipments {
+		if !planned[s] {
+			plan.Unplanned = append(plan.Unplanned, Unplanned{ShipmentId: shipment.Id, Reason: unplannedReason(vehicles, shipment)})
+		}
+	}
+	return plan
+}
+
+func unplannedReason(vehicles []Vehicle, shipment Shipment) Reason {
+	reason := ReasonResourceType
+	for _, vehicle := range vehicles {
+		if vehicle.ResourceType != shipment.ResourceType {
+			continue
+		}
+		if vehicle.Capacity >= shipment.Volume {
+			return ReasonNoRoute
+		}
+		reason = ReasonCapacity
+	}
+	return reason
+}
+
+type planner struct {
+	graph     Graph
+	shipments []Shipment
+	shortest  map[uuid.UUID]map[uuid.UUID]Path
+}
+
+// stop lists shipments by index in planner.shipments
+type stop struct {
+	checkpointId uuid.UUID
+	pickups      []int
+	deliveries   []int
+}
+
+type route struct {
+	stops []stop
+	path  Path
+}
+
+func (route route) shipments() int {
+	count := 0
+	for _, stop := range route.stops {
+		count += len(stop.pickups)
+	}
+	return count
+}
+
+type insertion struct {
+	route   route
+	addedKm float64
+}
+
+// insert : The cheapest way to add the shipment to the route of the vehicle, nil when it does not fit
+func (planner *planner) insert(vehicle Vehicle, current route, s int) *insertion {
+	shipment := planner.shipments[s]
+	if shipment.ResourceType != vehicle.ResourceType || shipment.Volume > vehicle.Capacity || shipment.Pickup == shipment.Delivery {
+		return nil
+	}
+	// The last stop is the destination of the vehicle when it has one, nothing is picked up there nor added after it
+	last := len(current.stops)
+	if vehicle.To != nil && *vehicle.To != vehicle.From {
+		last--
+	}
+
+	var best *insertion
+	try := func(stops []stop) {
+		path, ok := planner.follow(vehicle, stops)
+		if !ok {
+			return
+		}
+		added := path.weight.primary - current.path.weight.primary
+		if best == nil || added < best.addedKm {
+			best = &insertion{route: route{stops: stops, path: path}, addedKm: added}
+		}
+	}
+	pickUp := func(stop *stop) { stop.pickups = append(stop.pickups, s) }
+	deliver := func(stop *stop) { stop.deliveries = append(stop.deliveries, s) }
+	for _, withPickup := range places(current.stops, shipment.Pickup, 0, last-1, last, pickUp) {
+		// The delivery comes after the pickup, which moved the destination one stop further when it added a stop
+		end := last
+		if len(withPickup.stops) > len(current.stops) {
+			end++
+		}
+		for _, withDelivery := range places(withPickup.stops, shipment.Delivery, withPickup.at+1, len(withPickup.stops)-1, end, deliver) {
+			try(withDelivery.stops)
+		}
+	}
+	return best
+}
+
+type placed struct {
+	stops []stop
+	at    int
+}
+
+// places : Every way to do something at the checkpoint from stop index first on, in an existing stop at the checkpoint
+// up to index lastMerge or in a new stop inserted at an index up to lastInsert
+func places(stops []stop, checkpointId uuid.UUID, first int, lastMerge int, lastInsert int, do func(*stop)) []placed {
+	var result []placed
+	for i := first; i <= lastMerge; i++ {
+		if stops[i].checkpointId == checkpointId {
+			changed := copyStops(stops)
+			do(&changed[i])
+			result = append(result, placed{stops: changed, at: i})
+		}
+	}
+	for i := max(first, 1); i <= lastInsert; i++ {
+		changed := make([]stop, 0, len(stops)+1)
+		changed = append(changed, copyStops(stops[:i])...)
+		changed = append(changed, stop{checkpointId: checkpointId})
+		changed = append(changed, copyStops(stops[i:])...)
+		do(&changed[i])
+		result = append(result, placed{stops: changed, at: i})
+	}
+	return result
+}
+
+func copyStops(stops []stop) []stop {
+	copied := make([]stop, len(stops))
+	for i, original := range stops {
+		copied[i] = stop{
+			checkpointId: original.checkpointId,
+			pickups:      append([]int{}, original.pickups...),
+			deliveries:   append([]int{}, original.deliveries...),
+		}
+	}
+	return copied
+}
+
+// follow : The shortest path through the stops going only once through a checkpoint, false when there is none or when
+// the vehicle would carry more than its capacity
+func (planner *planner) follow(vehicle Vehicle, stops []stop) (Path, bool) {
+	var load float64
+	checkpoints := make([]uuid.UUID, len(stops))
+	for i, stop := range stops {
+		if i > 0 && stop.checkpointId == stops[i-1].checkpointId {
+			return Path{}, false
+		}
+		checkpoints[i] = stop.checkpointId
+		for _, s := range stop.deliveries {
+			load -= planner.shipments[s].Volume
+		}
+		for _, s := range stop.pickups {
+			load += planner.shipments[s].Volume
+		}
+		if load > vehicle.Capacity {
+			return Path{}, false
+		}
+	}
+	return planner.graph.through(checkpoints, ObjectiveDistance, planner.shortestFrom)
+}
+
+func (planner *planner) shortestFrom(checkpointId uuid.UUID) map[uuid.UUID]Path {
+	paths, ok := planner.shortest[checkpointId]
+	if !ok {
+		paths = planner.graph.ShortestPaths(checkpointId, ObjectiveDistance)
+		planner.shortest[checkpointId] = paths
+	}
+	return paths
+}
+
+func (planner *planner) assignment(vehicle Vehicle, route route) Assignment {
+	assignment := Assignment{VehicleId: vehicle.Id, Stops: make([]Stop, len(route.stops)), Path: route.path}
+	var load float64
+	for i, stop := range route.stops {
+		assignment.Stops[i] = Stop{CheckpointId: stop.checkpointId, Pickups: []uuid.UUID{}, Deliveries: []uuid.UUID{}}
+		for _, s := range stop.deliveries {
+			load -= planner.shipments[s].Volume
+			assignment.Stops[i].Deliveries = append(assignment.Stops[i].Deliveries, planner.shipments[s].Id)
+		}
+		for _, s := range stop.pickups {
+			load += planner.shipments[s].Volume
+			assignment.Stops[i].Pickups = append(assignment.Stops[i].Pickups, planner.shipments[s].Id)
+		}
+		assignment.Stops[i].Load = load
+	}
+	return assignment
+}
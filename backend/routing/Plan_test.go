@@ -0,0 +1,209 @@
+package routing
+
+import (
+	"testing"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestPlan(t *testing.T) {
+	n := network{}
+	graph := NewGraph([]models.Leg{
+		// A line A-B-C-D
+		n.leg("A", "B", 1, 1, 0),
+		n.leg("B", "C", 1, 1, 0),
+		n.leg("C", "D", 1, 1, 0),
+		// S is closest through X, so is the way back from S
+		n.leg("P", "X", 1, 1, 0),
+		n.leg("X", "S", 1, 1, 0),
+		n.leg("X", "Q", 1, 1, 0),
+		n.leg("S", "Q", 5, 5, 0),
+	})
+	vehicle := func(name string, capacity float64, from string, to string) Vehicle {
+		vehicle := Vehicle{Id: n.id(name), ResourceType: models.ResourceTypeBulk, Capacity: capacity, From: n.id(from)}
+		if to != "" {
+			destination := n.id(to)
+			vehicle.To = &destination
+		}
+		return vehicle
+	}
+	shipment := func(name string, volume float64, pickup string, delivery string) Shipment {
+		return Shipment{Id: n.id(name), ResourceType: models.ResourceTypeBulk, Volume: volume, Pickup: n.id(pickup), Delivery: n.id(delivery)}
+	}
+	liquid := shipment("liquid", 1, "B", "C")
+	liquid.ResourceType = models.ResourceTypeLiquid
+
+	tests := []struct {
+		name          string
+		vehicles      []Vehicle
+		shipments     []Shipment
+		wantStops     map[string]string
+		wantUnplanned map[string]Reason
+	}{
+		{
+			name:      "one shipment",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments: []Shipment{shipment("s1", 5, "B", "C")},
+			wantStops: map[string]string{"v1": "A-B-C"},
+		},
+		{
+			name:      "picked up before delivered",
+			vehicles:  []Vehicle{vehicle("v1", 10, "D", "")},
+			shipments: []Shipment{shipment("s1", 5, "C", "B")},
+			wantStops: map[string]string{"v1": "D-C-B"},
+		},
+		{
+			name:          "delivery behind the pickup",
+			vehicles:      []Vehicle{vehicle("v1", 10, "B", "")},
+			shipments:     []Shipment{shipment("s1", 5, "C", "A")},
+			wantUnplanned: map[string]Reason{"s1": ReasonNoRoute},
+		},
+		{
+			name:          "over capacity together",
+			vehicles:      []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments:     []Shipment{shipment("s1", 6, "B", "C"), shipment("s2", 6, "B", "D")},
+			wantStops:     map[string]string{"v1": "A-B-C"},
+			wantUnplanned: map[string]Reason{"s2": ReasonNoRoute},
+		},
+		{
+			name:      "room freed by a delivery",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments: []Shipment{shipment("s1", 6, "B", "C"), shipment("s2", 6, "C", "D")},
+			wantStops: map[string]string{"v1": "A-B-C-D"},
+		},
+		{
+			name:      "shared stops",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments: []Shipment{shipment("s1", 4, "B", "D"), shipment("s2", 4, "B", "C")},
+			wantStops: map[string]string{"v1": "A-B-C-D"},
+		},
+		{
+			name:          "larger than every vehicle",
+			vehicles:      []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments:     []Shipment{shipment("s1", 11, "B", "C")},
+			wantUnplanned: map[string]Reason{"s1": ReasonCapacity},
+		},
+		{
+			name:          "no vehicle for the resource type",
+			vehicles:      []Vehicle{vehicle("v1", 10, "A", "")},
+			shipments:     []Shipment{liquid},
+			wantUnplanned: map[string]Reason{"liquid": ReasonResourceType},
+		},
+		{
+			name:      "closest vehicle",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", ""), vehicle("v2", 10, "B", "")},
+			shipments: []Shipment{shipment("s1", 5, "C", "D")},
+			wantStops: map[string]string{"v2": "B-C-D"},
+		},
+		{
+			name:      "destination stays last",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "D")},
+			shipments: []Shipment{shipment("s1", 5, "B", "C")},
+			wantStops: map[string]string{"v1": "A-B-C-D"},
+		},
+		{
+			name:      "delivered at the destination",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "D")},
+			shipments: []Shipment{shipment("s1", 5, "B", "D")},
+			wantStops: map[string]string{"v1": "A-B-D"},
+		},
+		{
+			name:          "nothing picked up at the destination",
+			vehicles:      []Vehicle{vehicle("v1", 10, "A", "C")},
+			shipments:     []Shipment{shipment("s1", 5, "C", "D")},
+			wantUnplanned: map[string]Reason{"s1": ReasonNoRoute},
+		},
+		{
+			name:      "destination where the vehicle is",
+			vehicles:  []Vehicle{vehicle("v1", 10, "A", "A")},
+			shipments: []Shipment{shipment("s1", 5, "B", "C")},
+			wantStops: map[string]string{"v1": "A-B-C"},
+		},
+		{
+			// P-X-S then S-X-Q goes twice through X, S-Q goes around it
+			name:      "segment goes around a used checkpoint",
+			vehicles:  []Vehicle{vehicle("v1", 10, "P", "Q")},
+			shipments: []Shipment{shipment("s1", 5, "S", "Q")},
+			wantStops: map[string]string{"v1": "P-S-Q"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := graph.Plan(test.vehicles, test.shipments)
+
+			gotStops := map[string]string{}
+			for _, assignment := range plan.Assignments {
+				checkpoints := make([]uuid.UUID, len(assignment.Stops))
+				for i, stop := range assignment.Stops {
+					checkpoints[i] = stop.CheckpointId
+				}
+				gotStops[n.names([]uuid.UUID{assignment.VehicleId})] = n.names(checkpoints)
+				for _, vehicle := range test.vehicles {
+					if vehicle.Id == assignment.VehicleId {
+						checkAssignment(t, n, vehicle, assignment)
+					}
+				}
+			}
+			if !sameMap(gotStops, test.wantStops) {
+				t.Errorf("stops = %v, want %v", gotStops, test.wantStops)
+			}
+
+			gotUnplanned := map[string]Reason{}
+			for _, unplanned := range plan.Unplanned {
+				gotUnplanned[n.names([]uuid.UUID{unplanned.ShipmentId})] = unplanned.Reason
+			}
+			if !sameMap(gotUnplanned, test.wantUnplanned) {
+				t.Errorf("unplanned = %v, want %v", gotUnplanned, test.wantUnplanned)
+			}
+		})
+	}
+}
+
+// checkAssignment : The rules every route of a plan keeps, whatever the shipments
+func checkAssignment(t *testing.T, n network, vehicle Vehicle, assignment Assignment) {
+	t.Helper()
+	pickedUp := map[uuid.UUID]int{}
+	for i, stop := range assignment.Stops {
+		for _, shipmentId := range stop.Pickups {
+			pickedUp[shipmentId] = i
+		}
+		for _, shipmentId := range stop.Deliveries {
+			if at, ok := pickedUp[shipmentId]; !ok || at >= i {
+				t.Errorf("%s delivered at stop %d before it is picked up", n.names([]uuid.UUID{shipmentId}), i)
+			}
+		}
+		if stop.Load > vehicle.Capacity {
+			t.Errorf("stop %d leaves with %v, more than the capacity %v", i, stop.Load, vehicle.Capacity)
+		}
+	}
+	last := assignment.Stops[len(assignment.Stops)-1].CheckpointId
+	if vehicle.To != nil && *vehicle.To != vehicle.From && last != *vehicle.To {
+		t.Errorf("the route ends at %s, not at the destination", n.names([]uuid.UUID{last}))
+	}
+	if revisits(assignment.Path) {
+		t.Errorf("path %s goes twice through a checkpoint", n.names(assignment.Path.Checkpoints))
+	}
+	// The path goes through the stops in order
+	next := 0
+	for _, checkpointId := range assignment.Path.Checkpoints {
+		if next < len(assignment.Stops) && assignment.Stops[next].CheckpointId == checkpointId {
+			next++
+		}
+	}
+	if next != len(assignment.Stops) {
+		t.Errorf("path %s misses stops", n.names(assignment.Path.Checkpoints))
+	}
+}
+
+func sameMap[V comparable](got map[string]V, want map[string]V) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for key, value := range want {
+		if got[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// MaxStops is the largest number of intermediate stops whose order is optimised, every order is tried
+const MaxStops = 8
+
+var (
+	// ErrNoPath : The open legs do not connect the checkpoints
+	ErrNoPath = errors.New("no path connects the checkpoints")
+	// ErrRevisit : Every path through the stops goes twice through a checkpoint, which a route can not do
+	ErrRevisit = errors.New("every path through the stops goes twice through a checkpoint")
+)
+
+// Best : The best path from one checkpoint to another through every stop. The stops are visited in the order given
+// when keepOrder is set, in the best order otherwise. A route goes only once through a checkpoint, so neither can the path:
+// each segment goes around the checkpoints the path already went through and the stops still ahead
+func (graph Graph) Best(from uuid.UUID, to uuid.UUID, stops []uuid.UUID, objective Objective, keepOrder bool) (Path, error) {
+	shortest := map[uuid.UUID]map[uuid.UUID]Path{}
+	shortestFrom := func(origin uuid.UUID) map[uuid.UUID]Path {
+		paths, ok := shortest[origin]
+		if !ok {
+			paths = graph.ShortestPaths(origin, objective)
+			shortest[origin] = paths
+		}
+		return paths
+	}
+
+	var best *Path
+	unreachable := false
+	try := func(order []uuid.UUID) {
+		checkpoints := append(append([]uuid.UUID{from}, order...), to)
+		// The unrestricted segments weigh no more than the ones going around checkpoints, an order they do not make
+		// better than the best one can be skipped
+		var bound weight
+		for i := 1; i < len(checkpoints); i++ {
+			segment, ok := shortestFrom(checkpoints[i-1])[checkpoints[i]]
+			if !ok {
+				unreachable = true
+				return
+			}
+			bound = bound.add(segment.weight)
+		}
+		if best != nil && !bound.less(best.weight) {
+			return
+		}
+		path, ok := graph.through(checkpoints, objective, shortestFrom)
+		if !ok {
+			return
+		}
+		if best == nil || path.weight.less(best.weight) {
+			best = &path
+		}
+	}
+
+	if keepOrder || len(stops) < 2 {
+		try(stops)
+	} else {
+		permute(append([]uuid.UUID{}, stops...), len(stops), try)
+	}
+	if best != nil {
+		return *best, nil
+	}
+	if unreachable {
+		return Path{}, ErrNoPath
+	}
+	return Path{}, ErrRevisit
+}
+
+// permute : Call try with every order of the first n items (Heap's algorithm)
+func permute(items []uuid.UUID, n int, try func([]uuid.UUID)) {
+	if n <= 1 {
+		try(items)
+		return
+	}
+	for i := 0; i < n-1; i++ {
+		permute(items, n-1, try)
+		if n%2 == 0 {
+			items[i], items[n-1] = items[n-1], items[i]
+		} else {
+			items[0], items[n-1] = items[n-1], items[0]
+		}
+	}
+	permute(items, n-1, try)
+}
@@ -0,0 +1,131 @@
+package routing
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestBest(t *testing.T) {
+	n := network{}
+	graph := NewGraph([]models.Leg{
+		// A line A-B-C-D with a detour A-E-C
+		n.leg("A", "B", 1, 1, 0),
+		n.leg("B", "C", 1, 1, 0),
+		n.leg("C", "D", 1, 1, 0),
+		n.leg("A", "E", 5, 5, 0),
+		n.leg("E", "C", 5, 5, 0),
+		// S is closest through X, so are the way back from S and H
+		n.leg("P", "X", 1, 1, 0),
+		n.leg("X", "S", 1, 1, 0),
+		n.leg("X", "Q", 1, 1, 0),
+		n.leg("S", "Q", 5, 5, 0),
+		n.leg("P", "S", 10, 10, 0),
+		// H hangs off X, a path through it comes back through X
+		n.leg("X", "H", 1, 1, 0),
+		// U has no leg
+		n.leg("U", "V", 1, 1, 0),
+	})
+
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		stops     []string
+		keepOrder bool
+		want      string
+		wantErr   error
+	}{
+		{"no stop", "A", "D", nil, false, "A-B-C-D", nil},
+		{"stops in the best order", "A", "D", []string{"C", "B"}, false, "A-B-C-D", nil},
+		{"stops in the order given", "A", "C", []string{"B"}, true, "A-B-C", nil},
+		// Going back to B from C goes through B first, or through C again
+		{"order given going back", "A", "D", []string{"C", "B"}, true, "", ErrRevisit},
+		// P-X-S then S-X-Q goes twice through X, S-Q goes around it
+		{"segment goes around a used checkpoint", "P", "Q", []string{"S"}, false, "P-X-S-Q", nil},
+		// A-B-C goes through B, which comes after C
+		{"segment goes around a checkpoint ahead", "A", "B", []string{"C"}, false, "A-E-C-B", nil},
+		{"dead end stop", "P", "Q", []string{"H"}, false, "", ErrRevisit},
+		{"stop out of reach", "A", "D", []string{"U"}, false, "", ErrNoPath},
+		{"destination out of reach", "A", "V", nil, false, "", ErrNoPath},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stops := make([]uuid.UUID, len(test.stops))
+			for i, name := range test.stops {
+				stops[i] = n.id(name)
+			}
+			path, err := graph.Best(n.id(test.from), n.id(test.to), stops, ObjectiveDistance, test.keepOrder)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("err = %v with path %s, want %v", err, n.names(path.Checkpoints), test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v, want %s", err, test.want)
+			}
+			if got := n.names(path.Checkpoints); got != test.want {
+				t.Errorf("path = %s, want %s", got, test.want)
+			}
+			if revisits(path) {
+				t.Errorf("path %s goes twice through a checkpoint", n.names(path.Checkpoints))
+			}
+		})
+	}
+}
+
+func TestPermute(t *testing.T) {
+	n := network{}
+	tests := []struct {
+		name  string
+		items []string
+		want  int
+	}{
+		{"no item", nil, 1},
+		{"one item", []string{"A"}, 1},
+		{"two items", []string{"A", "B"}, 2},
+		{"three items", []string{"A", "B", "C"}, 6},
+		{"five items", []string{"A", "B", "C", "D", "E"}, 120},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := make([]uuid.UUID, len(test.items))
+			for i, name := range test.items {
+				items[i] = n.id(name)
+			}
+			want := sorted(strings.Split(n.names(items), "-"))
+			seen := map[string]bool{}
+			permute(items, len(items), func(order []uuid.UUID) {
+				if got := sorted(strings.Split(n.names(order), "-")); strings.Join(got, "-") != strings.Join(want, "-") {
+					t.Errorf("order %s does not hold every item once", n.names(order))
+				}
+				seen[n.names(order)] = true
+			})
+			if len(seen) != test.want {
+				t.Errorf("%d distinct orders, want %d", len(seen), test.want)
+			}
+		})
+	}
+}
+
+func sorted(items []string) []string {
+	sort.Strings(items)
+	return items
+}
+
+// revisits : Whether the path goes twice through a checkpoint
+func revisits(path Path) bool {
+	seen := make(map[uuid.UUID]bool, len(path.Checkpoints))
+	for _, checkpointId := range path.Checkpoints {
+		if seen[checkpointId] {
+			return true
+		}
+		seen[checkpointId] = true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"tms-backend/apierror"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeleteCheckpoint : Delete a checkpoint nothing goes through anymore, the usage is in the details of the conflict otherwise
+func DeleteCheckpoint(db *gorm.DB, checkpointId uuid.UUID) (models.Checkpoint, error) {
+	var checkpoint models.Checkpoint
+	err := Atomically(db, func(tx *gorm.DB) error {
+		err := forUpdate(tx).First(&checkpoint, "id = ?", checkpointId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierror.ErrCheckpointNotFound
+		}
+		if err != nil {
+			return err
+		}
+		usage, err := checkpoint.Usage(tx)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return apierror.ErrCheckpointInUse.WithDetails(usage)
+		}
+		return tx.Delete(&checkpoint).Error
+	})
+	return checkpoint, err
+}
+
+// DeleteCountry : Delete a country without checkpoints
+func DeleteCountry(db *gorm.DB, countryId uuid.UUID) (models.Country, error) {
+	var country models.Country
+	err := Atomically(db, func(tx *gorm.DB) error {
+		err := forUpdate(tx).First(&country, "id = ?", countryId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierror.ErrCountryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		count, err := country.CountCheckpoints(tx)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return apierror.ErrCountryInUse.WithDetails(map[string]int64{"checkpoints": count})
+		}
+		return tx.Delete(&country).Error
+	})
+	return country, err
+}
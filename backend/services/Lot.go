@@ -0,0 +1,162 @@
+package services
+
+import (
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IsCompatible : Check that the tractor follows a route through the lot checkpoints, carries its resource and has room left at pick-up
+func IsCompatible(db *gorm.DB, lot models.Lot, tractor models.Tractor) bool {
+	if tractor.RouteId == nil || tractor.CurrentCheckpointId == nil {
+		return false
+	}
+	if lot.CurrentCheckpointId == nil || lot.StartCheckpointId == nil {
+		return false
+	}
+	if lot.ResourceType != tractor.ResourceType {
+		return false
+	}
+	if tractor.State != models.StatePending {
+		return false
+	}
+
+	var currentRouteCheckpoint models.RouteCheckpoint
+	var lotRouteCheckpoint models.RouteCheckpoint
+	if err := currentRouteCheckpoint.GetRouteCheckpoint(db, *tractor.RouteId, *tractor.CurrentCheckpointId); err != nil {
+		return false
+	}
+	if err := lotRouteCheckpoint.GetRouteCheckpoint(db, *tractor.RouteId, *lot.CurrentCheckpointId); err != nil {
+		return false
+	}
+	if currentRouteCheckpoint.Position > lotRouteCheckpoint.Position {
+		return false
+	}
+	volumeAtCheckpoint, err := tractor.GetVolumeAtCheckpoint(db, *lot.StartCheckpointId)
+	if err != nil {
+		return false
+	}
+	return tractor.MaxVolume-volumeAtCheckpoint >= lot.Volume
+}
+
+// AssignTractorToLot : Plan the pick-up and the drop-off of the lot by the tractor, the lot boards right away when the tractor is already at its start.
+// lotVersion is the version of the lot the caller read, 0 skips the check.
+func AssignTractorToLot(db *gorm.DB, user models.User, lotId uuid.UUID, tractorId uuid.UUID, lotVersion int64) (models.Lot, error) {
+	var lot models.Lot
+	err := Atomically(db, func(tx *gorm.DB) error {
+		tractor, err := lockTractor(tx, tractorId)
+		if err != nil {
+			return err
+		}
+		lot, err = lockLot(tx, lotId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageLot(user, lot) || !auth.CanManageTractor(user, tractor) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("lots", lot.Id, lot.Version, lotVersion); err != nil {
+			return err
+		}
+		if lot.TrafficManagerId == nil || lot.EndCheckpointId == nil || !IsCompatible(tx, lot, tractor) {
+			return apierror.ErrLotIncompatible
+		}
+		return scheduleLot(tx, &tractor, &lot)
+	})
+	return lot, err
+}
+
+// scheduleLot : Record the pick-up and the drop-off of the lot along the route of the tractor, the lot boards right away
+// when the tractor is already at its start
+func scheduleLot(tx *gorm.DB, tractor *models.Tractor, lot *models.Lot) error {
+	var routeCheckpointStart models.RouteCheckpoint
+	var routeCheckpointEnd models.RouteCheckpoint
+	if err := routeCheckpointStart.GetRouteCheckpoint(tx, *tractor.RouteId, *lot.StartCheckpointId); err != nil {
+		return err
+	}
+	if err := routeCheckpointEnd.GetRouteCheckpoint(tx, *tractor.RouteId, *lot.EndCheckpointId); err != nil {
+		return err
+	}
+
+	var transactionIn models.Transaction
+	var transactionOut models.Transaction
+	if err := transactionIn.CreateTransaction(tx, models.TransactionState(models.TransactionStateIn), lot.Id, tractor.Id, *tractor.RouteId, *lot.StartCheckpointId, *lot.TrafficManagerId, routeCheckpointStart.Id); err != nil {
+		return err
+	}
+	if err := transactionOut.CreateTransaction(tx, models.TransactionState(models.TransactionStateOut), lot.Id, tractor.Id, *tractor.RouteId, *lot.EndCheckpointId, *lot.TrafficManagerId, routeCheckpointEnd.Id); err != nil {
+		return err
+	}
+
+	lot.TractorId = &tractor.Id
+	if *lot.StartCheckpointId == *tractor.CurrentCheckpointId {
+		lot.InTractor = true
+		lot.State = models.StateInTransit
+		tractor.CurrentVolume += lot.Volume
+		if err := tx.Save(tractor).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Save(lot).Error
+}
+
+// AssociateLotToTrafficManager : Hand the lot to a traffic manager of its organization, it then waits for a tractor. lotVersion 0 skips the version check
+func AssociateLotToTrafficManager(db *gorm.DB, user models.User, lotId uuid.UUID, trafficManagerId uuid.UUID, lotVersion int64) (models.Lot, error) {
+	var lot models.Lot
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		lot, err = lockLot(tx, lotId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageLot(user, lot) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("lots", lot.Id, lot.Version, lotVersion); err != nil {
+			return err
+		}
+		if _, err := trafficManagerOf(tx, trafficManagerId, lot.OrganizationId); err != nil {
+			return err
+		}
+		lot.TrafficManagerId = &trafficManagerId
+		lot.State = models.StatePending
+		return tx.Save(&lot).Error
+	})
+	return lot, err
+}
+
+// AssignTraderToLot : Hand the lot to the least busy trader and put it on the market until limitDate, lotVersion 0 skips the version check
+func AssignTraderToLot(db *gorm.DB, user models.User, lotId uuid.UUID, limitDate time.Time, lotVersion int64) (models.Lot, error) {
+	var lot models.Lot
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		lot, err = lockLot(tx, lotId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageLot(user, lot) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("lots", lot.Id, lot.Version, lotVersion); err != nil {
+			return err
+		}
+
+		trader, err := availableTrader(tx, &models.Lot{})
+		if err != nil {
+			return err
+		}
+		lot.TraderId = &trader.Id
+		lot.State = models.StateAtTrader
+		if err := tx.Save(&lot).Error; err != nil {
+			return err
+		}
+
+		var offer models.Offer
+		_, err = offer.CreateOfferLot(tx, limitDate, lot.Id)
+		return err
+	})
+	return lot, err
+}
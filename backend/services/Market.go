@@ -0,0 +1,267 @@
+package services
+
+import (
+	"errors"
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PutLotOnMarket : Offer the lot on the stock exchange until limitDate, lotVersion 0 skips the version check
+func PutLotOnMarket(db *gorm.DB, user models.User, lotId uuid.UUID, limitDate time.Time, lotVersion int64) (models.Offer, error) {
+	offer := models.Offer{LimitDate: limitDate, LotId: &lotId}
+	err := Atomically(db, func(tx *gorm.DB) error {
+		lot, err := lockLot(tx, lotId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageLot(user, lot) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("lots", lot.Id, lot.Version, lotVersion); err != nil {
+			return err
+		}
+		lot.State = models.StateOnMarket
+		if err := tx.Save(&lot).Error; err != nil {
+			return err
+		}
+		return tx.Create(&offer).Error
+	})
+	return offer, err
+}
+
+// PutTractorOnMarket : Offer the room left in the tractor on the stock exchange until limitDate, tractorVersion 0 skips the version check
+func PutTractorOnMarket(db *gorm.DB, user models.User, tractorId uuid.UUID, limitDate time.Time, tractorVersion int64) (models.Offer, error) {
+	offer := models.Offer{LimitDate: limitDate, TractorId: &tractorId}
+	err := Atomically(db, func(tx *gorm.DB) error {
+		tractor, err := lockTractor(tx, tractorId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageTractor(user, tractor) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, tractorVersion); err != nil {
+			return err
+		}
+		tractor.State = models.StateOnMarket
+		if err := tx.Save(&tractor).Error; err != nil {
+			return err
+		}
+		return tx.Create(&offer).Error
+	})
+	return offer, err
+}
+
+// BidOnLot : Bid to carry the lot of the offer, which is still on the market and not owned by the user
+func BidOnLot(db *gorm.DB, user models.User, offerId uuid.UUID, amount float64) (models.Bid, error) {
+	bid := models.Bid{OfferId: offerId, Bid: amount, State: "in_progress", OwnerId: user.Id}
+	err := Atomically(db, func(tx *gorm.DB) error {
+		offer, err := findOffer(tx, offerId)
+		if err != nil {
+			return err
+		}
+		if offer.LotId == nil {
+			return apierror.ErrOfferNotFound
+		}
+		lot, err := lockLot(tx, *offer.LotId)
+		if err != nil {
+			return err
+		}
+		if lot.OwnerId == user.Id {
+			return apierror.ErrOwnOffer
+		}
+		if err := expectOpen(tx, offer, lot.State); err != nil {
+			return err
+		}
+		return tx.Create(&bid).Error
+	})
+	return bid, err
+}
+
+// BidOnTractor : Bid for room in the tractor of the offer, which is still on the market and not owned by the user
+func BidOnTractor(db *gorm.DB, user models.User, offerId uuid.UUID, amount float64, volume float64) (models.Bid, error) {
+	bid := models.Bid{OfferId: offerId, Bid: amount, Volume: volume, State: "in_progress", OwnerId: user.Id}
+	err := Atomically(db, func(tx *gorm.DB) error {
+		offer, err := findOffer(tx, offerId)
+		if err != nil {
+			return err
+		}
+		if offer.TractorId == nil {
+			return apierror.ErrOfferNotFound
+		}
+		tractor, err := lockTractor(tx, *offer.TractorId)
+		if err != nil {
+			return err
+		}
+		if tractor.OwnerId == user.Id {
+			return apierror.ErrOwnOffer
+		}
+		if err := expectOpen(tx, offer, tractor.State); err != nil {
+			return err
+		}
+		return tx.Create(&bid).Error
+	})
+	return bid, err
+}
+
+func findOffer(tx *gorm.DB, offerId uuid.UUID) (models.Offer, error) {
+	var offer models.Offer
+	err := tx.First(&offer, "id = ?", offerId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return offer, apierror.ErrOfferNotFound
+	}
+	return offer, err
+}
+
+// expectOpen : Bids are only taken while the lot or tractor is on the market and before the limit date of its offer
+func expectOpen(tx *gorm.DB, offer models.Offer, state models.State) error {
+	var simulation models.Simulation
+	if err := tx.First(&simulation).Error; err != nil {
+		return apierror.ErrSimulationNotFound.WithCause(err)
+	}
+	if state != models.StateOnMarket || !offer.LimitDate.After(simulation.SimulationDate) {
+		return apierror.ErrOfferClosed.WithDetails(map[string]interface{}{"limit_date": offer.LimitDate, "state": state})
+	}
+	return nil
+}
+
+// CloseExpiredOffers : Settle the bids of the offers past their limit date and take their lots and tractors off the market
+func CloseExpiredOffers(db *gorm.DB) error {
+	return Atomically(db, func(tx *gorm.DB) error {
+		if err := settleTractorBids(tx); err != nil {
+			return err
+		}
+		if err := settleLotBids(tx); err != nil {
+			return err
+		}
+		if err := returnTractorsFromMarket(tx); err != nil {
+			return err
+		}
+		return returnLotsFromMarket(tx)
+	})
+}
+
+// settleLotBids : The lowest bid wins the lot, the others are rejected
+func settleLotBids(tx *gorm.DB) error {
+	var offers []models.Offer
+	query := `
+		SELECT offers.*
+		FROM offers
+		JOIN lots l ON offers.lot_id = l.id
+		WHERE offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1)
+		AND offers.lot_id IS NOT NULL AND offers.tractor_id IS NULL
+		AND l.state = 'on_market'
+	`
+	if err := tx.Raw(query).Scan(&offers).Error; err != nil {
+		return err
+	}
+
+	for _, offer := range offers {
+		var bids []models.Bid
+		if err := tx.Where("offer_id = ?", offer.Id).Order("bid asc").Find(&bids).Error; err != nil {
+			return err
+		}
+		for i, bid := range bids {
+			if i == 0 {
+				bid.State = "accepted"
+			} else {
+				bid.State = "rejected"
+			}
+			if err := tx.Save(&bid).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// settleTractorBids : The highest bids fill the tractor, the ones not fitting in the room left are rejected
+func settleTractorBids(tx *gorm.DB) error {
+	var offers []models.Offer
+	query := `
+		SELECT offers.*
+		FROM offers
+		JOIN tractors t ON offers.tractor_id = t.id
+		WHERE offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1)
+		AND offers.tractor_id IS NOT NULL AND offers.lot_id IS NULL
+		AND t.state = 'on_market'
+	`
+	if err := tx.Raw(query).Scan(&offers).Error; err != nil {
+		return err
+	}
+
+	for _, offer := range offers {
+		tractor, err := lockTractor(tx, *offer.TractorId)
+		if err != nil {
+			return err
+		}
+		var bids []models.Bid
+		if err := tx.Where("offer_id = ?", offer.Id).Order("bid desc").Find(&bids).Error; err != nil {
+			return err
+		}
+		loaded := tractor.CurrentVolume
+		for _, bid := range bids {
+			if bid.State != "in_progress" {
+				continue
+			}
+			if tractor.MaxVolume-tractor.CurrentVolume < bid.Volume {
+				bid.State = "rejected"
+			} else {
+				bid.State = "accepted"
+				tractor.CurrentVolume += bid.Volume
+			}
+			if err := tx.Save(&bid).Error; err != nil {
+				return err
+			}
+		}
+		if tractor.CurrentVolume != loaded {
+			if err := tx.Save(&tractor).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func returnLotsFromMarket(tx *gorm.DB) error {
+	query := `
+		UPDATE lots
+		SET
+			state = 'return_from_market',
+			version = lots.version + 1
+		FROM offers
+		WHERE offers.lot_id = lots.id AND offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1) AND lots.state = 'on_market'
+		RETURNING lots.id
+	`
+	var lotIds []uuid.UUID
+	if err := tx.Raw(query).Scan(&lotIds).Error; err != nil {
+		return err
+	}
+	// Raw SQL skips the audit callbacks, so the state change is recorded by hand
+	return models.RecordAudit(tx, models.AuditActionUpdate, "lots", lotIds,
+		map[string]interface{}{"state": models.StateOnMarket}, map[string]interface{}{"state": models.StateReturnFromMarket})
+}
+
+func returnTractorsFromMarket(tx *gorm.DB) error {
+	query := `
+		UPDATE tractors
+		SET
+			state = 'return_from_market',
+			version = tractors.version + 1
+		FROM offers
+		WHERE offers.tractor_id = tractors.id AND offers.limit_date <= (SELECT simulation_date FROM simulations LIMIT 1) AND tractors.state = 'on_market'
+		RETURNING tractors.id
+	`
+	var tractorIds []uuid.UUID
+	if err := tx.Raw(query).Scan(&tractorIds).Error; err != nil {
+		return err
+	}
+	// Raw SQL skips the audit callbacks, so the state change is recorded by hand
+	return models.RecordAudit(tx, models.AuditActionUpdate, "tractors", tractorIds,
+		map[string]interface{}{"state": models.StateOnMarket}, map[string]interface{}{"state": models.StateReturnFromMarket})
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"sort"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+	"tms-backend/routing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PlannedRoute is a route of a plan to accept, the tractor follows the checkpoints and carries the lots
+type PlannedRoute struct {
+	TractorId     uuid.UUID
+	Name          string
+	CheckpointIds []uuid.UUID
+	LotIds        []uuid.UUID
+}
+
+// PlanRoutes : Plan routes for the pending lots of the traffic manager on its pending tractors that follow no route yet,
+// see routing.Graph.Plan. Lots leave from their start checkpoint and tractors from their current one
+func PlanRoutes(db *gorm.DB, trafficManagerId uuid.UUID) (routing.Plan, error) {
+	var tractors []models.Tractor
+	if err := db.Where("traffic_manager_id = ? AND state = ? AND route_id IS NULL AND current_checkpoint_id IS NOT NULL",
+		trafficManagerId, models.StatePending).Order("created_at, id").Find(&tractors).Error; err != nil {
+		return routing.Plan{}, err
+	}
+	var lots []models.Lot
+	if err := db.Where("traffic_manager_id = ? AND state = ? AND tractor_id IS NULL AND start_checkpoint_id IS NOT NULL AND end_checkpoint_id IS NOT NULL",
+		trafficManagerId, models.StatePending).Order("created_at, id").Find(&lots).Error; err != nil {
+		return routing.Plan{}, err
+	}
+	var legs []models.Leg
+	if err := db.Where("closed = ?", false).Find(&legs).Error; err != nil {
+		return routing.Plan{}, err
+	}
+
+	vehicles := make([]routing.Vehicle, len(tractors))
+	for i, tractor := range tractors {
+		vehicles[i] = routing.Vehicle{
+			Id:           tractor.Id,
+			ResourceType: tractor.ResourceType,
+			Capacity:     tractor.MaxVolume - tractor.CurrentVolume,
+			From:         *tractor.CurrentCheckpointId,
+			To:           tractor.EndCheckpointId,
+		}
+	}
+	shipments := make([]routing.Shipment, len(lots))
+	for i, lot := range lots {
+		shipments[i] = routing.Shipment{
+			Id:           lot.Id,
+			ResourceType: lot.ResourceType,
+			Volume:       lot.Volume,
+			Pickup:       *lot.StartCheckpointId,
+			Delivery:     *lot.EndCheckpointId,
+		}
+	}
+	return routing.NewGraph(legs).Plan(vehicles, shipments), nil
+}
+
+// AcceptPlan : Create the routes of the plan, bind them to their tractors and assign them their lots, all or nothing.
+// Everything PlanRoutes relied on is checked again, ErrPlanOutdated tells a tractor or a lot changed in between
+func AcceptPlan(db *gorm.DB, user models.User, plannedRoutes []PlannedRoute) ([]models.Route, error) {
+	var tractorIds, lotIds []uuid.UUID
+	for _, plannedRoute := range plannedRoutes {
+		tractorIds = append(tractorIds, plannedRoute.TractorId)
+		lotIds = append(lotIds, plannedRoute.LotIds...)
+	}
+	if duplicated(tractorIds) {
+		return nil, apierror.InvalidParameter("routes").WithMessage("A tractor follows only one route of the plan")
+	}
+	if duplicated(lotIds) {
+		return nil, apierror.InvalidParameter("routes").WithMessage("A lot is carried by only one tractor of the plan")
+	}
+
+	routes := make([]models.Route, len(plannedRoutes))
+	err := Atomically(db, func(tx *gorm.DB) error {
+		// Rows are locked in id order, tractors before lots, so concurrent plans can not deadlock
+		tractors := map[uuid.UUID]models.Tractor{}
+		for _, tractorId := range sortedIds(tractorIds) {
+			tractor, err := lockTractor(tx, tractorId)
+			if err != nil {
+				return err
+			}
+			tractors[tractorId] = tractor
+		}
+		lots := map[uuid.UUID]models.Lot{}
+		for _, lotId := range sortedIds(lotIds) {
+			lot, err := lockLot(tx, lotId)
+			if err != nil {
+				return err
+			}
+			lots[lotId] = lot
+		}
+
+		for i, plannedRoute := range plannedRoutes {
+			tractor := tractors[plannedRoute.TractorId]
+			carried := make([]models.Lot, len(plannedRoute.LotIds))
+			for j, lotId := range plannedRoute.LotIds {
+				carried[j] = lots[lotId]
+			}
+			if err := checkPlannedRoute(user, tractor, carried, plannedRoute.CheckpointIds); err != nil {
+				return err
+			}
+
+			name := plannedRoute.Name
+			if name == "" {
+				name = "Plan for " + tractor.Name
+			}
+			stops := make([]RouteStop, len(plannedRoute.CheckpointIds))
+			for j, checkpointId := range plannedRoute.CheckpointIds {
+				stops[j] = RouteStop{CheckpointId: checkpointId, Position: uint(j + 1)}
+			}
+			route, err := CreateRoute(tx, models.Route{Name: name, TrafficManagerId: user.Id}, stops)
+			if err != nil {
+				return err
+			}
+			routes[i] = route
+
+			tractor.RouteId = &route.Id
+			for j := range carried {
+				if err := scheduleLot(tx, &tractor, &carried[j]); err != nil {
+					return err
+				}
+			}
+			if err := tx.Save(&tractor).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return routes, err
+}
+
+// checkPlannedRoute : Check that the tractor can still follow the checkpoints carrying the lots
+func checkPlannedRoute(user models.User, tractor models.Tractor, lots []models.Lot, checkpointIds []uuid.UUID) error {
+	if !auth.CanManageTractor(user, tractor) {
+		return apierror.ErrForbidden
+	}
+	if tractor.State != models.StatePending || tractor.RouteId != nil || tractor.CurrentCheckpointId == nil ||
+		len(checkpointIds) < 2 || checkpointIds[0] != *tractor.CurrentCheckpointId {
+		return apierror.ErrPlanOutdated.WithDetails(map[string]interface{}{"tractor_id": tractor.Id})
+	}
+	positions := make(map[uuid.UUID]int, len(checkpointIds))
+	for i, checkpointId := range checkpointIds {
+		if _, ok := positions[checkpointId]; ok {
+			return apierror.InvalidParameter("checkpoint_ids").WithMessage("A route goes only once through a checkpoint")
+		}
+		positions[checkpointId] = i
+	}
+
+	// Volume picked up and dropped off at each position of the route
+	changes := make([]float64, len(checkpointIds))
+	for _, lot := range lots {
+		if !auth.CanManageLot(user, lot) {
+			return apierror.ErrForbidden
+		}
+		if lot.State != models.StatePending || lot.TractorId != nil || lot.TrafficManagerId == nil ||
+			lot.StartCheckpointId == nil || lot.EndCheckpointId == nil {
+			return apierror.ErrPlanOutdated.WithDetails(map[string]interface{}{"lot_id": lot.Id})
+		}
+		if lot.ResourceType != tractor.ResourceType {
+			return apierror.ErrResourceTypeMismatch.WithDetails(map[string]interface{}{
+				"lot_id": lot.Id, "lot_resource_type": lot.ResourceType, "tractor_resource_type": tractor.ResourceType})
+		}
+		pickup, hasPickup := positions[*lot.StartCheckpointId]
+		delivery, hasDelivery := positions[*lot.EndCheckpointId]
+		if !hasPickup || !hasDelivery || pickup >= delivery {
+			return apierror.ErrLotIncompatible.WithMessage("The route does not go through the start of the lot, then its end").
+				WithDetails(map[string]interface{}{"lot_id": lot.Id, "tractor_id": tractor.Id})
+		}
+		changes[pickup] += lot.Volume
+		changes[delivery] -= lot.Volume
+	}
+	load := tractor.CurrentVolume
+	for i, change := range changes {
+		load += change
+		if load > tractor.MaxVolume {
+			return apierror.ErrTractorCapacityExceeded.WithDetails(map[string]interface{}{
+				"tractor_id": tractor.Id, "checkpoint_id": checkpointIds[i], "volume": load, "max_volume": tractor.MaxVolume})
+		}
+	}
+	return nil
+}
+
+func duplicated(ids []uuid.UUID) bool {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+func sortedIds(ids []uuid.UUID) []uuid.UUID {
+	sorted := append([]uuid.UUID{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+	return sorted
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+	"tms-backend/routing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RouteStop is a checkpoint of a route being created, stops are visited by increasing position
+type RouteStop struct {
+	CheckpointId uuid.UUID
+	Position     uint
+}
+
+// CreateRoute : Create the route with its stops, see saveStops
+func CreateRoute(db *gorm.DB, route models.Route, stops []RouteStop) (models.Route, error) {
+	if strings.TrimSpace(route.Name) == "" {
+		return route, apierror.InvalidParameter("name").WithMessage("name is required")
+	}
+	err := Atomically(db, func(tx *gorm.DB) error {
+		if err := tx.Create(&route).Error; err != nil {
+			return err
+		}
+		return saveStops(tx, route, stops)
+	})
+	return route, err
+}
+
+// UpdateRoute : Rename the route when name is set and replace its stops when stops is not nil. The stops of a route in
+// use can not change, the usage is in the details of the conflict
+func UpdateRoute(db *gorm.DB, user models.User, routeId uuid.UUID, name *string, stops []RouteStop) (models.Route, error) {
+	var route models.Route
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		route, err = lockRoute(tx, routeId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageRoute(user, route) {
+			return apierror.ErrForbidden
+		}
+		if name != nil {
+			if strings.TrimSpace(*name) == "" {
+				return apierror.InvalidParameter("name").WithMessage("name can not be empty")
+			}
+			route.Name = *name
+		}
+		if stops != nil {
+			usage, err := route.Usage(tx)
+			if err != nil {
+				return err
+			}
+			if usage.InUse() {
+				return apierror.ErrRouteInUse.WithDetails(usage)
+			}
+			if err := tx.Where("route_id = ?", route.Id).Delete(&models.RouteCheckpoint{}).Error; err != nil {
+				return err
+			}
+			if err := saveStops(tx, route, stops); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&route).Error
+	})
+	return route, err
+}
+
+// DeleteRoute : Delete a route with its stops once no tractor follows it and no transaction refers to it
+func DeleteRoute(db *gorm.DB, user models.User, routeId uuid.UUID) (models.Route, error) {
+	var route models.Route
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		route, err = lockRoute(tx, routeId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageRoute(user, route) {
+			return apierror.ErrForbidden
+		}
+		usage, err := route.Usage(tx)
+		if err != nil {
+			return err
+		}
+		if usage.InUse() {
+			return apierror.ErrRouteInUse.WithDetails(usage)
+		}
+		if err := tx.Where("route_id = ?", route.Id).Delete(&models.RouteCheckpoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&route).Error
+	})
+	return route, err
+}
+
+// saveStops : Create the stops of the route. They are visited by increasing position, positions follow each other
+// without gap, a checkpoint appears once and every two consecutive stops are connected by an open leg
+func saveStops(tx *gorm.DB, route models.Route, stops []RouteStop) error {
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].Position < stops[j].Position })
+	if err := checkStops(stops); err != nil {
+		return err
+	}
+	checkpointIds := make([]uuid.UUID, len(stops))
+	for i, stop := range stops {
+		checkpointIds[i] = stop.CheckpointId
+	}
+	if err := checkCheckpoints(tx, checkpointIds); err != nil {
+		return err
+	}
+	for i := 1; i < len(stops); i++ {
+		if err := checkLeg(tx, stops[i-1].CheckpointId, stops[i].CheckpointId); err != nil {
+			return err
+		}
+	}
+	for _, stop := range stops {
+		routeCheckpoint := models.RouteCheckpoint{
+			RouteId:      route.Id,
+			CheckpointId: stop.CheckpointId,
+			Position:     stop.Position,
+		}
+		if err := tx.Create(&routeCheckpoint).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkStops : Refuse less than two stops, shared or missing positions and checkpoints visited twice, stops are sorted
+// by position. The simulation moves a tractor to the stop at the next position of its current checkpoint
+func checkStops(stops []RouteStop) error {
+	if len(stops) < 2 {
+		return apierror.ErrRouteStopsInvalid.WithMessage("A route goes through at least two checkpoints")
+	}
+	positions := make(map[uuid.UUID]uint, len(stops))
+	for i, stop := range stops {
+		if i > 0 && stop.Position == stops[i-1].Position {
+			return apierror.ErrRouteStopsInvalid.WithMessage(fmt.Sprintf("Two stops are at position %d", stop.Position)).
+				WithDetails(map[string]interface{}{"position": stop.Position})
+		}
+		if i > 0 && stop.Position != stops[i-1].Position+1 {
+			return apierror.ErrRouteStopsInvalid.WithMessage(fmt.Sprintf("No stop at position %d", stops[i-1].Position+1)).
+				WithDetails(map[string]interface{}{"position": stops[i-1].Position + 1})
+		}
+		if position, ok := positions[stop.CheckpointId]; ok {
+			return apierror.ErrRouteStopsInvalid.WithMessage("The route goes twice through a checkpoint").
+				WithDetails(map[string]interface{}{"checkpoint_id": stop.CheckpointId, "positions": []uint{position, stop.Position}})
+		}
+		positions[stop.CheckpointId] = stop.Position
+	}
+	return nil
+}
+
+// SuggestRoute : Best path over the open legs from one checkpoint to another through the stops, see routing.Graph.Best
+func SuggestRoute(db *gorm.DB, from uuid.UUID, to uuid.UUID, stops []uuid.UUID, objective routing.Objective, keepOrder bool) (routing.Path, error) {
+	if err := checkCheckpoints(db, append([]uuid.UUID{from, to}, stops...)); err != nil {
+		return routing.Path{}, err
+	}
+
+	var legs []models.Leg
+	if err := db.Where("closed = ?", false).Find(&legs).Error; err != nil {
+		return routing.Path{}, err
+	}
+	path, err := routing.NewGraph(legs).Best(from, to, stops, objective, keepOrder)
+	if errors.Is(err, routing.ErrNoPath) {
+		return path, apierror.ErrNoRouteFound
+	}
+	if errors.Is(err, routing.ErrRevisit) {
+		return path, apierror.ErrNoRouteFound.WithMessage("Every route through the stops goes twice through a checkpoint")
+	}
+	return path, err
+}
+
+// checkCheckpoints : Refuse the first unknown checkpoint
+func checkCheckpoints(db *gorm.DB, checkpointIds []uuid.UUID) error {
+	var found []uuid.UUID
+	if err := db.Model(&models.Checkpoint{}).Where("id IN ?", checkpointIds).Pluck("id", &found).Error; err != nil {
+		return err
+	}
+	known := make(map[uuid.UUID]bool, len(found))
+	for _, id := range found {
+		known[id] = true
+	}
+	for _, id := range checkpointIds {
+		if !known[id] {
+			return apierror.ErrCheckpointNotFound.WithDetails(map[string]interface{}{"checkpoint_id": id})
+		}
+	}
+	return nil
+}
+
+// checkLeg : Refuse to go from one checkpoint to the other when no open leg connects them
+func checkLeg(tx *gorm.DB, fromCheckpointId uuid.UUID, toCheckpointId uuid.UUID) error {
+	details := map[string]interface{}{"from_checkpoint_id": fromCheckpointId, "to_checkpoint_id": toCheckpointId}
+	var leg models.Leg
+	err := leg.FindBetween(tx, fromCheckpointId, toCheckpointId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apierror.ErrRouteLegMissing.WithDetails(details)
+	}
+	if err != nil {
+		return err
+	}
+	if leg.Closed {
+		details["leg_id"] = leg.Id
+		return apierror.ErrRouteLegClosed.WithDetails(details)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdvanceSimulationDate : Move the simulation one day forward and close the offers that expired
+func AdvanceSimulationDate(db *gorm.DB) (time.Time, error) {
+	var newDate time.Time
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var simulation models.Simulation
+		if err := forUpdate(tx).First(&simulation).Error; err != nil {
+			return fmt.Errorf("unable to fetch simulation date: %w", err)
+		}
+
+		newDate = simulation.SimulationDate.AddDate(0, 0, 1)
+		if err := tx.Model(&simulation).Where("id = ?", simulation.ID).Update("simulation_date", newDate).Error; err != nil {
+			return fmt.Errorf("unable to update simulation date: %w", err)
+		}
+		return CloseExpiredOffers(tx)
+	})
+	return newDate, err
+}
+
+// MoveTractors : Move every tractor in transit to the next checkpoint of its route and run the transactions planned there.
+// The whole move is one transaction, tractors are either all moved or none is.
+func MoveTractors(db *gorm.DB) error {
+	return Atomically(db, func(tx *gorm.DB) error {
+		var tractorIds []uuid.UUID
+		if err := tx.Model(&models.Tractor{}).Where("state = ?", models.StateInTransit).Order("id").Pluck("id", &tractorIds).Error; err != nil {
+			return fmt.Errorf("unable to fetch tractors: %w", err)
+		}
+		for _, tractorId := range tractorIds {
+			if err := moveTractor(tx, tractorId); err != nil {
+				return fmt.Errorf("tractor %s: %w", tractorId, err)
+			}
+		}
+		return nil
+	})
+}
+
+func moveTractor(tx *gorm.DB, tractorId uuid.UUID) error {
+	tractor, err := lockTractor(tx, tractorId)
+	if err != nil {
+		return err
+	}
+	// A tractor put in transit without a route has nowhere to go, `check` reports it
+	if tractor.State != models.StateInTransit || tractor.RouteId == nil || tractor.CurrentCheckpointId == nil {
+		return nil
+	}
+
+	var currentRouteCheckpoint models.RouteCheckpoint
+	if err := currentRouteCheckpoint.GetRouteCheckpoint(tx, *tractor.RouteId, *tractor.CurrentCheckpointId); err != nil {
+		return fmt.Errorf("unable to fetch route checkpoint: %w", err)
+	}
+	var nextRouteCheckpoint models.RouteCheckpoint
+	if err := nextRouteCheckpoint.GetNextCheckpoint(tx, *tractor.RouteId, currentRouteCheckpoint.Position); err != nil {
+		return fmt.Errorf("unable to fetch next route checkpoint: %w", err)
+	}
+
+	tractor.CurrentCheckpointId = &nextRouteCheckpoint.CheckpointId
+	var lastCheckpointPosition uint
+	if err := tx.Raw("SELECT max(position) FROM route_checkpoints WHERE route_id = ?", tractor.RouteId).Scan(&lastCheckpointPosition).Error; err != nil {
+		return err
+	}
+	if nextRouteCheckpoint.Position == lastCheckpointPosition {
+		tractor.State = models.StateArchive
+	}
+
+	// The lots on board follow the tractor
+	if err := tx.Model(&models.Lot{}).Where("tractor_id = ? AND in_tractor = ?", tractor.Id, true).
+		Update("current_checkpoint_id", nextRouteCheckpoint.CheckpointId).Error; err != nil {
+		return fmt.Errorf("unable to move lots: %w", err)
+	}
+	if err := execTransactions(tx, &tractor, nextRouteCheckpoint.CheckpointId); err != nil {
+		return err
+	}
+	return tx.Save(&tractor).Error
+}
+
+// execTransactions : Load and unload the lots planned at the checkpoint, the volume is kept on the locked tractor
+func execTransactions(tx *gorm.DB, tractor *models.Tractor, checkpointId uuid.UUID) error {
+	var transactionModel models.Transaction
+	transactions, err := transactionModel.FindByRouteIdAndCheckpointIdAndTractorId(tx, *tractor.RouteId, checkpointId, tractor.Id)
+	if err != nil {
+		return fmt.Errorf("unable to fetch transactions: %w", err)
+	}
+	for _, transaction := range transactions {
+		if transaction.Lot == nil {
+			return errors.New("transaction " + transaction.Id.String() + " has no lot")
+		}
+		lot := transaction.Lot
+		if transaction.TransactionType == models.TransactionState(models.TransactionStateIn) {
+			lot.State = models.StateInTransit
+			lot.InTractor = true
+			tractor.CurrentVolume += lot.Volume
+		} else {
+			lot.State = models.StateArchive
+			lot.InTractor = false
+			tractor.CurrentVolume -= lot.Volume
+		}
+		if err := tx.Save(lot).Error; err != nil {
+			return fmt.Errorf("unable to execute transaction: %w", err)
+		}
+	}
+	return nil
+}
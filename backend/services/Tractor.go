@@ -0,0 +1,70 @@
+package services
+
+import (
+	"time"
+	"tms-backend/apierror"
+	"tms-backend/auth"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AssociateTractorToTrafficManager : Hand the tractor to a traffic manager of its organization, it then waits for lots. tractorVersion 0 skips the version check
+func AssociateTractorToTrafficManager(db *gorm.DB, user models.User, tractorId uuid.UUID, trafficManagerId uuid.UUID, tractorVersion int64) (models.Tractor, error) {
+	var tractor models.Tractor
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		tractor, err = lockTractor(tx, tractorId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageTractor(user, tractor) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, tractorVersion); err != nil {
+			return err
+		}
+		if _, err := trafficManagerOf(tx, trafficManagerId, tractor.OrganizationId); err != nil {
+			return err
+		}
+		tractor.TrafficManagerId = &trafficManagerId
+		tractor.State = models.StatePending
+		return tx.Save(&tractor).Error
+	})
+	return tractor, err
+}
+
+// AssignTraderToTractor : Hand the tractor to the least busy trader and put it on the market until limitDate, tractorVersion 0 skips the version check
+func AssignTraderToTractor(db *gorm.DB, user models.User, tractorId uuid.UUID, limitDate time.Time, tractorVersion int64) (models.Tractor, error) {
+	var tractor models.Tractor
+	err := Atomically(db, func(tx *gorm.DB) error {
+		var err error
+		tractor, err = lockTractor(tx, tractorId)
+		if err != nil {
+			return err
+		}
+		if !auth.CanManageTractor(user, tractor) {
+			return apierror.ErrForbidden
+		}
+		if err := models.ExpectVersion("tractors", tractor.Id, tractor.Version, tractorVersion); err != nil {
+			return err
+		}
+
+		trader, err := availableTrader(tx, &models.Tractor{})
+		if err != nil {
+			return err
+		}
+		tractor.TraderId = &trader.Id
+		tractor.State = models.StateAtTrader
+		tractor.LimitDate = limitDate
+		if err := tx.Save(&tractor).Error; err != nil {
+			return err
+		}
+
+		var offer models.Offer
+		_, err = offer.CreateOfferTractor(tx, limitDate, tractor.Id)
+		return err
+	})
+	return tractor, err
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"tms-backend/apierror"
+	"tms-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Atomically : Run fn in a DB transaction, everything it wrote is rolled back when it returns an error or panics.
+// The db is opened with SkipDefaultTransaction, so operations writing several rows must go through it.
+func Atomically(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(fn)
+}
+
+// forUpdate : Hold a row lock on the rows read until the end of the transaction
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// lockTractor : Load the tractor and lock it, tractors are always locked before lots so operations can not deadlock
+func lockTractor(tx *gorm.DB, tractorId uuid.UUID) (models.Tractor, error) {
+	var tractor models.Tractor
+	err := forUpdate(tx).First(&tractor, "id = ?", tractorId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tractor, apierror.ErrTractorNotFound
+	}
+	return tractor, err
+}
+
+// lockLot : Load the lot and lock it
+func lockLot(tx *gorm.DB, lotId uuid.UUID) (models.Lot, error) {
+	var lot models.Lot
+	err := forUpdate(tx).First(&lot, "id = ?", lotId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return lot, apierror.ErrLotNotFound
+	}
+	return lot, err
+}
+
+// lockRoute : Load the route and lock it, tractors can not be bound to it nor transactions planned along it meanwhile
+func lockRoute(tx *gorm.DB, routeId uuid.UUID) (models.Route, error) {
+	var route models.Route
+	err := forUpdate(tx).First(&route, "id = ?", routeId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return route, apierror.ErrRouteNotFound
+	}
+	return route, err
+}
+
+// trafficManagerOf : Load the traffic manager a lot or a tractor of the organization is handed to, users of other roles
+// or tenants are not found, so their ids do not leak
+func trafficManagerOf(tx *gorm.DB, trafficManagerId uuid.UUID, organizationId *uuid.UUID) (models.User, error) {
+	var trafficManager models.User
+	err := tx.First(&trafficManager, "id = ? AND role = ?", trafficManagerId, models.RoleTrafficManager).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return trafficManager, apierror.ErrTrafficManagerNotFound
+	}
+	if err != nil {
+		return trafficManager, err
+	}
+	if organizationId == nil || trafficManager.OrganizationId == nil || *trafficManager.OrganizationId != *organizationId {
+		return trafficManager, apierror.ErrTrafficManagerNotFound
+	}
+	return trafficManager, nil
+}
+
+// availableTrader : The trader with the fewest lots or tractors (depending on model) waiting at a trader
+func availableTrader(tx *gorm.DB, model interface{}) (models.User, error) {
+	var user models.User
+	traders, err := user.FindByRole(tx, models.RoleTrader)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(traders) == 0 {
+		return models.User{}, apierror.ErrTraderNotFound.WithMessage("No trader available")
+	}
+
+	var counts []struct {
+		TraderId uuid.UUID
+		Count    int
+	}
+	if err := tx.Model(model).Select("trader_id, count(*) AS count").
+		Where("state = ? AND trader_id IS NOT NULL", models.StateAtTrader).
+		Group("trader_id").Scan(&counts).Error; err != nil {
+		return models.User{}, err
+	}
+	traderCounts := make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		traderCounts[count.TraderId] = count.Count
+	}
+
+	selectedTrader := traders[0]
+	for _, trader := range traders[1:] {
+		if traderCounts[trader.Id] <= traderCounts[selectedTrader.Id] {
+			selectedTrader = trader
+		}
+	}
+	return selectedTrader, nil
+}
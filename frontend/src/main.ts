@@ -1,5 +1,77 @@
 import './app.css'
+import axios from 'axios'
+import { get } from 'svelte/store'
 import App from './App.svelte'
+import { accessToken, refreshToken } from '@stores/store'
+
+const API_BASE_URL = import.meta.env.VITE_API_BASE_URL
+
+const nativeFetch = window.fetch.bind(window)
+
+// Exchange the refresh token for a new token pair, shared by concurrent requests
+let pendingRefresh: Promise<boolean> | null = null
+function refreshSession(): Promise<boolean> {
+  if (!pendingRefresh) {
+    pendingRefresh = nativeFetch(`${API_BASE_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: get(refreshToken) }),
+    })
+      .then(async (response) => {
+        if (!response.ok) {
+          return false
+        }
+        const tokens = await response.json()
+        accessToken.set(tokens.token)
+        refreshToken.set(tokens.refresh_token)
+        return true
+      })
+      .catch(() => false)
+      .finally(() => {
+        pendingRefresh = null
+      })
+  }
+  return pendingRefresh
+}
+
+// Attach the access token to every call made to the backend
+axios.interceptors.request.use((config) => {
+  const token = get(accessToken)
+  if (token && config.url?.startsWith(API_BASE_URL)) {
+    config.headers.Authorization = `Bearer ${token}`
+  }
+  return config
+})
+
+// Retry once with a fresh access token when the current one has expired
+axios.interceptors.response.use(undefined, async (error) => {
+  const config = error.config
+  if (error.response?.status === 401 && config && !config._retried && get(refreshToken)) {
+    config._retried = true
+    if (await refreshSession()) {
+      return axios(config)
+    }
+  }
+  return Promise.reject(error)
+})
+
+function withToken(init: RequestInit): RequestInit {
+  const headers = new Headers(init.headers)
+  headers.set('Authorization', `Bearer ${get(accessToken)}`)
+  return { ...init, headers }
+}
+
+window.fetch = async (input: RequestInfo | URL, init: RequestInit = {}) => {
+  const url = input instanceof Request ? input.url : input.toString()
+  if (!get(accessToken) || !url.startsWith(API_BASE_URL)) {
+    return nativeFetch(input, init)
+  }
+  const response = await nativeFetch(input, withToken(init))
+  if (response.status === 401 && get(refreshToken) && (await refreshSession())) {
+    return nativeFetch(input, withToken(init))
+  }
+  return response
+}
 
 const app = new App({
   target: document.getElementById('app')!,
@@ -6,10 +6,14 @@ export type UserRole = "admin" | "traffic_manager" | "trader" | "client";
 // Store data into local storage
 const storedRole = (localStorage.getItem("userRole") as UserRole) || "admin";
 const storedId = localStorage.getItem("userId") || "0";
+const storedToken = localStorage.getItem("accessToken") || "";
+const storedRefreshToken = localStorage.getItem("refreshToken") || "";
 
 // Store variables into localStorage
 export const userRole = writable<UserRole>(storedRole);
 export const userId = writable<string>(storedId);
+export const accessToken = writable<string>(storedToken);
+export const refreshToken = writable<string>(storedRefreshToken);
 
 if (
   storedId === "0" &&
@@ -26,6 +30,12 @@ userRole.subscribe((value) => {
 userId.subscribe((value) => {
   localStorage.setItem("userId", value);
 });
+accessToken.subscribe((value) => {
+  localStorage.setItem("accessToken", value);
+});
+refreshToken.subscribe((value) => {
+  localStorage.setItem("refreshToken", value);
+});
 
 export const currentTab = writable<string>("");
 export const currentTrafficManagerTab = writable<string>("");